@@ -1,13 +1,13 @@
 package main
 
 import (
-	"encoding/base64"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"joshu/pkg/b64"
 	"net/url"
 	"time"
 )
@@ -29,6 +29,9 @@ func makeHeader(title string) fyne.CanvasObject {
 	return header
 }
 
+// base64AutoDetect is the variant choice detecting the variant on decode.
+const base64AutoDetect = "Auto-detect"
+
 func makeBase64UI(w fyne.Window) fyne.CanvasObject {
 	header := makeHeader("Base64 Encoder/Decoder")
 	footer := makeFooter()
@@ -43,14 +46,37 @@ func makeBase64UI(w fyne.Window) fyne.CanvasObject {
 	output.Wrapping = fyne.TextWrapBreak
 	output.SetPlaceHolder("Output Result")
 
+	// Auto-detect encodes standard base64 and detects the variant on decode.
+	variants := []string{base64AutoDetect}
+	for _, v := range b64.Variants {
+		variants = append(variants, v.Label())
+	}
+	variantSelect := widget.NewSelect(variants, func(string) {})
+	variantSelect.SetSelected(base64AutoDetect)
+	selectedVariant := func() b64.Variant {
+		for _, v := range b64.Variants {
+			if v.Label() == variantSelect.Selected {
+				return v
+			}
+		}
+		return b64.Standard
+	}
+
+	variantInfo := widget.NewLabel("")
+
 	encodeButton := widget.NewButtonWithIcon("Encode", theme.MediaFastForwardIcon(), func() {
 		if input.Text == "" {
 			input.Text = w.Clipboard().Content()
 			input.Refresh()
 		}
-		out := base64.StdEncoding.EncodeToString([]byte(input.Text))
-		output.Text = out
+		out, err := b64.EncodeVariant([]byte(input.Text), selectedVariant())
+		if err == nil {
+			output.Text = out
+		} else {
+			output.Text = err.Error()
+		}
 		output.Refresh()
+		variantInfo.SetText("")
 	})
 	encodeButton.Importance = widget.HighImportance
 
@@ -67,13 +93,21 @@ func makeBase64UI(w fyne.Window) fyne.CanvasObject {
 			input.Text = w.Clipboard().Content()
 			input.Refresh()
 		}
-		out, err := base64.StdEncoding.DecodeString(input.Text)
+		var out []byte
+		var err error
+		variant := selectedVariant()
+		if variantSelect.Selected == base64AutoDetect {
+			out, variant, err = b64.DecodeAuto(input.Text)
+		} else {
+			out, err = b64.DecodeVariant(input.Text, variant)
+		}
 		if err == nil {
 			output.Text = string(out)
+			variantInfo.SetText("Decoded as " + variant.Label())
 		} else {
 			output.Text = err.Error()
+			variantInfo.SetText("")
 		}
-		output.Text = string(out)
 		output.Refresh()
 	})
 	decodeButton.Importance = widget.HighImportance
@@ -88,7 +122,10 @@ func makeBase64UI(w fyne.Window) fyne.CanvasObject {
 		container.NewGridWithRows(2,
 			container.NewBorder(
 				nil,
-				container.NewGridWithColumns(4, encodeButton, decodeButton, copyButton, clearButton),
+				container.NewVBox(
+					container.NewBorder(nil, nil, widget.NewLabel("Variant"), variantInfo, variantSelect),
+					container.NewGridWithColumns(4, encodeButton, decodeButton, copyButton, clearButton),
+				),
 				nil,
 				nil,
 				input),
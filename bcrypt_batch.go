@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/bcryptx"
+	"runtime"
+	"strconv"
+)
+
+const (
+	batchHashMode   = "Hash plaintexts"
+	batchVerifyMode = "Verify plaintext/hash pairs"
+)
+
+func makeBcryptBatchUI(w fyne.Window) fyne.CanvasObject {
+	title := canvas.NewText("Bcrypt Batch", theme.ForegroundColor())
+	title.TextSize = 18
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	subTitle := canvas.NewText("Load a CSV or text file with one plaintext per line, or plaintext and hash pairs, and hash or verify them all.", theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	mode := widget.NewRadioGroup([]string{batchHashMode, batchVerifyMode}, nil)
+	mode.Horizontal = true
+	mode.SetSelected(batchHashMode)
+
+	costInput := widget.NewEntry()
+	costInput.SetPlaceHolder("Cost")
+	costInput.Text = strconv.Itoa(bcryptx.DefaultCost)
+
+	workersInput := widget.NewEntry()
+	workersInput.SetPlaceHolder("Workers")
+	workersInput.Text = strconv.Itoa(runtime.NumCPU())
+
+	fileText := canvas.NewText("No file loaded", theme.ForegroundColor())
+	fileText.TextSize = 14
+
+	progress := widget.NewProgressBar()
+
+	var records []bcryptx.Record
+	var format bcryptx.BatchFormat
+	var cancel context.CancelFunc
+
+	loadButton := widget.NewButtonWithIcon("Load File", theme.FolderOpenIcon(), func() {
+		open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+
+			format = bcryptx.FormatFromName(reader.URI().Name())
+			loaded, skipped, err := bcryptx.ReadBatch(reader, format)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			records = loaded
+			fileText.Text = fmt.Sprintf("%s (%d records)", reader.URI().Name(), len(records))
+			if skipped > 0 {
+				fileText.Text = fmt.Sprintf("%s (%d records, %d header or blank rows skipped)", reader.URI().Name(), len(records), skipped)
+			}
+			fileText.Refresh()
+			progress.SetValue(0)
+			setStatus("", true)
+		}, w)
+		open.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".txt"}))
+		open.Show()
+	})
+
+	var startButton, cancelButton, saveButton *widget.Button
+	startButton = widget.NewButtonWithIcon("Start", theme.MediaPlayIcon(), func() {
+		if len(records) == 0 {
+			setStatus("Load a file first", false)
+			return
+		}
+		workers, err := strconv.Atoi(workersInput.Text)
+		if err != nil || workers < 1 {
+			setStatus("Workers must be a positive number", false)
+			return
+		}
+		cost, err := strconv.Atoi(costInput.Text)
+		if err == nil {
+			err = bcryptx.ValidateCost(cost)
+		}
+		if mode.Selected == batchHashMode && err != nil {
+			setStatus(fmt.Sprintf("Invalid cost: %v", err), false)
+			return
+		}
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		startButton.Disable()
+		loadButton.Disable()
+		saveButton.Disable()
+		cancelButton.Enable()
+		progress.Max = float64(len(records))
+		progress.SetValue(0)
+		setStatus("Working...", true)
+
+		verify := mode.Selected == batchVerifyMode
+		go func() {
+			defer func() {
+				startButton.Enable()
+				loadButton.Enable()
+				saveButton.Enable()
+				cancelButton.Disable()
+			}()
+
+			onProgress := func(done int) { progress.SetValue(float64(done)) }
+			var err error
+			if verify {
+				err = bcryptx.VerifyBatch(ctx, records, workers, onProgress)
+			} else {
+				err = bcryptx.HashBatch(ctx, records, cost, workers, onProgress)
+			}
+			if err != nil {
+				setStatus(fmt.Sprintf("Stopped: %v", err), false)
+				return
+			}
+			setStatus(batchSummary(records, verify), true)
+		}()
+	})
+	startButton.Importance = widget.HighImportance
+
+	cancelButton = widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		if cancel != nil {
+			cancel()
+		}
+	})
+	cancelButton.Disable()
+
+	saveButton = widget.NewButtonWithIcon("Save Results", theme.DocumentSaveIcon(), func() {
+		if len(records) == 0 {
+			setStatus("Nothing to save", false)
+			return
+		}
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+
+			if err := bcryptx.WriteBatch(writer, bcryptx.FormatFromName(writer.URI().Name()), records); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			setStatus(fmt.Sprintf("Saved %d results to %s", len(records), writer.URI().Name()), true)
+		}, w)
+		save.SetFileName("bcrypt-results." + string(format))
+		save.Show()
+	})
+
+	return container.NewVBox(
+		title,
+		subTitle,
+		mode,
+		container.NewHBox(loadButton, fileText),
+		container.NewGridWithColumns(5,
+			widget.NewLabel("Cost"), costInput,
+			widget.NewLabel("Workers"), workersInput,
+			container.NewGridWithColumns(2, startButton, cancelButton),
+		),
+		progress,
+		status,
+		saveButton,
+	)
+}
+
+func batchSummary(records []bcryptx.Record, verify bool) string {
+	var matched, failed int
+	for _, r := range records {
+		switch {
+		case r.Err != nil:
+			failed++
+		case r.Match:
+			matched++
+		}
+	}
+	if verify {
+		return fmt.Sprintf("%d match, %d do not match, %d errors", matched, len(records)-matched-failed, failed)
+	}
+	return fmt.Sprintf("Hashed %d records, %d errors", len(records)-failed, failed)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/bcryptx"
+	"strconv"
+	"time"
+)
+
+// makeBcryptBenchmarkUI builds the cost benchmark section. onUse is called
+// with the recommended cost when the user chooses to apply it.
+func makeBcryptBenchmarkUI(onUse func(cost int)) fyne.CanvasObject {
+	title := canvas.NewText("Bcrypt Benchmark", theme.ForegroundColor())
+	title.TextSize = 18
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	subTitle := canvas.NewText(fmt.Sprintf("Time costs %d to %d on this machine and find the one closest to a target latency.",
+		bcryptx.MinBenchmarkCost, bcryptx.MaxBenchmarkCost), theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	targetInput := widget.NewEntry()
+	targetInput.SetPlaceHolder("Target in ms")
+	targetInput.Text = strconv.FormatInt(bcryptx.DefaultTarget.Milliseconds(), 10)
+
+	results := container.NewGridWithColumns(4)
+	progress := widget.NewProgressBar()
+	progress.Min = bcryptx.MinBenchmarkCost - 1
+	progress.Max = bcryptx.MaxBenchmarkCost
+	progress.Hide()
+
+	recommended := 0
+	var runButton, cancelButton, useButton *widget.Button
+	var cancel context.CancelFunc
+
+	useButton = widget.NewButton("Use Recommended Cost", func() {
+		if recommended > 0 {
+			onUse(recommended)
+		}
+	})
+	useButton.Disable()
+
+	runButton = widget.NewButtonWithIcon("Run", theme.MediaPlayIcon(), func() {
+		ms, err := strconv.Atoi(targetInput.Text)
+		if err != nil || ms < 1 {
+			setStatus("Target must be a positive number of milliseconds", false)
+			return
+		}
+		target := time.Duration(ms) * time.Millisecond
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		runButton.Disable()
+		cancelButton.Enable()
+		useButton.Disable()
+		results.Objects = nil
+		results.Refresh()
+		progress.SetValue(progress.Min)
+		progress.Show()
+		setStatus("Benchmarking, the highest costs take several seconds...", true)
+
+		go func() {
+			defer runButton.Enable()
+			defer cancelButton.Disable()
+			defer progress.Hide()
+
+			timings, err := bcryptx.Benchmark(ctx, func(t bcryptx.Timing) {
+				results.Add(widget.NewLabel(fmt.Sprintf("Cost %d: %v", t.Cost, t.Duration.Round(time.Millisecond))))
+				progress.SetValue(float64(t.Cost))
+			})
+			recommended = bcryptx.Recommend(timings, target)
+			if err != nil {
+				setStatus(fmt.Sprintf("Stopped: %v", err), false)
+				return
+			}
+			if recommended < bcryptx.MinPolicyCost {
+				setStatus(fmt.Sprintf("Recommended cost %d is below the minimum of %d, use %d instead",
+					recommended, bcryptx.MinPolicyCost, bcryptx.MinPolicyCost), false)
+				recommended = bcryptx.MinPolicyCost
+			} else {
+				setStatus(fmt.Sprintf("Recommended cost for %v: %d", target, recommended), true)
+			}
+			useButton.Enable()
+		}()
+	})
+	runButton.Importance = widget.HighImportance
+
+	cancelButton = widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		if cancel != nil {
+			cancel()
+		}
+	})
+	cancelButton.Disable()
+
+	return container.NewVBox(
+		title,
+		subTitle,
+		container.NewGridWithColumns(4, widget.NewLabel("Target latency (ms)"), targetInput, runButton, cancelButton),
+		progress,
+		results,
+		status,
+		useButton,
+	)
+}
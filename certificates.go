@@ -0,0 +1,358 @@
+package main
+
+import (
+	"crypto/x509/pkix"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/certs"
+	"strconv"
+	"strings"
+)
+
+// makeCertificatesUI builds the certificates tab: CSRs, self-signed
+// certificates, and leaf certificates signed by a throwaway test CA.
+func makeCertificatesUI(w fyne.Window) fyne.CanvasObject {
+	header := makeHeader("Certificates")
+	footer := makeFooter()
+
+	subTitle := canvas.NewText("Create certificate requests, self-signed certificates and a test CA for local services.",
+		theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	commonName := widget.NewEntry()
+	commonName.SetText("localhost")
+	organization := widget.NewEntry()
+	organizationalUnit := widget.NewEntry()
+	country := widget.NewEntry()
+	country.SetPlaceHolder("Two letter code, e.g. VN")
+	province := widget.NewEntry()
+	locality := widget.NewEntry()
+
+	sans := widget.NewEntry()
+	sans.SetText("localhost, 127.0.0.1, ::1")
+	sans.SetPlaceHolder("DNS names, IP addresses, e-mails and URIs separated by commas")
+
+	days := widget.NewEntry()
+	days.SetText(strconv.Itoa(certs.DefaultDays))
+
+	var usageNames, extUsageNames []string
+	for _, usage := range certs.KeyUsages {
+		usageNames = append(usageNames, string(usage))
+	}
+	for _, usage := range certs.ExtKeyUsages {
+		extUsageNames = append(extUsageNames, string(usage))
+	}
+	usageGroup := widget.NewCheckGroup(usageNames, func([]string) {})
+	usageGroup.Horizontal = true
+	extUsageGroup := widget.NewCheckGroup(extUsageNames, func([]string) {})
+	extUsageGroup.Horizontal = true
+
+	// options reads the form; unchecked usage groups leave the package
+	// defaults for the kind of certificate in place.
+	options := func() (certs.Options, error) {
+		opts := certs.Options{
+			Subject: subject(commonName.Text, organization.Text, organizationalUnit.Text,
+				country.Text, province.Text, locality.Text),
+			SANs: certs.ParseSANs(sans.Text),
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(days.Text))
+		if err != nil || n <= 0 {
+			return opts, fmt.Errorf("validity must be a positive number of days")
+		}
+		opts.Days = n
+		for _, usage := range usageGroup.Selected {
+			opts.KeyUsages = append(opts.KeyUsages, certs.KeyUsage(usage))
+		}
+		for _, usage := range extUsageGroup.Selected {
+			opts.ExtKeyUsages = append(opts.ExtKeyUsages, certs.ExtKeyUsage(usage))
+		}
+		return opts, nil
+	}
+
+	var keyTypes []string
+	for _, keyType := range certs.KeyTypes {
+		keyTypes = append(keyTypes, string(keyType))
+	}
+	keyTypeSelect := widget.NewSelect(keyTypes, func(string) {})
+	keyTypeSelect.SetSelectedIndex(0)
+
+	privateKeyBox := widget.NewMultiLineEntry()
+	privateKeyBox.SetPlaceHolder("Private key (generate one or paste an RSA, ECDSA or Ed25519 key)")
+	privateKeyBox.Wrapping = fyne.TextWrapBreak
+
+	outputBox := widget.NewMultiLineEntry()
+	outputBox.SetPlaceHolder("Certificate or certificate request")
+	outputBox.Wrapping = fyne.TextWrapBreak
+
+	caName := widget.NewEntry()
+	caName.SetText("Joshu Test CA")
+	caCertBox := widget.NewMultiLineEntry()
+	caCertBox.SetPlaceHolder("CA certificate")
+	caCertBox.Wrapping = fyne.TextWrapBreak
+	caKeyBox := widget.NewMultiLineEntry()
+	caKeyBox.SetPlaceHolder("CA private key")
+	caKeyBox.Wrapping = fyne.TextWrapBreak
+	csrBox := widget.NewMultiLineEntry()
+	csrBox.SetPlaceHolder("Certificate request to sign with the CA")
+	csrBox.Wrapping = fyne.TextWrapBreak
+
+	// issuedByCA records whether outputBox holds a certificate from the test
+	// CA, so the bundle can include the chain.
+	issuedByCA := false
+	setOutput := func(text string, fromCA bool, message string) {
+		outputBox.SetText(text)
+		issuedByCA = fromCA
+		setStatus(message, true)
+	}
+
+	var buttons []*widget.Button
+	setBusy := func(busy bool) {
+		for _, button := range buttons {
+			if busy {
+				button.Disable()
+			} else {
+				button.Enable()
+			}
+		}
+	}
+
+	// RSA keys can take seconds, so keys are generated in the background.
+	generateKey := func(done func(key string)) {
+		keyType := certs.KeyType(keyTypeSelect.Selected)
+		setBusy(true)
+		setStatus(fmt.Sprintf("Generating a %s key...", keyType), true)
+		go func() {
+			defer setBusy(false)
+			key, err := certs.GenerateKey(keyType)
+			if err != nil {
+				setStatus(err.Error(), false)
+				return
+			}
+			done(key)
+		}()
+	}
+
+	generateKeyButton := widget.NewButton("Generate Key", func() {
+		generateKey(func(key string) {
+			privateKeyBox.SetText(key)
+			setStatus(fmt.Sprintf("Generated a %s key", keyTypeSelect.Selected), true)
+		})
+	})
+
+	csrButton := widget.NewButton("Create CSR", func() {
+		opts, err := options()
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		csr, err := certs.CreateRequest(opts, privateKeyBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		csrBox.SetText(csr)
+		setOutput(csr, false, "Created a certificate request, also copied to the CA section for signing")
+	})
+
+	selfSignButton := widget.NewButton("Self-Signed Certificate", func() {
+		opts, err := options()
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		cert, err := certs.SelfSign(opts, privateKeyBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		setOutput(cert, false, "Created a self-signed certificate")
+	})
+	selfSignButton.Importance = widget.HighImportance
+
+	signButton := widget.NewButton("Sign with Test CA", func() {
+		opts, err := options()
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		cert, err := certs.Sign(opts, privateKeyBox.Text, caCertBox.Text, caKeyBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		setOutput(cert, true, "Created a certificate signed by the test CA")
+	})
+	signButton.Importance = widget.HighImportance
+
+	createCAButton := widget.NewButton("Create Test CA", func() {
+		opts, err := options()
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		opts.Subject = pkix.Name{CommonName: strings.TrimSpace(caName.Text)}
+		opts.SANs = nil
+		opts.KeyUsages = nil
+		opts.ExtKeyUsages = nil
+		generateKey(func(key string) {
+			cert, err := certs.CreateCA(opts, key)
+			if err != nil {
+				setStatus(err.Error(), false)
+				return
+			}
+			caKeyBox.SetText(key)
+			caCertBox.SetText(cert)
+			setStatus("Created a test CA, trust its certificate only on machines you control", true)
+		})
+	})
+
+	signCSRButton := widget.NewButton("Sign CSR", func() {
+		opts, err := options()
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		cert, err := certs.SignRequest(csrBox.Text, opts, caCertBox.Text, caKeyBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		setOutput(cert, true, "Signed the certificate request with the test CA")
+	})
+
+	buttons = []*widget.Button{generateKeyButton, csrButton, selfSignButton, signButton, createCAButton, signCSRButton}
+
+	saveFile := func(content string) {
+		if strings.TrimSpace(content) == "" {
+			setStatus("Nothing to save", false)
+			return
+		}
+		dialog.ShowFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+
+			if _, err := writer.Write([]byte(content)); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			setStatus("Saved "+writer.URI().Name(), true)
+		}, w)
+	}
+
+	copyButton := func(box *widget.Entry) *widget.Button {
+		return widget.NewButtonWithIcon("Copy", theme.ContentCopyIcon(), func() {
+			w.Clipboard().SetContent(box.Text)
+		})
+	}
+	saveButton := func(label string, box *widget.Entry) *widget.Button {
+		return widget.NewButtonWithIcon(label, theme.DocumentSaveIcon(), func() {
+			saveFile(certs.Bundle(box.Text))
+		})
+	}
+
+	includeKey := widget.NewCheck("Include private key", func(bool) {})
+	includeChain := widget.NewCheck("Include CA certificate", func(bool) {})
+	includeChain.SetChecked(true)
+	saveBundleButton := widget.NewButtonWithIcon("Save Bundle", theme.DocumentSaveIcon(), func() {
+		objects := []string{outputBox.Text}
+		if includeChain.Checked && issuedByCA {
+			objects = append(objects, caCertBox.Text)
+		}
+		if includeKey.Checked {
+			objects = append(objects, privateKeyBox.Text)
+		}
+		saveFile(certs.Bundle(objects...))
+	})
+
+	textBox := func(box *widget.Entry, bottom fyne.CanvasObject) fyne.CanvasObject {
+		return container.NewBorder(nil, bottom,
+			container.NewGridWrap(fyne.NewSize(1, 200), layout.NewSpacer()), nil,
+			box)
+	}
+
+	caTitle := canvas.NewText("Test CA", theme.ForegroundColor())
+	caTitle.TextSize = 18
+	caTitle.TextStyle = fyne.TextStyle{Bold: true}
+
+	caSubTitle := canvas.NewText("A local certificate authority for signing leaf certificates. Never use it in production.",
+		theme.ForegroundColor())
+	caSubTitle.TextSize = 14
+	caSubTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	return container.NewVScroll(container.NewPadded(container.NewBorder(header, footer, layout.NewSpacer(), layout.NewSpacer(),
+		container.NewVBox(
+			subTitle,
+			widget.NewForm(
+				widget.NewFormItem("Common name", commonName),
+				widget.NewFormItem("Organization", organization),
+				widget.NewFormItem("Organizational unit", organizationalUnit),
+				widget.NewFormItem("Country", country),
+				widget.NewFormItem("State or province", province),
+				widget.NewFormItem("Locality", locality),
+				widget.NewFormItem("Subject alt names", sans),
+				widget.NewFormItem("Validity (days)", days),
+				widget.NewFormItem("Key usage", usageGroup),
+				widget.NewFormItem("Extended key usage", extUsageGroup),
+			),
+			widget.NewLabel("Leave the usages unchecked for the usual TLS server and client defaults."),
+			container.NewHBox(widget.NewLabel("Key type"), keyTypeSelect, generateKeyButton),
+			container.NewGridWithColumns(2,
+				textBox(privateKeyBox, container.NewHBox(copyButton(privateKeyBox), saveButton("Save Key", privateKeyBox))),
+				textBox(outputBox, container.NewHBox(copyButton(outputBox), saveButton("Save", outputBox),
+					saveBundleButton, includeChain, includeKey)),
+			),
+			container.NewHBox(csrButton, selfSignButton, signButton),
+			status,
+			caTitle,
+			caSubTitle,
+			container.NewBorder(nil, nil, widget.NewLabel("CA name"), createCAButton, caName),
+			container.NewGridWithColumns(3,
+				textBox(caCertBox, container.NewHBox(copyButton(caCertBox), saveButton("Save", caCertBox))),
+				textBox(caKeyBox, container.NewHBox(copyButton(caKeyBox), saveButton("Save", caKeyBox))),
+				textBox(csrBox, signCSRButton),
+			),
+		))))
+}
+
+// subject builds a distinguished name from the non-empty form fields.
+func subject(commonName, organization, organizationalUnit, country, province, locality string) pkix.Name {
+	name := pkix.Name{CommonName: strings.TrimSpace(commonName)}
+	add := func(values *[]string, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			*values = append(*values, value)
+		}
+	}
+	add(&name.Organization, organization)
+	add(&name.OrganizationalUnit, organizationalUnit)
+	add(&name.Country, country)
+	add(&name.Province, province)
+	add(&name.Locality, locality)
+	return name
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"joshu/pkg/rsakeys"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Exit codes returned by runCLI.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// errUsage marks errors caused by bad command line input.
+var errUsage = errors.New("usage")
+
+const cliUsage = `Usage: joshu [command] [subcommand] [flags] [file]
+
+Without a command the graphical interface is started.
+
+Commands:
+  bcrypt hash   [-cost 12] [-prehash none] [file]
+                                            hash the plaintext read from file or stdin
+  bcrypt verify -hash HASH [-prehash none] [file]
+                                            check a plaintext against a bcrypt hash
+  bcrypt batch  [-verify] [-cost 12] [-workers N] [-format csv|txt] [file]
+                                            hash plaintexts, or verify plaintext/hash pairs
+  bcrypt inspect [-min-cost 10] [file]      show the version, cost, salt and checksum of a hash
+  bcrypt bench  [-target 250ms]             time costs 4-16 and recommend one
+  bcrypt convert [-to 2b] [file]            rewrite a hash's version as $2a$, $2b$ or $2y$
+  pwhash hash   [-alg argon2id] [-cost 12] [-m 65536] [-t 3] [-p 4] [-ln 17] [-r 8] [-salt 16] [-len 32] [file]
+                                            hash with bcrypt, argon2id, scrypt or pbkdf2-sha256
+  pwhash verify -hash HASH [-max-memory 256] [file]
+                                            check a plaintext against any supported hash,
+                                            refusing argon2id hashes needing more MiB of memory
+  pwgen         [-type strong_pwd] [-n 1] [-format txt|csv|json] [-bcrypt COST]
+  passphrase    [-words 6] [-sep -] [-caps lowercase] [-digit] [-symbol] [-wordlist F] [-n 1]
+  rsa gen       [-bits 2048] [-format pkcs1] [-private F] [-public F] [-passphrase P]
+                                            -passphrase encrypts a pkcs8 or openssh private key
+  rsa convert   [-format pkcs8] [-public] [-passphrase P] [-new-passphrase P] [file]
+                                            convert a key between pkcs1, pkcs8, openssh and jwk
+  rsa encrypt   -key PUBLIC.pem [-padding OAEP] [-hash SHA-256] [-label L] [file]
+                                            encrypt to base64 with a public key in any format
+  rsa decrypt   -key PRIVATE.pem [-passphrase P] [-padding OAEP] [-hash SHA-256] [-label L] [file]
+                                            decrypt base64 with a private key in any format
+  rsa sign      -key PRIVATE.pem [-passphrase P] [-scheme PSS] [-hash SHA-256] [file]
+                                            sign to a base64 signature
+  rsa verify    -key PUBLIC.pem -sig SIG [-scheme PSS] [-hash SHA-256] [file]
+  rsa seal      -key PUBLIC.pem [-hash SHA-256] [-format json|binary] [-out F] [file]
+                                            encrypt data of any size with AES-256-GCM and RSA-OAEP
+  rsa open      -key PRIVATE.pem [-passphrase P] [-out F] [file]
+                                            decrypt a sealed envelope in either format
+  ec gen        [-curve P-256] [-format pkcs8] [-private F] [-public F]
+                                            generate a P-256, P-384, P-521, Ed25519 or X25519 key pair
+  ec sign       -key PRIVATE.pem [-passphrase P] [file]
+                                            sign with ECDSA (SHA-2 matching the curve) or Ed25519
+  ec verify     -key PUBLIC.pem -sig SIG [file]
+  ec ecdh       -key PRIVATE.pem [-passphrase P] -peer PUBLIC.pem
+                                            print the hex ECDH shared secret
+  key inspect   [file]                      describe PEM/DER/OpenSSH/JWK keys, CSRs and certificates
+  key match     -private F [-passphrase P] -public F
+                                            check that a private key matches a public key or certificate
+  cert csr      -key PRIVATE.pem [-passphrase P] [-cn NAME] [-san a,b] [subject flags]
+                                            create a certificate signing request
+  cert self     -key PRIVATE.pem [-passphrase P] [-cn NAME] [-san a,b] [-days 365] [-ca] [-usage U] [-eku U] [subject flags]
+                                            create a self-signed certificate
+  cert ca       [-key PRIVATE.pem [-passphrase P] | -key-type "ECDSA P-256" -key-out F] [-cn NAME] [-days 365]
+                                            create a test certificate authority
+  cert sign     -ca-cert F -ca-key F [-ca-passphrase P] (-csr F | -key PRIVATE.pem [-passphrase P]) [-days 365] [-usage U] [-eku U]
+                                            issue a leaf certificate from the test CA
+  keystore list -passphrase P [-store F]    list the stored keys with their ids and fingerprints
+  keystore add  -passphrase P -private F [-public F] [-label L] [-store F]
+                                            store a key pair, creating the keystore if needed
+  keystore get  -passphrase P -id ID [-public] [-store F]
+                                            print a stored private or public key
+  keystore remove -passphrase P -id ID [-store F]
+  jwk from      [-kid ID] [-use sig|enc|none] [-alg A] [-private] [-passphrase P] [file]
+                                            convert a PEM or OpenSSH key to a JWK, kid the thumbprint by default
+  jwk to        [-private] [file]           convert a JWK or the keys of a JWK Set to PEM
+  jwk thumbprint [-passphrase P] [file]     print the RFC 7638 SHA-256 thumbprint of a key in any format
+  jwk set       [-private] [-use U] [-alg A] [-passphrase P] FILE...
+                                            assemble a JWK Set from keys in any format
+  jwt decode    [file]                      print the header and claims of a JWT with human readable times
+  jwt verify    (-key F [-passphrase P] | -secret S) [file]
+                                            verify a JWT with a key or certificate in any format, a JWK Set or a secret
+  jwt sign      (-key F [-passphrase P] | -secret S) [-alg A] [-header F] [file]
+                                            sign the JSON claims read from file or stdin
+  jose inspect  [file]                      break a JWS or JWE in compact or JSON serialization into its parts
+  jose sign     (-key F [-passphrase P] | -secret S) [-alg A] [-json] [-header F] [file]
+                                            sign as a compact JWS, or general JSON with a signature per key
+  jose verify   (-key F [-passphrase P] | -secret S) [file]
+                                            verify a JWS and print its payload
+  jose encrypt  (-key F | -secret S) [-alg RSA-OAEP-256|dir|ECDH-ES] [-json] [-header F] [file]
+                                            encrypt with A256GCM as a compact or general JSON JWE
+  jose decrypt  (-key F [-passphrase P] | -secret S) [file]
+                                            decrypt a JWE and print its plaintext
+  json pretty|minify|repair [file]
+  base64 encode [-variant standard|url|raw|raw-url|mime] [file]
+                                            encode, mime wrapping lines at 76 columns with CRLF
+  base64 decode [-variant auto] [file]      decode; auto ignores whitespace and missing padding
+  base64 detect [file]                      print which variant the input is encoded in
+
+Input is read from file when given, otherwise from stdin. Results are written to stdout.
+base64 enc and dec are short for base64 encode and decode.
+Every -passphrase and -secret flag has a -file form reading the first line of a file.
+Passphrase flags next to a private key decrypt it when it is encrypted.
+Certificate subject flags are -cn, -org, -ou, -country, -state and -locality; -san, -usage and
+-eku take comma separated lists, and the usages default to those of a TLS server and client.
+`
+
+type cliCommand func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+
+var cliCommands = map[string]map[string]cliCommand{
+	"bcrypt": {
+		"hash":    cliBcryptHash,
+		"verify":  cliBcryptVerify,
+		"batch":   cliBcryptBatch,
+		"inspect": cliBcryptInspect,
+		"convert": cliBcryptConvert,
+		"bench":   cliBcryptBench,
+	},
+	"pwhash": {
+		"hash":   cliPwhashHash,
+		"verify": cliPwhashVerify,
+	},
+	"pwgen": {
+		"": cliPwgen,
+	},
+	"passphrase": {
+		"": cliPassphrase,
+	},
+	"rsa": {
+		"gen":     cliRSAGen,
+		"encrypt": cliRSAEncrypt,
+		"decrypt": cliRSADecrypt,
+		"convert": cliRSAConvert,
+		"sign":    cliRSASign,
+		"verify":  cliRSAVerify,
+		"seal":    cliRSASeal,
+		"open":    cliRSAOpen,
+	},
+	"ec": {
+		"gen":    cliECGen,
+		"sign":   cliECSign,
+		"verify": cliECVerify,
+		"ecdh":   cliECDH,
+	},
+	"key": {
+		"inspect": cliKeyInspect,
+		"match":   cliKeyMatch,
+	},
+	"cert": {
+		"csr":  cliCertCSR,
+		"self": cliCertSelf,
+		"ca":   cliCertCA,
+		"sign": cliCertSign,
+	},
+	"keystore": {
+		"list":   cliKeystoreList,
+		"add":    cliKeystoreAdd,
+		"get":    cliKeystoreGet,
+		"remove": cliKeystoreRemove,
+	},
+	"jwk": {
+		"from":       cliJWKFrom,
+		"to":         cliJWKTo,
+		"thumbprint": cliJWKThumbprint,
+		"set":        cliJWKSet,
+	},
+	"jwt": {
+		"decode": cliJWTDecode,
+		"verify": cliJWTVerify,
+		"sign":   cliJWTSign,
+	},
+	"jose": {
+		"inspect": cliJOSEInspect,
+		"sign":    cliJOSESign,
+		"verify":  cliJOSEVerify,
+		"encrypt": cliJOSEEncrypt,
+		"decrypt": cliJOSEDecrypt,
+	},
+	"json": {
+		"pretty": cliJsonPretty,
+		"minify": cliJsonMinify,
+		"repair": cliJsonRepair,
+	},
+	"base64": {
+		"encode": cliBase64Encode,
+		"decode": cliBase64Decode,
+		"detect": cliBase64Detect,
+		// Short aliases of encode and decode.
+		"enc": cliBase64Encode,
+		"dec": cliBase64Decode,
+	},
+}
+
+// runCLI executes the subcommand described by args and returns the process exit code.
+func runCLI(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	name := args[0]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		fmt.Fprint(stdout, cliUsage)
+		return exitOK
+	}
+
+	subcommands, ok := cliCommands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "joshu: unknown command %q\n\n%s", name, cliUsage)
+		return exitUsage
+	}
+
+	rest := args[1:]
+	command, ok := subcommands[""]
+	if !ok {
+		if len(rest) == 0 {
+			fmt.Fprintf(stderr, "joshu %s: missing subcommand (%s)\n", name, subcommandNames(subcommands))
+			return exitUsage
+		}
+		command, ok = subcommands[rest[0]]
+		if !ok {
+			fmt.Fprintf(stderr, "joshu %s: unknown subcommand %q (%s)\n", name, rest[0], subcommandNames(subcommands))
+			return exitUsage
+		}
+		rest = rest[1:]
+	}
+
+	err := command(rest, stdin, stdout, stderr)
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "joshu %s: %v\n", name, err)
+		return exitUsage
+	default:
+		fmt.Fprintf(stderr, "joshu %s: %v\n", name, err)
+		return exitFailure
+	}
+}
+
+func subcommandNames(subcommands map[string]cliCommand) string {
+	var names []string
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
+
+// newFlagSet returns a flag set that reports parse errors instead of exiting.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("%w: too many arguments", errUsage)
+	}
+	return nil
+}
+
+// readInput reads the whole content of the file named in args, or stdin when no file is given.
+func readInput(args []string, stdin io.Reader) ([]byte, error) {
+	if len(args) == 0 || args[0] == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(args[0])
+}
+
+// readLine reads the input and strips a single trailing newline, as added by echo or a text editor.
+func readLine(args []string, stdin io.Reader) (string, error) {
+	data, err := readInput(args, stdin)
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimSuffix(string(data), "\n")
+	s = strings.TrimSuffix(s, "\r")
+	return s, nil
+}
+
+// writeOutput writes content to the named file, or to stdout when path is empty.
+func writeOutput(path, content string, perm os.FileMode, stdout io.Writer) error {
+	if path == "" {
+		_, err := io.WriteString(stdout, content)
+		return err
+	}
+	return os.WriteFile(path, []byte(content), perm)
+}
+
+// passphraseFlag registers -name and -name-file and returns a function
+// reading the passphrase given by either, or "" when neither is set. The file
+// form keeps the passphrase out of the shell history and process list.
+func passphraseFlag(fs *flag.FlagSet, name, usage string) func() (string, error) {
+	value := fs.String(name, "", usage)
+	path := fs.String(name+"-file", "", "read -"+name+" from the first line of this file")
+	return func() (string, error) {
+		if *path == "" {
+			return *value, nil
+		}
+		if *value != "" {
+			return "", fmt.Errorf("%w: -%s and -%s-file are mutually exclusive", errUsage, name, name)
+		}
+		data, err := os.ReadFile(*path)
+		if err != nil {
+			return "", err
+		}
+		line, _, _ := strings.Cut(string(data), "\n")
+		return strings.TrimSuffix(line, "\r"), nil
+	}
+}
+
+// keyFile is a key file flag registered by keyFlag.
+type keyFile struct {
+	name       string
+	path       *string
+	passphrase func() (string, error)
+}
+
+// keyFlag registers -name for a file containing a key in any format and,
+// unless passphrase is empty, a passphrase flag of that name decrypting the
+// key when it is an encrypted private key.
+func keyFlag(fs *flag.FlagSet, name, usage, passphrase string) *keyFile {
+	k := &keyFile{name: name, path: fs.String(name, "", usage)}
+	if passphrase != "" {
+		k.passphrase = passphraseFlag(fs, passphrase, "passphrase of an encrypted -"+name)
+	}
+	return k
+}
+
+// given reports whether the flag was set.
+func (k *keyFile) given() bool {
+	return *k.path != ""
+}
+
+// read returns the key in the file, decrypted if it is an encrypted private
+// key. It fails when the flag was not set.
+func (k *keyFile) read() (string, error) {
+	if *k.path == "" {
+		return "", fmt.Errorf("%w: -%s is required", errUsage, k.name)
+	}
+	key, err := os.ReadFile(*k.path)
+	if err != nil {
+		return "", err
+	}
+	if k.passphrase == nil {
+		return string(key), nil
+	}
+	pass, err := k.passphrase()
+	if err != nil {
+		return "", err
+	}
+	return rsakeys.UnlockPrivateKey(string(key), pass)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"joshu/pkg/b64"
+)
+
+func cliBase64Encode(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("base64 encode", stderr)
+	variant := fs.String("variant", string(b64.Standard), "standard, url, raw, raw-url or mime")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkBase64Variant(*variant); err != nil {
+		return err
+	}
+
+	input, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	out, err := b64.EncodeVariant(input, b64.Variant(*variant))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, out)
+	return err
+}
+
+func cliBase64Decode(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("base64 decode", stderr)
+	variant := fs.String("variant", base64AutoVariant, "auto, standard, url, raw, raw-url or mime")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *variant != base64AutoVariant {
+		if err := checkBase64Variant(*variant); err != nil {
+			return err
+		}
+	}
+
+	input, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	var out []byte
+	if *variant == base64AutoVariant {
+		out, _, err = b64.DecodeAuto(string(input))
+	} else {
+		out, err = b64.DecodeVariant(string(input), b64.Variant(*variant))
+	}
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(out)
+	return err
+}
+
+func cliBase64Detect(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("base64 detect", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	input, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	out, variant, err := b64.DecodeAuto(string(input))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "%s: %s, %d bytes\n", variant, variant.Label(), len(out))
+	return err
+}
+
+// base64AutoVariant is the -variant of base64 decode detecting the variant.
+const base64AutoVariant = "auto"
+
+func checkBase64Variant(name string) error {
+	for _, v := range b64.Variants {
+		if string(v) == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown -variant %q", errUsage, name)
+}
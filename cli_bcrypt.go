@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"joshu/pkg/bcryptx"
+	"strings"
+	"time"
+)
+
+func cliBcryptHash(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("bcrypt hash", stderr)
+	cost := fs.Int("cost", bcryptx.DefaultCost, "bcrypt cost")
+	preHash := fs.String("prehash", string(bcryptx.NoPreHash), "pre-hash mode: none, sha256-base64 or sha256-hex")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := bcryptx.ValidateCost(*cost); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	password, err := readLine(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	if password, err = bcryptx.PreHash(*preHash).Apply(password); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	hash, err := bcryptx.Hash(password, *cost)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, hash)
+	return err
+}
+
+func cliBcryptVerify(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("bcrypt verify", stderr)
+	hash := fs.String("hash", "", "bcrypt hash to check against")
+	preHash := fs.String("prehash", string(bcryptx.NoPreHash), "pre-hash mode: none, sha256-base64 or sha256-hex")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *hash == "" {
+		return fmt.Errorf("%w: -hash is required", errUsage)
+	}
+
+	password, err := readLine(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	if password, err = bcryptx.PreHash(*preHash).Apply(password); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if err := bcryptx.Verify(*hash, password); err != nil {
+		return errors.New(bcryptx.Explain(err))
+	}
+	if _, err := fmt.Fprintln(stdout, "Passwords match"); err != nil {
+		return err
+	}
+	for _, warning := range bcryptx.CheckPassword(password) {
+		fmt.Fprintln(stdout, "warning:", warning)
+	}
+	return nil
+}
+
+func cliBcryptConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("bcrypt convert", stderr)
+	to := fs.String("to", "2b", "version to convert to: 2a, 2b or 2y")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	hash, err := readLine(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	converted, err := bcryptx.ConvertPrefix(hash, strings.Trim(*to, "$"))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, converted)
+	return err
+}
+
+func cliBcryptBatch(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("bcrypt batch", stderr)
+	verify := fs.Bool("verify", false, "verify plaintext/hash pairs instead of hashing")
+	cost := fs.Int("cost", bcryptx.DefaultCost, "bcrypt cost")
+	workers := fs.Int("workers", 0, "number of concurrent workers, 0 for one per CPU")
+	format := fs.String("format", "", "input and output format: csv or txt, guessed from the file name by default")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if !*verify {
+		if err := bcryptx.ValidateCost(*cost); err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+	}
+
+	batchFormat := bcryptx.BatchFormat(*format)
+	if batchFormat == "" {
+		batchFormat = bcryptx.FormatFromName(fs.Arg(0))
+	}
+	input, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	records, skipped, err := bcryptx.ReadBatch(bytes.NewReader(input), batchFormat)
+	if err != nil {
+		return err
+	}
+	if skipped > 0 {
+		// stdout holds the results, so the count goes to stderr.
+		fmt.Fprintf(stderr, "skipped %d header or blank row(s)\n", skipped)
+	}
+
+	if *verify {
+		err = bcryptx.VerifyBatch(context.Background(), records, *workers, nil)
+	} else {
+		err = bcryptx.HashBatch(context.Background(), records, *cost, *workers, nil)
+	}
+	if err != nil {
+		return err
+	}
+	if err := bcryptx.WriteBatch(stdout, batchFormat, records); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if r.Err != nil || r.Verified && !r.Match {
+			return errors.New("some records failed, see the result column")
+		}
+	}
+	return nil
+}
+
+func cliBcryptInspect(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("bcrypt inspect", stderr)
+	minCost := fs.Int("min-cost", bcryptx.MinPolicyCost, "warn about costs below this")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	hash, err := readLine(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	info, err := bcryptx.Inspect(hash)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "version:  $%s$\ncost:     %d\nsalt:     %s\nchecksum: %s\n", info.Prefix, info.Cost, info.Salt, info.Checksum)
+	for _, warning := range info.Warnings(*minCost) {
+		fmt.Fprintln(stdout, "warning:", warning)
+	}
+	return nil
+}
+
+func cliBcryptBench(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("bcrypt bench", stderr)
+	target := fs.Duration("target", bcryptx.DefaultTarget, "hashing latency to aim for")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *target <= 0 {
+		return fmt.Errorf("%w: -target must be positive", errUsage)
+	}
+
+	timings, err := bcryptx.Benchmark(context.Background(), func(t bcryptx.Timing) {
+		fmt.Fprintf(stdout, "cost %2d  %v\n", t.Cost, t.Duration.Round(time.Microsecond))
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "recommended cost for %v: %d\n", *target, bcryptx.Recommend(timings, *target))
+	return err
+}
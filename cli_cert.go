@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"joshu/pkg/certs"
+	"os"
+	"strings"
+)
+
+// certOptionsFlag registers the certificate subject, SAN, validity and usage
+// flags and returns a function building certs.Options from them.
+func certOptionsFlag(fs *flag.FlagSet) func() certs.Options {
+	commonName := fs.String("cn", "", "subject common name")
+	organization := fs.String("org", "", "subject organization")
+	organizationalUnit := fs.String("ou", "", "subject organizational unit")
+	country := fs.String("country", "", "subject country code")
+	province := fs.String("state", "", "subject state or province")
+	locality := fs.String("locality", "", "subject locality")
+	sans := fs.String("san", "", "comma separated DNS names, IP addresses, e-mails and URIs, the -cn by default")
+	days := fs.Int("days", certs.DefaultDays, "validity in days")
+	usages := fs.String("usage", "", "comma separated key usages, e.g. digitalSignature,keyEncipherment")
+	extUsages := fs.String("eku", "", "comma separated extended key usages, e.g. serverAuth,clientAuth")
+	return func() certs.Options {
+		opts := certs.Options{
+			Subject: subject(*commonName, *organization, *organizationalUnit, *country, *province, *locality),
+			SANs:    certs.ParseSANs(*sans),
+			Days:    *days,
+		}
+		for _, usage := range certs.ParseSANs(*usages) {
+			opts.KeyUsages = append(opts.KeyUsages, certs.KeyUsage(usage))
+		}
+		for _, usage := range certs.ParseSANs(*extUsages) {
+			opts.ExtKeyUsages = append(opts.ExtKeyUsages, certs.ExtKeyUsage(usage))
+		}
+		return opts
+	}
+}
+
+func cliCertCSR(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("cert csr", stderr)
+	key := keyFlag(fs, "key", "PEM or OpenSSH file containing the RSA, ECDSA or Ed25519 private key", "passphrase")
+	options := certOptionsFlag(fs)
+	outPath := fs.String("out", "", "write the request to this file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	privateKey, err := key.read()
+	if err != nil {
+		return err
+	}
+
+	csr, err := certs.CreateRequest(options(), privateKey)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*outPath, csr, 0644, stdout)
+}
+
+func cliCertSelf(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("cert self", stderr)
+	key := keyFlag(fs, "key", "PEM or OpenSSH file containing the RSA, ECDSA or Ed25519 private key", "passphrase")
+	isCA := fs.Bool("ca", false, "make the certificate a certificate authority")
+	options := certOptionsFlag(fs)
+	outPath := fs.String("out", "", "write the certificate to this file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	privateKey, err := key.read()
+	if err != nil {
+		return err
+	}
+
+	opts := options()
+	opts.IsCA = *isCA
+	cert, err := certs.SelfSign(opts, privateKey)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*outPath, cert, 0644, stdout)
+}
+
+func cliCertCA(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("cert ca", stderr)
+	key := keyFlag(fs, "key", "use the private key in this file instead of generating one", "passphrase")
+	keyType := fs.String("key-type", string(certs.ECDSAP256), "type of the generated key: "+certKeyTypeNames())
+	keyOut := fs.String("key-out", "", "write the generated key to this file instead of stdout")
+	options := certOptionsFlag(fs)
+	outPath := fs.String("out", "", "write the certificate to this file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	opts := options()
+	if opts.Subject.CommonName == "" {
+		opts.Subject.CommonName = "Joshu Test CA"
+	}
+
+	var privateKey string
+	var err error
+	if key.given() {
+		if privateKey, err = key.read(); err != nil {
+			return err
+		}
+	} else if privateKey, err = certs.GenerateKey(certs.KeyType(*keyType)); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	cert, err := certs.CreateCA(opts, privateKey)
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(*outPath, cert, 0644, stdout); err != nil {
+		return err
+	}
+	if key.given() {
+		return nil
+	}
+	return writeOutput(*keyOut, privateKey, 0600, stdout)
+}
+
+func cliCertSign(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("cert sign", stderr)
+	caCertPath := fs.String("ca-cert", "", "PEM file containing the CA certificate")
+	caKey := keyFlag(fs, "ca-key", "file containing the CA private key", "ca-passphrase")
+	csrPath := fs.String("csr", "", "PEM file containing the certificate request to sign")
+	key := keyFlag(fs, "key", "certify the public half of this private key instead of a request", "passphrase")
+	options := certOptionsFlag(fs)
+	outPath := fs.String("out", "", "write the certificate to this file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *caCertPath == "" || !caKey.given() {
+		return fmt.Errorf("%w: -ca-cert and -ca-key are required", errUsage)
+	}
+	if (*csrPath == "") == !key.given() {
+		return fmt.Errorf("%w: exactly one of -csr and -key is required", errUsage)
+	}
+	caCert, err := os.ReadFile(*caCertPath)
+	if err != nil {
+		return err
+	}
+	caKeyText, err := caKey.read()
+	if err != nil {
+		return err
+	}
+
+	var cert string
+	if *csrPath != "" {
+		csr, err := os.ReadFile(*csrPath)
+		if err != nil {
+			return err
+		}
+		cert, err = certs.SignRequest(string(csr), options(), string(caCert), caKeyText)
+		if err != nil {
+			return err
+		}
+	} else {
+		privateKey, err := key.read()
+		if err != nil {
+			return err
+		}
+		cert, err = certs.Sign(options(), privateKey, string(caCert), caKeyText)
+		if err != nil {
+			return err
+		}
+	}
+	return writeOutput(*outPath, cert, 0644, stdout)
+}
+
+func certKeyTypeNames() string {
+	var names []string
+	for _, keyType := range certs.KeyTypes {
+		names = append(names, string(keyType))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/rsakeys"
+)
+
+func cliECGen(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("ec gen", stderr)
+	curve := fs.String("curve", string(eckeys.P256), "P-256, P-384, P-521, Ed25519 or X25519")
+	format := fs.String("format", string(rsakeys.PKCS8), "key format: pkcs8 or openssh")
+	privatePath := fs.String("private", "", "write the private key to this file instead of stdout")
+	publicPath := fs.String("public", "", "write the public key to this file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", errUsage, fs.Arg(0))
+	}
+
+	privateKey, publicKey, err := eckeys.Generate(eckeys.Curve(*curve), rsakeys.KeyFormat(*format))
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(*privatePath, privateKey, 0600, stdout); err != nil {
+		return err
+	}
+	return writeOutput(*publicPath, publicKey, 0644, stdout)
+}
+
+func cliECSign(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("ec sign", stderr)
+	key := keyFlag(fs, "key", "PEM or OpenSSH file containing the ECDSA or Ed25519 private key", "passphrase")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	privateKey, err := key.read()
+	if err != nil {
+		return err
+	}
+
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	signature, err := eckeys.Sign(data, privateKey)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, signature)
+	return err
+}
+
+func cliECVerify(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("ec verify", stderr)
+	key := keyFlag(fs, "key", "PEM or OpenSSH file containing the public key", "")
+	signature := fs.String("sig", "", "base64 signature to check")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *signature == "" {
+		return fmt.Errorf("%w: -sig is required", errUsage)
+	}
+	publicKey, err := key.read()
+	if err != nil {
+		return err
+	}
+
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	if err := eckeys.Verify(data, *signature, publicKey); err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	_, err = fmt.Fprintln(stdout, "Signature is valid")
+	return err
+}
+
+func cliECDH(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("ec ecdh", stderr)
+	key := keyFlag(fs, "key", "PEM file containing our ECDSA or X25519 private key", "passphrase")
+	peer := keyFlag(fs, "peer", "PEM or OpenSSH file containing the peer public key", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	privateKey, err := key.read()
+	if err != nil {
+		return err
+	}
+	peerKey, err := peer.read()
+	if err != nil {
+		return err
+	}
+
+	secret, err := eckeys.SharedSecret(privateKey, peerKey)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, hex.EncodeToString(secret))
+	return err
+}
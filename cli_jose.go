@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"joshu/pkg/jose"
+	"joshu/pkg/jwk"
+	"os"
+	"strings"
+)
+
+func cliJOSEInspect(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("jose inspect", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	object, err := jose.ParseObject(string(data))
+	if err != nil {
+		return err
+	}
+	kind := "JWS"
+	if object.Encrypted {
+		kind = "JWE"
+	}
+	fmt.Fprintf(stdout, "%s in %s serialization\n", kind, object.Serialization)
+	for _, segment := range object.Segments {
+		if _, err := fmt.Fprintf(stdout, "\n%s:\n%s\n", segment.Name, describeSegment(segment)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// joseInput is what jose sign and jose encrypt read.
+type joseInput struct {
+	header, payload []byte
+	keys            []jwk.Key
+	alg             jose.Algorithm
+	json            bool
+}
+
+// readJOSEInput reads the flags and payload shared by jose sign and jose
+// encrypt. defaultAlg picks the algorithm from the first key when -alg is
+// not given.
+func readJOSEInput(name string, args []string, stdin io.Reader, stderr io.Writer, defaultAlg func(any) jose.Algorithm) (joseInput, error) {
+	fs := newFlagSet(name, stderr)
+	keys := jwtKeysFlag(fs)
+	alg := fs.String("alg", "", "algorithm, by default the usual one of the key")
+	jsonForm := fs.Bool("json", false, "write general JSON instead of compact serialization")
+	headerPath := fs.String("header", "", "JSON protected header file, alg and kid are filled in")
+	if err := parseFlags(fs, args); err != nil {
+		return joseInput{}, err
+	}
+	input := joseInput{json: *jsonForm}
+	var err error
+	if input.keys, err = keys(); err != nil {
+		return joseInput{}, err
+	}
+	if *headerPath != "" {
+		if input.header, err = os.ReadFile(*headerPath); err != nil {
+			return joseInput{}, err
+		}
+	}
+	if input.alg = jose.Algorithm(*alg); input.alg == "" {
+		if input.alg = defaultAlg(input.keys[0].Key); input.alg == "" {
+			return joseInput{}, fmt.Errorf("%w: -alg is required for this key", errUsage)
+		}
+	}
+	if input.payload, err = readInput(fs.Args(), stdin); err != nil {
+		return joseInput{}, err
+	}
+	return input, nil
+}
+
+func cliJOSESign(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	input, err := readJOSEInput("jose sign", args, stdin, stderr, jose.AlgorithmFor)
+	if err != nil {
+		return err
+	}
+	sign := jose.SignToken
+	if input.json {
+		sign = jose.SignJSON
+	}
+	text, err := sign(input.header, input.payload, input.alg, input.keys)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, strings.TrimRight(text, "\n"))
+	return err
+}
+
+func cliJOSEVerify(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("jose verify", stderr)
+	keys := jwtKeysFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	k, err := keys()
+	if err != nil {
+		return err
+	}
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	object, err := jose.ParseObject(string(data))
+	if err != nil {
+		return err
+	}
+	payload, verified, err := object.Verify(k)
+	if err != nil {
+		return err
+	}
+	for i, key := range verified {
+		switch {
+		case key == nil:
+			fmt.Fprintf(stdout, "signature %d: not verified\n", i+1)
+		case key.KeyID != "":
+			fmt.Fprintf(stdout, "signature %d: valid, kid %s\n", i+1, key.KeyID)
+		default:
+			fmt.Fprintf(stdout, "signature %d: valid\n", i+1)
+		}
+	}
+	_, err = stdout.Write(payload)
+	return err
+}
+
+func cliJOSEEncrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	input, err := readJOSEInput("jose encrypt", args, stdin, stderr, jose.KeyAlgorithmFor)
+	if err != nil {
+		return err
+	}
+	encrypt := jose.Encrypt
+	if input.json {
+		encrypt = jose.EncryptJSON
+	}
+	text, err := encrypt(input.header, input.payload, input.alg, input.keys)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, strings.TrimRight(text, "\n"))
+	return err
+}
+
+func cliJOSEDecrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("jose decrypt", stderr)
+	keys := jwtKeysFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	k, err := keys()
+	if err != nil {
+		return err
+	}
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	object, err := jose.ParseObject(string(data))
+	if err != nil {
+		return err
+	}
+	plaintext, _, err := object.Decrypt(k)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(plaintext)
+	return err
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"joshu/pkg/jsonedit"
+)
+
+func cliJsonPretty(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return cliJson("json pretty", args, stdin, stdout, stderr, jsonedit.Beautify)
+}
+
+func cliJsonMinify(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return cliJson("json minify", args, stdin, stdout, stderr, jsonedit.OneLine)
+}
+
+func cliJsonRepair(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return cliJson("json repair", args, stdin, stdout, stderr, jsonedit.Repair)
+}
+
+func cliJson(name string, args []string, stdin io.Reader, stdout, stderr io.Writer, format func(string) (string, error)) error {
+	fs := newFlagSet(name, stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	input, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	output, err := format(string(input))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, output)
+	return err
+}
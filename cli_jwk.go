@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"joshu/pkg/jwk"
+	"os"
+)
+
+// jwkParamsFlag registers -use and -alg and returns a function applying them
+// to the default parameters of a key.
+func jwkParamsFlag(fs *flag.FlagSet) func(key any) (jwk.Params, error) {
+	use := fs.String("use", "", "key use: sig, enc or none, by default the usual use of the key")
+	alg := fs.String("alg", "", "alg member, by default the usual algorithm of the key")
+	return func(key any) (jwk.Params, error) {
+		params := jwk.DefaultParams(key)
+		switch *use {
+		case "":
+		case "none":
+			params.Use = ""
+		case jwk.UseSignature, jwk.UseEncryption:
+			params.Use = *use
+		default:
+			return params, fmt.Errorf("%w: -use must be sig, enc or none", errUsage)
+		}
+		if *alg != "" {
+			params.Alg = *alg
+		}
+		return params, nil
+	}
+}
+
+// jwkKey reads a key in any format as a JWK, public unless private is set,
+// with the thumbprint as kid. passphrase decrypts an encrypted private key.
+func jwkKey(text, passphrase string, private bool, params func(any) (jwk.Params, error)) (jwk.Key, error) {
+	key, err := jwk.ParseKeyText(text, passphrase)
+	if err != nil {
+		return jwk.Key{}, err
+	}
+	if !private {
+		key = jwk.Public(key)
+	}
+	p, err := params(key)
+	if err != nil {
+		return jwk.Key{}, err
+	}
+	if p.KeyID, err = jwk.Thumbprint(key); err != nil {
+		return jwk.Key{}, err
+	}
+	return jwk.Key{Key: key, Params: p}, nil
+}
+
+func cliJWKFrom(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("jwk from", stderr)
+	kid := fs.String("kid", "", "key id, the RFC 7638 thumbprint by default")
+	private := fs.Bool("private", false, "keep the private key instead of converting its public half")
+	params := jwkParamsFlag(fs)
+	passphrase := passphraseFlag(fs, "passphrase", "passphrase of an encrypted private key")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	pass, err := passphrase()
+	if err != nil {
+		return err
+	}
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	key, err := jwkKey(string(data), pass, *private, params)
+	if err != nil {
+		return err
+	}
+	if *kid != "" {
+		key.KeyID = *kid
+	}
+	text, err := jwk.Marshal(key.Key, key.Params)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, text)
+	return err
+}
+
+func cliJWKTo(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("jwk to", stderr)
+	private := fs.Bool("private", false, "write private keys instead of their public halves")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	keys, err := jwk.Parse(data)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		k := key.Key
+		if !*private {
+			k = jwk.Public(k)
+		}
+		text, err := jwk.EncodePEM(k)
+		if err != nil {
+			return err
+		}
+		if key.KeyID != "" {
+			fmt.Fprintf(stdout, "kid: %s\n", key.KeyID)
+		}
+		if _, err := io.WriteString(stdout, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cliJWKThumbprint(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("jwk thumbprint", stderr)
+	passphrase := passphraseFlag(fs, "passphrase", "passphrase of an encrypted private key")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	pass, err := passphrase()
+	if err != nil {
+		return err
+	}
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	key, err := jwk.ParseKeyText(string(data), pass)
+	if err != nil {
+		return err
+	}
+	thumbprint, err := jwk.Thumbprint(key)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, thumbprint)
+	return err
+}
+
+func cliJWKSet(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("jwk set", stderr)
+	private := fs.Bool("private", false, "include private keys instead of their public halves")
+	params := jwkParamsFlag(fs)
+	passphrase := passphraseFlag(fs, "passphrase", "passphrase of an encrypted private key")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("%w: no key files given", errUsage)
+	}
+	pass, err := passphrase()
+	if err != nil {
+		return err
+	}
+
+	var keys []jwk.Key
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		key, err := jwkKey(string(data), pass, *private, params)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	text, err := jwk.MarshalSet(keys)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, text)
+	return err
+}
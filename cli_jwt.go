@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"joshu/pkg/jose"
+	"joshu/pkg/jwk"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtKeysFlag registers -key with its -passphrase and -secret and returns a
+// function reading the keys given by either.
+func jwtKeysFlag(fs *flag.FlagSet) func() ([]jwk.Key, error) {
+	key := keyFlag(fs, "key", "PEM, OpenSSH or JWK key, JWK Set or certificate file", "passphrase")
+	secret := passphraseFlag(fs, "secret", "HMAC secret")
+	return func() ([]jwk.Key, error) {
+		s, err := secret()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case key.given() && s != "":
+			return nil, fmt.Errorf("%w: -key and -secret are mutually exclusive", errUsage)
+		case s != "":
+			return []jwk.Key{{Key: []byte(s)}}, nil
+		case !key.given():
+			return nil, fmt.Errorf("%w: -key or -secret is required", errUsage)
+		}
+		text, err := key.read()
+		if err != nil {
+			return nil, err
+		}
+		keys, err := jose.ParseKeys(text, "")
+		if err != nil {
+			return nil, err
+		}
+		if _, secret := keys[0].Key.([]byte); secret && len(keys) == 1 {
+			return nil, fmt.Errorf("%s: not a PEM, OpenSSH or JWK key, use -secret-file for secrets", *key.path)
+		}
+		return keys, nil
+	}
+}
+
+// printJWT writes the header and payload of token, pretty printed, and the
+// times of its NumericDate claims.
+func printJWT(token *jose.Token, stdout io.Writer) error {
+	for _, part := range []struct {
+		name string
+		data []byte
+	}{{"Header", token.Header}, {"Payload", token.Payload}} {
+		if _, err := fmt.Fprintf(stdout, "%s:\n%s\n", part.name, strings.TrimRight(prettyJSON(part.data), "\n")); err != nil {
+			return err
+		}
+	}
+	for _, claim := range jose.TimeClaims(token.Payload) {
+		fmt.Fprintf(stdout, "%s: %s (%s)\n", claim.Name, claim.Time.Local().Format(time.RFC3339), relativeTime(claim.Time))
+	}
+	for _, problem := range jose.CheckTimes(token.Payload, time.Now()) {
+		fmt.Fprintf(stdout, "warning: the token is %s\n", problem)
+	}
+	return nil
+}
+
+func cliJWTDecode(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("jwt decode", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	token, err := jose.DecodeToken(string(data))
+	if err != nil {
+		return err
+	}
+	return printJWT(token, stdout)
+}
+
+func cliJWTVerify(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("jwt verify", stderr)
+	keys := jwtKeysFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	k, err := keys()
+	if err != nil {
+		return err
+	}
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	token, err := jose.DecodeToken(string(data))
+	if err != nil {
+		return err
+	}
+	key, err := token.Verify(k)
+	if err != nil {
+		return err
+	}
+	message := "valid " + string(token.Alg) + " signature"
+	if key.KeyID != "" {
+		message += ", kid " + key.KeyID
+	}
+	if _, err := fmt.Fprintln(stdout, message); err != nil {
+		return err
+	}
+	return printJWT(token, stdout)
+}
+
+func cliJWTSign(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("jwt sign", stderr)
+	keys := jwtKeysFlag(fs)
+	alg := fs.String("alg", "", "signature algorithm, by default HS256 for secrets and the usual one of the key")
+	headerPath := fs.String("header", "", "JSON header file, alg and kid are filled in")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	k, err := keys()
+	if err != nil {
+		return err
+	}
+	var header []byte
+	if *headerPath != "" {
+		if header, err = os.ReadFile(*headerPath); err != nil {
+			return err
+		}
+	} else {
+		header = []byte(`{"typ": "JWT"}`)
+	}
+	algorithm := jose.Algorithm(*alg)
+	if algorithm == "" {
+		if algorithm = jose.AlgorithmFor(k[0].Key); algorithm == "" {
+			return fmt.Errorf("%w: -alg is required for this key", errUsage)
+		}
+	}
+	payload, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(payload) {
+		return errors.New("the claims are not valid JSON")
+	}
+
+	token, err := jose.SignToken(header, payload, algorithm, k)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, token)
+	return err
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"joshu/pkg/keyinspect"
+	"text/tabwriter"
+)
+
+func cliKeyInspect(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("key inspect", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	reports, err := keyinspect.Inspect(data)
+	if err != nil {
+		return err
+	}
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprintln(stdout, report.Title())
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		for _, field := range report.Fields() {
+			fmt.Fprintf(tw, "  %s:\t%s\n", field.Name, field.Value)
+		}
+		tw.Flush()
+		for _, warning := range report.Warnings {
+			fmt.Fprintln(stdout, "  warning:", warning)
+		}
+	}
+	return nil
+}
+
+func cliKeyMatch(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("key match", stderr)
+	private := keyFlag(fs, "private", "file containing the private key", "passphrase")
+	public := keyFlag(fs, "public", "file containing the public key, certificate or certificate request", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	privateKey, err := private.read()
+	if err != nil {
+		return err
+	}
+	publicKey, err := public.read()
+	if err != nil {
+		return err
+	}
+
+	if err := keyinspect.Match([]byte(privateKey), []byte(publicKey)); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, "The keys form a pair")
+	return err
+}
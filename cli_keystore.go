@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"joshu/pkg/keystore"
+	"text/tabwriter"
+)
+
+// keystoreFlags registers -store and the master -passphrase and returns a
+// function opening the keystore, or creating it when create is set.
+func keystoreFlags(fs *flag.FlagSet) func(create bool) (*keystore.Store, error) {
+	path := fs.String("store", "", "keystore file, by default "+keystore.FileName+" in the user config directory")
+	passphrase := passphraseFlag(fs, "passphrase", "master passphrase of the keystore")
+	return func(create bool) (*keystore.Store, error) {
+		pass, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		if pass == "" {
+			return nil, fmt.Errorf("%w: -passphrase is required", errUsage)
+		}
+		storePath := *path
+		if storePath == "" {
+			if storePath, err = keystore.DefaultPath(); err != nil {
+				return nil, err
+			}
+		}
+		if create && !keystore.Exists(storePath) {
+			return keystore.Create(storePath, pass)
+		}
+		return keystore.Open(storePath, pass)
+	}
+}
+
+func cliKeystoreList(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("keystore list", stderr)
+	open := keystoreFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	store, err := open(false)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tLABEL\tALGORITHM\tCREATED\tFINGERPRINT")
+	for _, e := range store.Entries() {
+		algorithm := e.Algorithm
+		if e.Bits > 0 {
+			algorithm = fmt.Sprintf("%s %d bit", e.Algorithm, e.Bits)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.ID, e.Label, algorithm,
+			e.Created.Local().Format("2006-01-02 15:04"), e.Fingerprint)
+	}
+	return tw.Flush()
+}
+
+func cliKeystoreAdd(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("keystore add", stderr)
+	open := keystoreFlags(fs)
+	private := keyFlag(fs, "private", "file containing the private key, stored encrypted if it is", "")
+	public := keyFlag(fs, "public", "file containing the public key, derived from the private key by default", "")
+	label := fs.String("label", "", "label of the key pair")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	privateKey, err := private.read()
+	if err != nil {
+		return err
+	}
+	var publicKey string
+	if public.given() {
+		if publicKey, err = public.read(); err != nil {
+			return err
+		}
+	}
+
+	store, err := open(true)
+	if err != nil {
+		return err
+	}
+	entry, err := store.Add(*label, privateKey, publicKey)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, entry.ID)
+	return err
+}
+
+func cliKeystoreGet(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("keystore get", stderr)
+	open := keystoreFlags(fs)
+	id := fs.String("id", "", "id of the key pair, see keystore list")
+	public := fs.Bool("public", false, "print the public key instead of the private key")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return fmt.Errorf("%w: -id is required", errUsage)
+	}
+	store, err := open(false)
+	if err != nil {
+		return err
+	}
+
+	entry, err := store.Get(*id)
+	if err != nil {
+		return err
+	}
+	if *public {
+		_, err = io.WriteString(stdout, entry.PublicKey)
+	} else {
+		_, err = io.WriteString(stdout, entry.PrivateKey)
+	}
+	return err
+}
+
+func cliKeystoreRemove(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("keystore remove", stderr)
+	open := keystoreFlags(fs)
+	id := fs.String("id", "", "id of the key pair, see keystore list")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return fmt.Errorf("%w: -id is required", errUsage)
+	}
+	store, err := open(false)
+	if err != nil {
+		return err
+	}
+	return store.Remove(*id)
+}
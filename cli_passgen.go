@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"joshu/pkg/bcryptx"
+	"joshu/pkg/passgen"
+	"strings"
+)
+
+func cliPwgen(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var formats []string
+	for _, f := range passgen.ExportFormats {
+		formats = append(formats, string(f))
+	}
+
+	fs := newFlagSet("pwgen", stderr)
+	strength := fs.String("type", passgen.StrongPassword, "password type: "+strings.Join(passwordTypeKeys(), ", "))
+	count := fs.Int("n", 1, "number of passwords to generate")
+	format := fs.String("format", string(passgen.TextFormat), "output format: "+strings.Join(formats, ", "))
+	cost := fs.Int("bcrypt", 0, "also output bcrypt hashes at this cost, 0 to disable")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return fmt.Errorf("%w: -n must be positive", errUsage)
+	}
+	policy, ok := passgen.LookupPolicy(*strength)
+	if !ok {
+		return fmt.Errorf("%w: no such strength %q", errUsage, *strength)
+	}
+
+	passwords, err := passgen.GenerateBulk(policy, *count)
+	if err != nil {
+		return err
+	}
+	credentials := make([]passgen.Credential, len(passwords))
+	for i, p := range passwords {
+		credentials[i].Password = p
+	}
+	if *cost != 0 {
+		hashes, err := bcryptx.HashAll(passwords, *cost, 0, nil)
+		if err != nil {
+			return err
+		}
+		for i := range credentials {
+			credentials[i].Hash = hashes[i]
+		}
+	}
+
+	if err := passgen.Export(stdout, passgen.ExportFormat(*format), credentials); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
+
+func cliPassphrase(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var capitalizations []string
+	for _, c := range passgen.Capitalizations {
+		capitalizations = append(capitalizations, string(c))
+	}
+
+	fs := newFlagSet("passphrase", stderr)
+	words := fs.Int("words", 6, "number of words")
+	separator := fs.String("sep", "-", "separator between words")
+	capitalization := fs.String("caps", string(passgen.LowerCaseWords), "capitalization: "+strings.Join(capitalizations, ", "))
+	digit := fs.Bool("digit", false, "append a random digit to a random word")
+	symbol := fs.Bool("symbol", false, "append a random symbol to a random word")
+	wordlistPath := fs.String("wordlist", "", "wordlist file, one word per line or EFF diceware format")
+	count := fs.Int("n", 1, "number of passphrases to generate")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return fmt.Errorf("%w: -n must be positive", errUsage)
+	}
+
+	opts := passgen.PassphraseOptions{
+		Words:          *words,
+		Separator:      *separator,
+		Capitalization: passgen.Capitalization(*capitalization),
+		AddDigit:       *digit,
+		AddSymbol:      *symbol,
+	}
+	if *wordlistPath != "" {
+		list, err := passgen.LoadWordlist(*wordlistPath)
+		if err != nil {
+			return err
+		}
+		opts.Wordlist = list
+	}
+
+	for i := 0; i < *count; i++ {
+		phrase, err := passgen.GeneratePassphrase(opts)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		if _, err := fmt.Fprintln(stdout, phrase.Phrase); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// passwordTypeKeys returns the keys accepted by GetKey in display order.
+func passwordTypeKeys() []string {
+	var keys []string
+	for _, p := range passgen.DefaultPolicies {
+		keys = append(keys, p.Key)
+	}
+	return keys
+}
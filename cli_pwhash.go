@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"joshu/pkg/pwhash"
+)
+
+func cliPwhashHash(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("pwhash hash", stderr)
+	alg := fs.String("alg", string(pwhash.Argon2id), "algorithm: bcrypt, argon2id, scrypt or pbkdf2-sha256")
+	cost := fs.Int("cost", -1, "bcrypt cost")
+	memory := fs.Int("m", -1, "argon2id memory in KiB")
+	iterations := fs.Int("t", -1, "argon2id or pbkdf2-sha256 iterations")
+	parallelism := fs.Int("p", -1, "argon2id or scrypt parallelism")
+	logN := fs.Int("ln", -1, "scrypt CPU/memory cost as log2 N")
+	r := fs.Int("r", -1, "scrypt block size")
+	saltLength := fs.Int("salt", -1, "salt length in bytes")
+	keyLength := fs.Int("len", -1, "hash length in bytes")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	algorithm := pwhash.Algorithm(*alg)
+	p := pwhash.DefaultParams(algorithm)
+	// Flags left at -1 keep the algorithm's defaults.
+	for _, f := range []struct {
+		flag  *int
+		value *int
+	}{
+		{cost, &p.Cost}, {memory, &p.Memory}, {iterations, &p.Iterations}, {parallelism, &p.Parallelism},
+		{logN, &p.LogN}, {r, &p.R}, {saltLength, &p.SaltLength}, {keyLength, &p.KeyLength},
+	} {
+		if *f.flag != -1 {
+			*f.value = *f.flag
+		}
+	}
+	if err := p.Validate(algorithm); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	password, err := readLine(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	hash, err := pwhash.Hash(algorithm, password, p)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, hash)
+	return err
+}
+
+func cliPwhashVerify(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("pwhash verify", stderr)
+	hash := fs.String("hash", "", "bcrypt hash, PHC string or passlib or Django pbkdf2-sha256 hash to check against")
+	maxMemory := fs.Int("max-memory", pwhash.MaxVerifyArgon2Memory>>10, "most memory in MiB an argon2id hash may use")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *hash == "" {
+		return fmt.Errorf("%w: -hash is required", errUsage)
+	}
+
+	password, err := readLine(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	err = pwhash.VerifyMemory(*hash, password, *maxMemory<<10)
+	var limitErr *pwhash.MemoryLimitError
+	if errors.As(err, &limitErr) {
+		return fmt.Errorf("%v, raise -max-memory to verify it anyway", err)
+	}
+	if err != nil {
+		return fmt.Errorf("not a match: %v", err)
+	}
+	_, err = fmt.Fprintln(stdout, "Passwords match")
+	return err
+}
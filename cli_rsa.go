@@ -0,0 +1,251 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"joshu/pkg/rsakeys"
+	"strings"
+)
+
+func cliRSAGen(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rsa gen", stderr)
+	bits := fs.Int("bits", 2048, "key size in bits")
+	privatePath := fs.String("private", "", "write the private key to this file instead of stdout")
+	publicPath := fs.String("public", "", "write the public key to this file instead of stdout")
+	format := fs.String("format", string(rsakeys.PKCS1), "key format: pkcs1, pkcs8, openssh or jwk")
+	passphrase := passphraseFlag(fs, "passphrase", "encrypt the private key (pkcs8 or openssh)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", errUsage, fs.Arg(0))
+	}
+	pass, err := passphrase()
+	if err != nil {
+		return err
+	}
+
+	privateKey, publicKey, err := rsakeys.GenerateWithPassphrase(*bits, rsakeys.KeyFormat(*format), pass)
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(*privatePath, privateKey, 0600, stdout); err != nil {
+		return err
+	}
+	return writeOutput(*publicPath, publicKey, 0644, stdout)
+}
+
+func cliRSAEncrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rsa encrypt", stderr)
+	key := keyFlag(fs, "key", "PEM file containing the RSA public key", "")
+	opts := encryptOptionFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	publicKey, err := key.read()
+	if err != nil {
+		return err
+	}
+
+	message, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	encrypted, err := rsakeys.EncryptWith(string(message), publicKey, *opts)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, encrypted)
+	return err
+}
+
+func cliRSADecrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rsa decrypt", stderr)
+	key := keyFlag(fs, "key", "PEM file containing the RSA private key", "passphrase")
+	opts := encryptOptionFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	privateKey, err := key.read()
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	decrypted, err := rsakeys.DecryptWith(strings.TrimSpace(string(encrypted)), privateKey, *opts)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, decrypted)
+	return err
+}
+
+func cliRSAConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rsa convert", stderr)
+	format := fs.String("format", string(rsakeys.PKCS8), "key format: pkcs1, pkcs8, openssh or jwk")
+	public := fs.Bool("public", false, "output only the public key")
+	passphrase := passphraseFlag(fs, "passphrase", "passphrase of an encrypted input key")
+	newPassphrase := passphraseFlag(fs, "new-passphrase", "encrypt the output key (pkcs8 or openssh)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	pass, err := passphrase()
+	if err != nil {
+		return err
+	}
+	newPass, err := newPassphrase()
+	if err != nil {
+		return err
+	}
+
+	input, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	if *public {
+		publicKey, err := rsakeys.ConvertPublicKey(string(input), rsakeys.KeyFormat(*format))
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(stdout, publicKey)
+		return err
+	}
+	privateKey, _, err := rsakeys.ConvertPrivateKeyWithPassphrase(string(input), pass, rsakeys.KeyFormat(*format), newPass)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, privateKey)
+	return err
+}
+
+// encryptOptionFlags registers the padding flags shared by rsa encrypt and decrypt.
+func encryptOptionFlags(fs *flag.FlagSet) *rsakeys.EncryptOptions {
+	opts := &rsakeys.EncryptOptions{Padding: rsakeys.OAEPPadding, Hash: rsakeys.SHA256}
+	fs.Func("padding", "OAEP (default) or PKCS1v15", func(s string) error {
+		opts.Padding = rsakeys.Padding(s)
+		return nil
+	})
+	fs.Func("hash", "OAEP hash: SHA-1, SHA-256 (default) or SHA-512", func(s string) error {
+		opts.Hash = rsakeys.Hash(s)
+		return nil
+	})
+	fs.StringVar(&opts.Label, "label", "", "OAEP label")
+	return opts
+}
+
+// signOptionFlags registers the scheme flags shared by rsa sign and verify.
+func signOptionFlags(fs *flag.FlagSet) *rsakeys.SignOptions {
+	opts := &rsakeys.SignOptions{Scheme: rsakeys.PSSSignature, Hash: rsakeys.SHA256}
+	fs.Func("scheme", "PSS (default) or PKCS1v15", func(s string) error {
+		opts.Scheme = rsakeys.SignatureScheme(s)
+		return nil
+	})
+	fs.Func("hash", "SHA-256 (default), SHA-384 or SHA-512", func(s string) error {
+		opts.Hash = rsakeys.Hash(s)
+		return nil
+	})
+	return opts
+}
+
+func cliRSASign(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rsa sign", stderr)
+	key := keyFlag(fs, "key", "PEM file containing the RSA private key", "passphrase")
+	opts := signOptionFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	privateKey, err := key.read()
+	if err != nil {
+		return err
+	}
+
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	signature, err := rsakeys.Sign(data, privateKey, *opts)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, signature)
+	return err
+}
+
+func cliRSAVerify(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rsa verify", stderr)
+	key := keyFlag(fs, "key", "PEM file containing the RSA public key", "")
+	signature := fs.String("sig", "", "base64 signature to check")
+	opts := signOptionFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *signature == "" {
+		return fmt.Errorf("%w: -sig is required", errUsage)
+	}
+	publicKey, err := key.read()
+	if err != nil {
+		return err
+	}
+
+	data, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	if err := rsakeys.Verify(data, *signature, publicKey, *opts); err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	_, err = fmt.Fprintln(stdout, "Signature is valid")
+	return err
+}
+
+func cliRSASeal(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rsa seal", stderr)
+	key := keyFlag(fs, "key", "PEM file containing the RSA public key", "")
+	hash := fs.String("hash", string(rsakeys.SHA256), "OAEP hash wrapping the AES key: SHA-1, SHA-256, SHA-384 or SHA-512")
+	format := fs.String("format", string(rsakeys.EnvelopeJSON), "envelope format: json or binary")
+	outPath := fs.String("out", "", "write the envelope to this file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	publicKey, err := key.read()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	envelope, err := rsakeys.Seal(plaintext, publicKey, rsakeys.Hash(*hash), rsakeys.EnvelopeFormat(*format))
+	if err != nil {
+		return err
+	}
+	return writeOutput(*outPath, string(envelope), 0644, stdout)
+}
+
+func cliRSAOpen(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rsa open", stderr)
+	key := keyFlag(fs, "key", "PEM file containing the RSA private key", "passphrase")
+	outPath := fs.String("out", "", "write the plaintext to this file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	privateKey, err := key.read()
+	if err != nil {
+		return err
+	}
+
+	envelope, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	plaintext, err := rsakeys.Open(envelope, privateKey)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*outPath, string(plaintext), 0600, stdout)
+}
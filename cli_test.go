@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"joshu/pkg/bcryptx"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCLI(t *testing.T) {
+	hash, err := bcryptx.Hash("correct horse", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyFiles := func(name string) []string {
+		return []string{"-private", filepath.Join(dir, name+".pem"), "-public", filepath.Join(dir, name+".pub")}
+	}
+	for _, name := range []string{"first", "second"} {
+		args := append([]string{"ec", "gen", "-curve", "Ed25519"}, keyFiles(name)...)
+		if code := runCLI(args, strings.NewReader(""), new(bytes.Buffer), new(bytes.Buffer)); code != exitOK {
+			t.Fatalf("%s returned %d", strings.Join(args, " "), code)
+		}
+	}
+	first, second := keyFiles("first"), keyFiles("second")
+
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		code       int
+		wantStdout string
+		wantStderr string
+	}{
+		{"help", []string{"help"}, "", exitOK, "Usage: joshu", ""},
+		{"flag help", []string{"bcrypt", "hash", "-h"}, "", exitOK, "", "-cost"},
+		{"base64 encode", []string{"base64", "enc"}, "hello", exitOK, "aGVsbG8=\n", ""},
+		{"json minify", []string{"json", "minify"}, "{ \"a\": [1, 2] }", exitOK, `{"a":[1,2]}`, ""},
+		{"bcrypt verify", []string{"bcrypt", "verify", "-hash", hash}, "correct horse\n", exitOK, "Passwords match", ""},
+		{"key match", []string{"key", "match", first[0], first[1], first[2], first[3]}, "", exitOK, "The keys form a pair", ""},
+
+		{"unknown command", []string{"frobnicate"}, "", exitUsage, "", `unknown command "frobnicate"`},
+		{"missing subcommand", []string{"bcrypt"}, "", exitUsage, "", "missing subcommand"},
+		{"unknown subcommand", []string{"bcrypt", "frob"}, "", exitUsage, "", `unknown subcommand "frob"`},
+		{"unknown flag", []string{"bcrypt", "hash", "-strength", "9"}, "", exitUsage, "", "flag provided but not defined: -strength"},
+		{"missing required flag", []string{"bcrypt", "verify"}, "", exitUsage, "", "-hash is required"},
+		{"too many arguments", []string{"json", "pretty", "a.json", "b.json"}, "", exitUsage, "", "too many arguments"},
+
+		{"bcrypt mismatch", []string{"bcrypt", "verify", "-hash", hash}, "wrong horse\n", exitFailure, "", "does not match"},
+		{"pwhash mismatch", []string{"pwhash", "verify", "-hash", hash}, "wrong horse\n", exitFailure, "", "not a match"},
+		{"pwhash memory limit", []string{"pwhash", "verify", "-hash", "$argon2id$v=19$m=2097152,t=1,p=4$c2FsdHNhbHRzYWx0c2FsdA$8nX7hwFEzIB8aPajJTYK8weHQc5Ngz0pFVAKvSu4jQA"}, "password\n", exitFailure, "", "raise -max-memory"},
+		{"key mismatch", []string{"key", "match", first[0], first[1], second[2], second[3]}, "", exitFailure, "", "does not match"},
+		{"missing file", []string{"json", "pretty", filepath.Join(dir, "missing.json")}, "", exitFailure, "", "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := runCLI(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.code {
+				t.Errorf("runCLI() = %d, want %d; stderr %q", code, tt.code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+			if tt.code != exitOK && stdout.Len() != 0 {
+				t.Errorf("stdout = %q after a failure, want nothing", stdout.String())
+			}
+		})
+	}
+}
+
+// TestBcryptBatchReportsSkippedRows checks that the count of skipped rows
+// goes to stderr, keeping stdout to the results.
+func TestBcryptBatchReportsSkippedRows(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	input := "password\n\nhunter2\n"
+	if code := runCLI([]string{"bcrypt", "batch", "-cost", "4"}, strings.NewReader(input), &stdout, &stderr); code != exitOK {
+		t.Fatalf("runCLI() = %d; stderr %q", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "skipped") {
+		t.Errorf("stderr = %q, want the skipped rows reported", stderr.String())
+	}
+	if strings.Contains(stdout.String(), "skipped") {
+		t.Errorf("stdout = %q, want only results", stdout.String())
+	}
+}
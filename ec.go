@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/rsakeys"
+	"time"
+)
+
+// makeECUI builds the elliptic-curve part of the key generator: key
+// generation, the signature test for signing curves and the ECDH demo for
+// key agreement curves. It returns the key boxes, and a function switching
+// the curve and generating a new key pair on it.
+func makeECUI(w fyne.Window) (fyne.CanvasObject, keyPairBoxes, func(eckeys.Curve)) {
+	curve := eckeys.P256
+
+	generateText := canvas.NewText("", theme.ForegroundColor())
+	generateText.TextStyle = fyne.TextStyle{Italic: true}
+
+	setGenerateStatus := func(text string, ok bool) {
+		generateText.Text = text
+		generateText.Color = colornames.Red
+		if ok {
+			generateText.Color = colornames.Green
+		}
+		generateText.Refresh()
+	}
+
+	keyFormatText := canvas.NewText("Format", theme.ForegroundColor())
+	var keyFormats []string
+	for _, f := range eckeys.Formats {
+		keyFormats = append(keyFormats, f.Label())
+	}
+	keyFormat := widget.NewSelect(keyFormats, nil)
+	keyFormat.SetSelectedIndex(0)
+	selectedKeyFormat := func() rsakeys.KeyFormat {
+		return eckeys.Formats[keyFormat.SelectedIndex()]
+	}
+
+	privateKeyTitle := canvas.NewText("Private Key", theme.ForegroundColor())
+	privateKeyTitle.TextSize = 18
+	privateKeyTitle.TextStyle = fyne.TextStyle{Bold: true}
+	publicKeyTitle := canvas.NewText("Public Key", theme.ForegroundColor())
+	publicKeyTitle.TextSize = 18
+	publicKeyTitle.TextStyle = fyne.TextStyle{Bold: true}
+
+	privateKeyTextBox := widget.NewMultiLineEntry()
+	privateKeyTextBox.SetPlaceHolder("Private key")
+	privateKeyTextBox.Wrapping = fyne.TextWrapBreak
+	publicKeyTextBox := widget.NewMultiLineEntry()
+	publicKeyTextBox.SetPlaceHolder("Public key")
+	publicKeyTextBox.Wrapping = fyne.TextWrapBreak
+
+	copyPrivateKeyButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		w.Clipboard().SetContent(privateKeyTextBox.Text)
+	})
+	copyPublicKeyButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		w.Clipboard().SetContent(publicKeyTextBox.Text)
+	})
+
+	generate := func() {
+		startTime := time.Now()
+		privateKey, publicKey, err := eckeys.Generate(curve, selectedKeyFormat())
+		if err != nil {
+			setGenerateStatus(err.Error(), false)
+			return
+		}
+		privateKeyTextBox.SetText(privateKey)
+		publicKeyTextBox.SetText(publicKey)
+		setGenerateStatus(fmt.Sprintf("Generated %s keys in %f seconds", curve.Label(), time.Since(startTime).Seconds()), true)
+	}
+	keyFormat.OnChanged = func(string) { generate() }
+
+	generateButton := widget.NewButton("Generate New Keys", generate)
+	generateButton.Importance = widget.HighImportance
+
+	genKeyContainer := container.NewVBox(
+		container.NewHBox(keyFormatText, keyFormat, generateButton),
+		generateText,
+		container.NewGridWithColumns(2,
+			container.NewBorder(
+				container.NewHBox(privateKeyTitle, copyPrivateKeyButton,
+					makeKeyFileButtons(w, privateKeyTextBox, "private.pem", true)), nil,
+				container.NewGridWrap(fyne.NewSize(1, 300), layout.NewSpacer()),
+				container.NewGridWrap(fyne.NewSize(1, 300), layout.NewSpacer()),
+				privateKeyTextBox,
+			),
+			container.NewBorder(
+				container.NewHBox(publicKeyTitle, copyPublicKeyButton,
+					makeKeyFileButtons(w, publicKeyTextBox, "public.pem", false)), nil,
+				container.NewGridWrap(fyne.NewSize(1, 300), layout.NewSpacer()),
+				container.NewGridWrap(fyne.NewSize(1, 300), layout.NewSpacer()),
+				publicKeyTextBox,
+			),
+		),
+	)
+
+	signContainer := makeECSignUI(privateKeyTextBox, publicKeyTextBox)
+	ecdhContainer, resetECDH := makeECDHUI(privateKeyTextBox, publicKeyTextBox, func() eckeys.Curve { return curve })
+
+	setCurve := func(c eckeys.Curve) {
+		curve = c
+		resetECDH()
+		if curve.CanSign() {
+			signContainer.Show()
+		} else {
+			signContainer.Hide()
+		}
+		if curve.CanAgree() {
+			ecdhContainer.Show()
+		} else {
+			ecdhContainer.Hide()
+		}
+		generate()
+	}
+
+	return container.NewVBox(genKeyContainer, signContainer, ecdhContainer),
+		keyPairBoxes{privateKeyTextBox, publicKeyTextBox}, setCurve
+}
+
+// makeECSignUI builds the ECDSA/Ed25519 sign and verify test.
+func makeECSignUI(privateKeyBox, publicKeyBox *widget.Entry) *fyne.Container {
+	title := canvas.NewText("Signature Test", theme.ForegroundColor())
+	title.TextSize = 24
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	messageInput := widget.NewMultiLineEntry()
+	messageInput.SetPlaceHolder("Enter text to sign")
+	messageInput.Wrapping = fyne.TextWrapBreak
+	messageInput.Text = "This is a test!"
+
+	signatureOutput := widget.NewMultiLineEntry()
+	signatureOutput.SetPlaceHolder("Signature (base64)")
+	signatureOutput.Wrapping = fyne.TextWrapBreak
+
+	signButton := widget.NewButton("Sign", func() {
+		signature, err := eckeys.Sign([]byte(messageInput.Text), privateKeyBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		signatureOutput.SetText(signature)
+		setStatus(fmt.Sprintf("Signed %d bytes", len(messageInput.Text)), true)
+	})
+	signButton.Importance = widget.HighImportance
+
+	verifyButton := widget.NewButton("Verify", func() {
+		if err := eckeys.Verify([]byte(messageInput.Text), signatureOutput.Text, publicKeyBox.Text); err != nil {
+			setStatus(fmt.Sprintf("Invalid signature: %v", err), false)
+			return
+		}
+		setStatus("Signature is valid", true)
+	})
+	verifyButton.Importance = widget.WarningImportance
+
+	return container.NewVBox(
+		title,
+		container.NewGridWithColumns(2,
+			container.NewBorder(nil, signButton,
+				container.NewGridWrap(fyne.NewSize(1, 150), layout.NewSpacer()), nil,
+				messageInput),
+			container.NewBorder(nil, verifyButton,
+				container.NewGridWrap(fyne.NewSize(1, 150), layout.NewSpacer()), nil,
+				signatureOutput),
+		),
+		status,
+	)
+}
+
+// makeECDHUI builds the key agreement demo: a peer key pair is generated on
+// the same curve and both sides derive the shared secret from their own
+// private key and the other side's public key. The returned function clears
+// the peer keys and secrets.
+func makeECDHUI(privateKeyBox, publicKeyBox *widget.Entry, curve func() eckeys.Curve) (*fyne.Container, func()) {
+	title := canvas.NewText("ECDH Key Agreement", theme.ForegroundColor())
+	title.TextSize = 24
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	subTitle := canvas.NewText("Both sides derive the same secret from their private key and the other's public key.",
+		theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	peerPrivateKeyBox := widget.NewMultiLineEntry()
+	peerPrivateKeyBox.SetPlaceHolder("Peer private key")
+	peerPrivateKeyBox.Wrapping = fyne.TextWrapBreak
+	peerPublicKeyBox := widget.NewMultiLineEntry()
+	peerPublicKeyBox.SetPlaceHolder("Peer public key")
+	peerPublicKeyBox.Wrapping = fyne.TextWrapBreak
+
+	ourSecret := widget.NewEntry()
+	ourSecret.SetPlaceHolder("Our shared secret (hex)")
+	peerSecret := widget.NewEntry()
+	peerSecret.SetPlaceHolder("Peer shared secret (hex)")
+
+	peerButton := widget.NewButton("Generate Peer Keys", func() {
+		privateKey, publicKey, err := eckeys.Generate(curve(), rsakeys.PKCS8)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		peerPrivateKeyBox.SetText(privateKey)
+		peerPublicKeyBox.SetText(publicKey)
+		setStatus("Generated a "+curve().Label()+" peer key pair", true)
+	})
+
+	deriveButton := widget.NewButton("Derive Shared Secret", func() {
+		ours, err := eckeys.SharedSecret(privateKeyBox.Text, peerPublicKeyBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		ourSecret.SetText(hex.EncodeToString(ours))
+		if peerPrivateKeyBox.Text == "" {
+			peerSecret.SetText("")
+			setStatus("Derived the shared secret with the peer public key", true)
+			return
+		}
+
+		theirs, err := eckeys.SharedSecret(peerPrivateKeyBox.Text, publicKeyBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		peerSecret.SetText(hex.EncodeToString(theirs))
+		if !bytes.Equal(ours, theirs) {
+			setStatus("The shared secrets differ, the peer keys do not belong together", false)
+			return
+		}
+		setStatus("Both sides derived the same shared secret", true)
+	})
+	deriveButton.Importance = widget.HighImportance
+
+	reset := func() {
+		peerPrivateKeyBox.SetText("")
+		peerPublicKeyBox.SetText("")
+		ourSecret.SetText("")
+		peerSecret.SetText("")
+		setStatus("", true)
+	}
+
+	return container.NewVBox(
+		title,
+		subTitle,
+		container.NewGridWithColumns(2,
+			container.NewBorder(nil, nil,
+				container.NewGridWrap(fyne.NewSize(1, 150), layout.NewSpacer()), nil,
+				peerPrivateKeyBox),
+			container.NewBorder(nil, nil,
+				container.NewGridWrap(fyne.NewSize(1, 150), layout.NewSpacer()), nil,
+				peerPublicKeyBox),
+		),
+		container.NewHBox(peerButton, deriveButton),
+		container.NewGridWithColumns(2, ourSecret, peerSecret),
+		status,
+	), reset
+}
@@ -1,5 +1,9 @@
 module joshu
 
+// Keep the go directive below 1.26, or set GODEBUG cryptocustomrand=1 some
+// other way: newer defaults make rsa.GenerateKey ignore its random reader,
+// and rsakeys.GenerateContext relies on that reader to stop abandoned key
+// searches.
 go 1.22
 
 require (
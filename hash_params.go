@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"joshu/pkg/bcryptx"
+	"joshu/pkg/pwhash"
+	"strconv"
+)
+
+// hashParamsForm is the algorithm selector and parameter entries of the
+// password hashing tool. Only the rows used by the selected algorithm are shown.
+type hashParamsForm struct {
+	content       *fyne.Container
+	algSelect     *widget.Select
+	fields        []*hashParamField
+	preHashSelect *widget.Select
+	preHashRow    *fyne.Container
+	// onChange, when set, is called after the algorithm or pre-hash mode changes.
+	onChange func()
+}
+
+// hashParamField is one labelled parameter entry bound to a Params field.
+type hashParamField struct {
+	row        *fyne.Container
+	entry      *widget.Entry
+	label      string
+	value      func(p *pwhash.Params) *int
+	algorithms []pwhash.Algorithm
+}
+
+func newHashParamsForm() *hashParamsForm {
+	f := &hashParamsForm{}
+	f.fields = []*hashParamField{
+		{label: "Cost", value: func(p *pwhash.Params) *int { return &p.Cost },
+			algorithms: []pwhash.Algorithm{pwhash.Bcrypt}},
+		{label: "Memory (KiB)", value: func(p *pwhash.Params) *int { return &p.Memory },
+			algorithms: []pwhash.Algorithm{pwhash.Argon2id}},
+		{label: "Iterations", value: func(p *pwhash.Params) *int { return &p.Iterations },
+			algorithms: []pwhash.Algorithm{pwhash.Argon2id, pwhash.PBKDF2SHA256}},
+		{label: "CPU/memory cost ln (N = 2^ln)", value: func(p *pwhash.Params) *int { return &p.LogN },
+			algorithms: []pwhash.Algorithm{pwhash.Scrypt}},
+		{label: "Block size r", value: func(p *pwhash.Params) *int { return &p.R },
+			algorithms: []pwhash.Algorithm{pwhash.Scrypt}},
+		{label: "Parallelism", value: func(p *pwhash.Params) *int { return &p.Parallelism },
+			algorithms: []pwhash.Algorithm{pwhash.Argon2id, pwhash.Scrypt}},
+		{label: "Salt length (bytes)", value: func(p *pwhash.Params) *int { return &p.SaltLength },
+			algorithms: []pwhash.Algorithm{pwhash.Argon2id, pwhash.Scrypt, pwhash.PBKDF2SHA256}},
+		{label: "Key length (bytes)", value: func(p *pwhash.Params) *int { return &p.KeyLength },
+			algorithms: []pwhash.Algorithm{pwhash.Argon2id, pwhash.Scrypt, pwhash.PBKDF2SHA256}},
+	}
+
+	rows := container.NewGridWithColumns(2)
+	for _, field := range f.fields {
+		field.entry = widget.NewEntry()
+		field.row = container.NewGridWithColumns(2, widget.NewLabel(field.label), field.entry)
+		rows.Add(field.row)
+	}
+
+	var names []string
+	for _, alg := range pwhash.Algorithms {
+		names = append(names, string(alg))
+	}
+	f.preHashSelect = newPreHashSelect(func() {
+		if f.onChange != nil {
+			f.onChange()
+		}
+	})
+	f.preHashRow = container.NewGridWithColumns(2, widget.NewLabel("Pre-hash"), f.preHashSelect)
+	rows.Add(f.preHashRow)
+
+	f.algSelect = widget.NewSelect(names, func(name string) {
+		f.reset(pwhash.Algorithm(name))
+		if f.onChange != nil {
+			f.onChange()
+		}
+	})
+
+	f.content = container.NewVBox(
+		container.NewGridWithColumns(2, widget.NewLabel("Algorithm"), f.algSelect),
+		rows,
+	)
+	f.algSelect.SetSelected(string(pwhash.Bcrypt))
+	return f
+}
+
+// reset shows the rows used by alg and fills them with its defaults.
+func (f *hashParamsForm) reset(alg pwhash.Algorithm) {
+	defaults := pwhash.DefaultParams(alg)
+	for _, field := range f.fields {
+		if !field.uses(alg) {
+			field.row.Hide()
+			continue
+		}
+		field.entry.SetText(strconv.Itoa(*field.value(&defaults)))
+		field.row.Show()
+	}
+	if alg == pwhash.Bcrypt {
+		f.preHashRow.Show()
+	} else {
+		f.preHashRow.Hide()
+	}
+}
+
+func (f *hashParamsForm) algorithm() pwhash.Algorithm {
+	return pwhash.Algorithm(f.algSelect.Selected)
+}
+
+// params parses the entries of the selected algorithm.
+func (f *hashParamsForm) params() (pwhash.Params, error) {
+	var p pwhash.Params
+	alg := f.algorithm()
+	for _, field := range f.fields {
+		if !field.uses(alg) {
+			continue
+		}
+		n, err := strconv.Atoi(field.entry.Text)
+		if err != nil {
+			return p, fmt.Errorf("%s must be a number", field.label)
+		}
+		*field.value(&p) = n
+	}
+	return p, nil
+}
+
+// preHash returns the pre-hash mode, which only applies to bcrypt.
+func (f *hashParamsForm) preHash() bcryptx.PreHash {
+	if f.algorithm() != pwhash.Bcrypt {
+		return bcryptx.NoPreHash
+	}
+	return bcryptx.PreHash(f.preHashSelect.Selected)
+}
+
+// newPreHashSelect returns a select of the bcrypt pre-hash modes.
+func newPreHashSelect(onChange func()) *widget.Select {
+	var modes []string
+	for _, m := range bcryptx.PreHashes {
+		modes = append(modes, string(m))
+	}
+	s := widget.NewSelect(modes, func(string) { onChange() })
+	s.SetSelected(string(bcryptx.NoPreHash))
+	return s
+}
+
+// useBcryptCost switches to bcrypt with the given cost.
+func (f *hashParamsForm) useBcryptCost(cost int) {
+	f.algSelect.SetSelected(string(pwhash.Bcrypt))
+	f.fields[0].entry.SetText(strconv.Itoa(cost))
+}
+
+func (field *hashParamField) uses(alg pwhash.Algorithm) bool {
+	for _, a := range field.algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
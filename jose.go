@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/jose"
+	"strings"
+	"unicode/utf8"
+)
+
+// Modes of the JOSE tab.
+const (
+	joseSignMode    = "JWS (sign)"
+	joseEncryptMode = "JWE (encrypt)"
+)
+
+// makeJOSEUI builds the JOSE tab: signing and encrypting payloads as JWS
+// and JWE in compact or general JSON serialization, verifying and
+// decrypting them, and a breakdown of their headers and segments.
+// generatedKeys gives the key boxes of the key generator tab.
+func makeJOSEUI(w fyne.Window, generatedKeys func() keyPairBoxes) fyne.CanvasObject {
+	header := makeHeader("JWS / JWE")
+	footer := makeFooter()
+
+	subTitle := canvas.NewText("Sign and encrypt with JWS and JWE (A256GCM) in compact or general JSON serialization, and break them down.",
+		theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	headerBox := widget.NewMultiLineEntry()
+	headerBox.SetPlaceHolder(`Protected header, such as {"cty": "JWT"}`)
+	payloadBox := widget.NewMultiLineEntry()
+	payloadBox.SetPlaceHolder("Payload or plaintext")
+	payloadBox.Wrapping = fyne.TextWrapBreak
+
+	keyBox := widget.NewMultiLineEntry()
+	keyBox.SetPlaceHolder("Secret, PEM or OpenSSH key, certificate, JWK or JWK Set; a JWK Set gives several signatures or recipients")
+	keyBox.Wrapping = fyne.TextWrapBreak
+	passphraseBox := widget.NewPasswordEntry()
+	passphraseBox.SetPlaceHolder("Passphrase of an encrypted private key")
+
+	objectBox := widget.NewMultiLineEntry()
+	objectBox.SetPlaceHolder("Paste a JWS or JWE in compact or JSON serialization")
+	objectBox.Wrapping = fyne.TextWrapBreak
+
+	algorithmNames := func(algs []jose.Algorithm) []string {
+		var names []string
+		for _, alg := range algs {
+			names = append(names, string(alg))
+		}
+		return names
+	}
+	algSelect := widget.NewSelect(algorithmNames(jose.Algorithms), func(string) {})
+	algSelect.SetSelected(string(jose.HS256))
+	serializationSelect := widget.NewSelect([]string{string(jose.Compact), string(jose.GeneralJSON)}, func(string) {})
+	serializationSelect.SetSelected(string(jose.Compact))
+
+	var protectButton, openButton *widget.Button
+	modeRadio := widget.NewRadioGroup([]string{joseSignMode, joseEncryptMode}, nil)
+	modeRadio.Horizontal = true
+	modeRadio.Required = true
+
+	// setMode switches the algorithms and buttons between signing and
+	// encryption, keeping the algorithm when it still applies.
+	setMode := func(mode string) {
+		algs, protect, open := jose.Algorithms, "Sign", "Verify"
+		if mode == joseEncryptMode {
+			algs, protect, open = jose.KeyAlgorithms, "Encrypt", "Decrypt"
+		}
+		selected := algSelect.Selected
+		algSelect.Options = algorithmNames(algs)
+		if !containsString(algSelect.Options, selected) {
+			selected = algSelect.Options[0]
+		}
+		algSelect.SetSelected(selected)
+		protectButton.SetText(protect)
+		openButton.SetText(open)
+	}
+
+	breakdownTitle := canvas.NewText("Breakdown", theme.ForegroundColor())
+	breakdownTitle.TextSize = 18
+	breakdownTitle.TextStyle = fyne.TextStyle{Bold: true}
+	breakdownInfo := widget.NewLabel("")
+	breakdown := container.NewVBox()
+
+	showBreakdown := func(object *jose.Object) {
+		breakdown.Objects = nil
+		kind := "JWS"
+		if object.Encrypted {
+			kind = "JWE"
+		}
+		var algs []string
+		for _, alg := range object.Algorithms() {
+			algs = append(algs, string(alg))
+		}
+		breakdownInfo.SetText(fmt.Sprintf("%s in %s serialization, %d part(s), alg %s", kind, object.Serialization,
+			len(object.Segments), strings.Join(algs, ", ")))
+		for _, segment := range object.Segments {
+			name := canvas.NewText(segment.Name, theme.ForegroundColor())
+			name.TextSize = 14
+			name.TextStyle = fyne.TextStyle{Bold: true}
+			value := widget.NewLabel(describeSegment(segment))
+			value.TextStyle = fyne.TextStyle{Monospace: true}
+			value.Wrapping = fyne.TextWrapBreak
+			breakdown.Add(container.NewVBox(name, value))
+		}
+		breakdown.Refresh()
+	}
+
+	objectBox.OnChanged = func(text string) {
+		if strings.TrimSpace(text) == "" {
+			breakdown.Objects = nil
+			breakdown.Refresh()
+			breakdownInfo.SetText("")
+			return
+		}
+		object, err := jose.ParseObject(text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		showBreakdown(object)
+		mode := joseSignMode
+		if object.Encrypted {
+			mode = joseEncryptMode
+		}
+		modeRadio.SetSelected(mode)
+		if object.Serialization == jose.Compact {
+			serializationSelect.SetSelected(string(jose.Compact))
+		} else {
+			serializationSelect.SetSelected(string(jose.GeneralJSON))
+		}
+		if algs := object.Algorithms(); len(algs) > 0 && containsString(algSelect.Options, string(algs[0])) {
+			algSelect.SetSelected(string(algs[0]))
+		}
+		setStatus("Parsed, not verified or decrypted", true)
+	}
+
+	protectButton = widget.NewButton("Sign", func() {
+		keys, err := jose.ParseKeys(keyBox.Text, passphraseBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		alg := jose.Algorithm(algSelect.Selected)
+		headerJSON, payload := []byte(headerBox.Text), []byte(payloadBox.Text)
+		compact := serializationSelect.Selected == string(jose.Compact)
+		var text string
+		switch {
+		case modeRadio.Selected == joseEncryptMode && compact:
+			text, err = jose.Encrypt(headerJSON, payload, alg, keys)
+		case modeRadio.Selected == joseEncryptMode:
+			text, err = jose.EncryptJSON(headerJSON, payload, alg, keys)
+		case compact:
+			text, err = jose.SignToken(headerJSON, payload, alg, keys)
+		default:
+			text, err = jose.SignJSON(headerJSON, payload, alg, keys)
+		}
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		objectBox.SetText(text)
+		if modeRadio.Selected == joseEncryptMode {
+			setStatus(fmt.Sprintf("Encrypted with %s and %s", alg, jose.A256GCM), true)
+			return
+		}
+		setStatus("Signed with "+string(alg), true)
+	})
+	protectButton.Importance = widget.WarningImportance
+
+	openButton = widget.NewButton("Verify", func() {
+		object, err := jose.ParseObject(objectBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		keys, err := jose.ParseKeys(keyBox.Text, passphraseBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		if object.Encrypted {
+			plaintext, key, err := object.Decrypt(keys)
+			if err != nil {
+				setStatus(err.Error(), false)
+				return
+			}
+			payloadBox.SetText(string(plaintext))
+			message := "Decrypted"
+			if key.KeyID != "" {
+				message += " with kid " + key.KeyID
+			}
+			setStatus(message, true)
+			return
+		}
+		payload, verified, err := object.Verify(keys)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		payloadBox.SetText(string(payload))
+		var results []string
+		for i, key := range verified {
+			switch {
+			case key == nil:
+				results = append(results, fmt.Sprintf("signature %d not verified", i+1))
+			case key.KeyID != "":
+				results = append(results, fmt.Sprintf("signature %d valid (kid %s)", i+1, key.KeyID))
+			default:
+				results = append(results, fmt.Sprintf("signature %d valid", i+1))
+			}
+		}
+		setStatus("Verified: "+strings.Join(results, ", "), true)
+	})
+	openButton.Importance = widget.HighImportance
+
+	modeRadio.OnChanged = setMode
+	modeRadio.SetSelected(joseSignMode)
+
+	// The generated keys fill the key box and pick their usual algorithm
+	// for the mode.
+	generatedKeyButtons := makeGeneratedKeyButtons(generatedKeys, keyBox, setStatus, func(key any) {
+		alg := jose.AlgorithmFor(key)
+		if modeRadio.Selected == joseEncryptMode {
+			alg = jose.KeyAlgorithmFor(key)
+		}
+		if alg != "" {
+			algSelect.SetSelected(string(alg))
+		}
+	})
+
+	copyObjectButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		w.Clipboard().SetContent(objectBox.Text)
+	})
+
+	return container.NewVScroll(container.NewPadded(container.NewBorder(header, footer, layout.NewSpacer(), layout.NewSpacer(),
+		container.NewVBox(
+			subTitle,
+			container.NewHBox(modeRadio, widget.NewLabel("Algorithm"), algSelect,
+				widget.NewLabel("Serialization"), serializationSelect),
+			container.NewGridWithColumns(2,
+				container.NewBorder(nil, nil, container.NewGridWrap(fyne.NewSize(1, 150), layout.NewSpacer()), nil, headerBox),
+				container.NewBorder(nil, nil, container.NewGridWrap(fyne.NewSize(1, 150), layout.NewSpacer()), nil, payloadBox),
+			),
+			container.NewBorder(nil, generatedKeyButtons,
+				container.NewGridWrap(fyne.NewSize(1, 120), layout.NewSpacer()), nil,
+				keyBox),
+			container.NewGridWithColumns(4, widget.NewLabel("Passphrase"), passphraseBox),
+			container.NewHBox(protectButton, openButton),
+			container.NewBorder(nil, nil, nil, copyObjectButton,
+				container.NewBorder(nil, nil, container.NewGridWrap(fyne.NewSize(1, 150), layout.NewSpacer()), nil, objectBox)),
+			status,
+			breakdownTitle,
+			breakdownInfo,
+			breakdown,
+		))))
+}
+
+// describeSegment formats a JWS or JWE part for display: headers as pretty
+// JSON, text as it is, and other bytes as their size and base64url form.
+func describeSegment(segment jose.Segment) string {
+	switch {
+	case segment.Header:
+		return strings.TrimRight(prettyJSON(segment.Decoded), "\n")
+	case len(segment.Decoded) == 0:
+		return "(empty)"
+	case utf8.Valid(segment.Decoded) && strings.IndexFunc(string(segment.Decoded), isControl) < 0:
+		return string(segment.Decoded)
+	case len(segment.Decoded) <= 32:
+		return fmt.Sprintf("%d bytes, hex %s", len(segment.Decoded), hex.EncodeToString(segment.Decoded))
+	}
+	encoded := segment.Encoded
+	if encoded == "" {
+		encoded = base64.RawURLEncoding.EncodeToString(segment.Decoded)
+	}
+	return fmt.Sprintf("%d bytes, %s", len(segment.Decoded), encoded)
+}
+
+// isControl reports control characters other than line breaks and tabs.
+func isControl(r rune) bool {
+	return r < ' ' && r != '\n' && r != '\r' && r != '\t' || r == 0x7f
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
-	"encoding/json"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	jsonrepair "github.com/RealAlexandreAI/json-repair"
+	"joshu/pkg/jsonedit"
 )
 
 const (
@@ -25,8 +24,7 @@ func makeJsonEditorUI(w fyne.Window) fyne.CanvasObject {
 		// one line toolbar
 		widget.NewToolbarAction(theme.MenuIcon(), func() {
 			status = oneLineStatus
-			var oneLineJSON string
-			err := oneLineJson(input.Text, &oneLineJSON)
+			oneLineJSON, err := jsonedit.OneLine(input.Text)
 			if err != nil {
 				return
 			} else {
@@ -37,8 +35,7 @@ func makeJsonEditorUI(w fyne.Window) fyne.CanvasObject {
 		// beautify toolbar
 		widget.NewToolbarAction(theme.ListIcon(), func() {
 			status = beautifyStatus
-			var prettyJSON string
-			err := jsonBeautify(input.Text, &prettyJSON)
+			prettyJSON, err := jsonedit.Beautify(input.Text)
 			if err != nil {
 				return
 			} else {
@@ -62,7 +59,7 @@ func makeJsonEditorUI(w fyne.Window) fyne.CanvasObject {
 			if len(input.Text) == 0 {
 				return
 			}
-			repairedJson, err := jsonrepair.RepairJSON(input.Text)
+			repairedJson, err := jsonedit.Repair(input.Text)
 			if err != nil {
 				return
 			}
@@ -70,8 +67,7 @@ func makeJsonEditorUI(w fyne.Window) fyne.CanvasObject {
 			if status == oneLineStatus {
 				input.SetText(repairedJson)
 			} else {
-				var prettyJSON string
-				err = jsonBeautify(repairedJson, &prettyJSON)
+				prettyJSON, err := jsonedit.Beautify(repairedJson)
 				if err != nil {
 					input.SetText(repairedJson)
 					input.Refresh()
@@ -98,35 +94,3 @@ func makeJsonEditorUI(w fyne.Window) fyne.CanvasObject {
 	paddedContent := container.NewPadded(content)
 	return paddedContent
 }
-
-func jsonBeautify(input string, output *string) error {
-	var jsonObj interface{}
-	err := json.Unmarshal([]byte(input), &jsonObj)
-	if err != nil {
-		return err
-	}
-
-	prettyJSONBytes, err := json.MarshalIndent(jsonObj, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	*output = string(prettyJSONBytes)
-	return nil
-}
-
-func oneLineJson(input string, output *string) error {
-	var jsonObj interface{}
-	err := json.Unmarshal([]byte(input), &jsonObj)
-	if err != nil {
-		return err
-	}
-
-	prettyJSONBytes, err := json.Marshal(jsonObj)
-	if err != nil {
-		return err
-	}
-
-	*output = string(prettyJSONBytes)
-	return nil
-}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/jwk"
+	"strings"
+)
+
+// Choices of the JWK use selector besides the uses themselves.
+const (
+	jwkDefaultUse = "Default"
+	jwkNoUse      = "None"
+)
+
+// jwkAlgorithms are suggested for the alg member; any value may be typed.
+var jwkAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512",
+	"EdDSA", "RSA-OAEP", "RSA-OAEP-256", "ECDH-ES"}
+
+// makeJWKUI builds the JWK tab: conversion between PEM/OpenSSH keys and
+// JWKs with thumbprint key ids, and a JWK Set assembled from several keys.
+func makeJWKUI(w fyne.Window) fyne.CanvasObject {
+	header := makeHeader("JWK")
+	footer := makeFooter()
+
+	subTitle := canvas.NewText("Convert RSA, EC and OKP keys to and from JSON Web Keys and assemble a JWK Set.",
+		theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	input := newKeyInput(w, "Paste a PEM, OpenSSH or JWK key, or a JWK Set")
+
+	kidInput := widget.NewEntry()
+	kidInput.SetPlaceHolder("RFC 7638 thumbprint")
+	useSelect := widget.NewSelect([]string{jwkDefaultUse, jwk.UseSignature, jwk.UseEncryption, jwkNoUse}, func(string) {})
+	useSelect.SetSelected(jwkDefaultUse)
+	algInput := widget.NewSelectEntry(jwkAlgorithms)
+	algInput.SetPlaceHolder("Default for the key")
+	includePrivate := widget.NewCheck("Include private key", func(bool) {})
+
+	output := widget.NewMultiLineEntry()
+	output.SetPlaceHolder("JWK or PEM")
+	output.Wrapping = fyne.TextWrapBreak
+
+	thumbprint := widget.NewLabel("")
+	copyThumbprintButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		w.Clipboard().SetContent(thumbprint.Text)
+	})
+
+	// convert reads the input key and the options; keys are made public
+	// unless the private key is included.
+	convert := func() (jwk.Key, error) {
+		key, err := jwk.ParseKeyText(string(input.data()), "")
+		if err != nil {
+			return jwk.Key{}, err
+		}
+		if !includePrivate.Checked {
+			key = jwk.Public(key)
+		}
+		params := jwk.DefaultParams(key)
+		switch useSelect.Selected {
+		case jwkNoUse:
+			params.Use = ""
+		case jwk.UseSignature, jwk.UseEncryption:
+			params.Use = useSelect.Selected
+		}
+		if alg := strings.TrimSpace(algInput.Text); alg != "" {
+			params.Alg = alg
+		}
+		digest, err := jwk.Thumbprint(key)
+		if err != nil {
+			return jwk.Key{}, err
+		}
+		thumbprint.SetText(digest)
+		params.KeyID = strings.TrimSpace(kidInput.Text)
+		if params.KeyID == "" {
+			params.KeyID = digest
+		}
+		return jwk.Key{Key: key, Params: params}, nil
+	}
+
+	toJWKButton := widget.NewButton("To JWK", func() {
+		key, err := convert()
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		text, err := jwk.Marshal(key.Key, key.Params)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		output.SetText(text)
+		setStatus("Converted to JWK with kid "+key.KeyID, true)
+	})
+	toJWKButton.Importance = widget.HighImportance
+
+	toPEMButton := widget.NewButton("To PEM", func() {
+		keys, err := jwk.Parse(input.data())
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		var b strings.Builder
+		for _, key := range keys {
+			k := key.Key
+			if !includePrivate.Checked {
+				k = jwk.Public(k)
+			}
+			text, err := jwk.EncodePEM(k)
+			if err != nil {
+				setStatus(err.Error(), false)
+				return
+			}
+			// Text before a PEM block is ignored by PEM readers.
+			if key.KeyID != "" {
+				fmt.Fprintf(&b, "kid: %s\n", key.KeyID)
+			}
+			b.WriteString(text)
+		}
+		output.SetText(b.String())
+		setStatus(fmt.Sprintf("Converted %d key(s) to PEM", len(keys)), true)
+	})
+
+	setTitle := canvas.NewText("JWK Set", theme.ForegroundColor())
+	setTitle.TextSize = 18
+	setTitle.TextStyle = fyne.TextStyle{Bold: true}
+
+	setBox := widget.NewMultiLineEntry()
+	setBox.SetPlaceHolder(`{"keys": []}`)
+	setBox.Wrapping = fyne.TextWrapBreak
+
+	// The set box is the state of the set, so it may be pasted or edited.
+	addButton := widget.NewButtonWithIcon("Add to Set", theme.ContentAddIcon(), func() {
+		key, err := convert()
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		var keys []jwk.Key
+		if strings.TrimSpace(setBox.Text) != "" {
+			if keys, err = jwk.Parse([]byte(setBox.Text)); err != nil {
+				setStatus("JWK Set: "+err.Error(), false)
+				return
+			}
+		}
+		replaced := false
+		for i := range keys {
+			if keys[i].KeyID == key.KeyID {
+				keys[i], replaced = key, true
+			}
+		}
+		if !replaced {
+			keys = append(keys, key)
+		}
+		text, err := jwk.MarshalSet(keys)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		setBox.SetText(text)
+		if replaced {
+			setStatus(fmt.Sprintf("Replaced the key with kid %s, the set has %d key(s)", key.KeyID, len(keys)), true)
+			return
+		}
+		setStatus(fmt.Sprintf("Added the key with kid %s, the set has %d key(s)", key.KeyID, len(keys)), true)
+	})
+	clearButton := widget.NewButtonWithIcon("Clear", theme.ContentClearIcon(), func() {
+		setBox.SetText("")
+	})
+	copyOutputButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		w.Clipboard().SetContent(output.Text)
+	})
+	copySetButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		w.Clipboard().SetContent(setBox.Text)
+	})
+
+	return container.NewVScroll(container.NewPadded(container.NewBorder(header, footer, layout.NewSpacer(), layout.NewSpacer(),
+		container.NewVBox(
+			subTitle,
+			container.NewGridWithColumns(4,
+				widget.NewLabel("Key ID"), kidInput,
+				widget.NewLabel("Use"), useSelect,
+				widget.NewLabel("Algorithm"), algInput,
+				includePrivate,
+			),
+			container.NewGridWithColumns(2,
+				input.content,
+				container.NewBorder(nil, container.NewHBox(copyOutputButton, makeKeyFileButtons(w, output, "key.json", true)),
+					container.NewGridWrap(fyne.NewSize(1, 200), layout.NewSpacer()), nil,
+					output),
+			),
+			container.NewHBox(toJWKButton, toPEMButton, addButton),
+			container.NewBorder(nil, nil, widget.NewLabel("Thumbprint"), copyThumbprintButton, thumbprint),
+			status,
+			setTitle,
+			container.NewBorder(nil, container.NewHBox(clearButton, copySetButton, makeKeyFileButtons(w, setBox, "jwks.json", false)),
+				container.NewGridWrap(fyne.NewSize(1, 300), layout.NewSpacer()), nil,
+				setBox),
+		))))
+}
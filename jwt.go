@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/jose"
+	"joshu/pkg/jsonedit"
+	"joshu/pkg/jwk"
+	"strings"
+	"time"
+)
+
+// jwtTimeLabels names the NumericDate claims shown under the payload.
+var jwtTimeLabels = map[string]string{
+	"iat": "Issued at",
+	"nbf": "Not before",
+	"exp": "Expires",
+}
+
+// makeJWTUI builds the JWT tab: decoding a token into its header and
+// payload, verifying its signature, and signing an edited payload.
+// generatedKeys gives the key boxes of the key generator tab.
+func makeJWTUI(w fyne.Window, generatedKeys func() keyPairBoxes) fyne.CanvasObject {
+	header := makeHeader("JWT")
+	footer := makeFooter()
+
+	subTitle := canvas.NewText("Decode, verify and sign JSON Web Tokens with a secret, a key or a JWK Set.",
+		theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	tokenBox := widget.NewMultiLineEntry()
+	tokenBox.SetPlaceHolder("Paste a JWT")
+	tokenBox.Wrapping = fyne.TextWrapBreak
+
+	now := time.Now().Unix()
+	headerBox := widget.NewMultiLineEntry()
+	headerBox.SetPlaceHolder("Header")
+	headerBox.SetText("{\n    \"typ\": \"JWT\"\n}")
+	payloadBox := widget.NewMultiLineEntry()
+	payloadBox.SetPlaceHolder("Payload")
+	payloadBox.SetText(fmt.Sprintf("{\n    \"sub\": \"1234567890\",\n    \"name\": \"John Doe\",\n    \"iat\": %d,\n    \"exp\": %d\n}",
+		now, now+3600))
+
+	times := container.NewVBox()
+	showTimes := func(payload string) {
+		times.Objects = nil
+		for _, claim := range jose.TimeClaims([]byte(payload)) {
+			label := widget.NewLabel(fmt.Sprintf("%s (%s): %s, %s", jwtTimeLabels[claim.Name], claim.Name,
+				claim.Time.Local().Format("2006-01-02 15:04:05 MST"), relativeTime(claim.Time)))
+			times.Add(label)
+		}
+		times.Refresh()
+	}
+	payloadBox.OnChanged = showTimes
+	showTimes(payloadBox.Text)
+
+	keyBox := widget.NewMultiLineEntry()
+	keyBox.SetPlaceHolder("HMAC secret, PEM or OpenSSH key, certificate, JWK or JWK Set")
+	keyBox.Wrapping = fyne.TextWrapBreak
+	passphraseBox := widget.NewPasswordEntry()
+	passphraseBox.SetPlaceHolder("Passphrase of an encrypted private key")
+
+	var algorithms []string
+	for _, alg := range jose.Algorithms {
+		algorithms = append(algorithms, string(alg))
+	}
+	algSelect := widget.NewSelect(algorithms, func(string) {})
+	algSelect.SetSelected(string(jose.HS256))
+
+	decode := func(text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		token, err := jose.DecodeToken(text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		headerBox.SetText(prettyJSON(token.Header))
+		payloadBox.SetText(prettyJSON(token.Payload))
+		if containsString(algorithms, string(token.Alg)) {
+			algSelect.SetSelected(string(token.Alg))
+		}
+		if problems := jose.CheckTimes(token.Payload, time.Now()); len(problems) > 0 {
+			setStatus("Decoded, not verified. The token is "+strings.Join(problems, " and "), false)
+			return
+		}
+		setStatus("Decoded, not verified", true)
+	}
+	tokenBox.OnChanged = decode
+
+	verifyButton := widget.NewButton("Verify", func() {
+		token, err := jose.DecodeToken(tokenBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		keys, err := jose.ParseKeys(keyBox.Text, passphraseBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		key, err := token.Verify(keys)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		message := fmt.Sprintf("The %s signature is valid", token.Alg)
+		if key.KeyID != "" {
+			message += ", verified with kid " + key.KeyID
+		}
+		if problems := jose.CheckTimes(token.Payload, time.Now()); len(problems) > 0 {
+			setStatus(message+", but the token is "+strings.Join(problems, " and "), false)
+			return
+		}
+		setStatus(message, true)
+	})
+	verifyButton.Importance = widget.HighImportance
+
+	signButton := widget.NewButton("Sign", func() {
+		keys, err := jose.ParseKeys(keyBox.Text, passphraseBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		token, err := jose.SignToken([]byte(headerBox.Text), []byte(payloadBox.Text), jose.Algorithm(algSelect.Selected), keys)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		tokenBox.SetText(token)
+		setStatus("Signed with "+algSelect.Selected, true)
+	})
+	signButton.Importance = widget.WarningImportance
+
+	generatedKeyButtons := makeGeneratedKeyButtons(generatedKeys, keyBox, setStatus, func(key any) {
+		if alg := jose.AlgorithmFor(key); alg != "" {
+			algSelect.SetSelected(string(alg))
+		}
+	})
+
+	copyTokenButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		w.Clipboard().SetContent(tokenBox.Text)
+	})
+
+	headerTitle := canvas.NewText("Header", theme.ForegroundColor())
+	headerTitle.TextSize = 14
+	headerTitle.TextStyle = fyne.TextStyle{Bold: true}
+	payloadTitle := canvas.NewText("Payload", theme.ForegroundColor())
+	payloadTitle.TextSize = 14
+	payloadTitle.TextStyle = fyne.TextStyle{Bold: true}
+
+	return container.NewVScroll(container.NewPadded(container.NewBorder(header, footer, layout.NewSpacer(), layout.NewSpacer(),
+		container.NewVBox(
+			subTitle,
+			container.NewBorder(nil, nil, nil, copyTokenButton,
+				container.NewBorder(nil, nil, container.NewGridWrap(fyne.NewSize(1, 120), layout.NewSpacer()), nil, tokenBox)),
+			container.NewGridWithColumns(2,
+				container.NewBorder(headerTitle, nil,
+					container.NewGridWrap(fyne.NewSize(1, 250), layout.NewSpacer()), nil,
+					headerBox),
+				container.NewBorder(payloadTitle, times,
+					container.NewGridWrap(fyne.NewSize(1, 250), layout.NewSpacer()), nil,
+					payloadBox),
+			),
+			container.NewBorder(nil, generatedKeyButtons,
+				container.NewGridWrap(fyne.NewSize(1, 150), layout.NewSpacer()), nil,
+				keyBox),
+			container.NewGridWithColumns(4, widget.NewLabel("Passphrase"), passphraseBox),
+			container.NewHBox(widget.NewLabel("Algorithm"), algSelect, verifyButton, signButton),
+			status,
+		))))
+}
+
+// makeGeneratedKeyButtons returns buttons copying the private or public key
+// of the key generator tab into keyBox, then passing the parsed public key to
+// picked; the public key is read since the private key may be encrypted.
+func makeGeneratedKeyButtons(generatedKeys func() keyPairBoxes, keyBox *widget.Entry,
+	setStatus func(string, bool), picked func(key any)) *fyne.Container {
+	useGeneratedKey := func(private bool) {
+		boxes := generatedKeys()
+		text := boxes.public.Text
+		if private {
+			text = boxes.private.Text
+		}
+		if strings.TrimSpace(text) == "" {
+			setStatus("Generate a key in the Key Generator tab first", false)
+			return
+		}
+		keyBox.SetText(text)
+		if key, err := jwk.ParseKeyText(boxes.public.Text, ""); err == nil {
+			picked(key)
+		}
+	}
+	return container.NewHBox(
+		widget.NewButton("Use Generated Private Key", func() { useGeneratedKey(true) }),
+		widget.NewButton("Use Generated Public Key", func() { useGeneratedKey(false) }),
+	)
+}
+
+// prettyJSON formats JSON the way the JSON editor does, leaving other data
+// as it is.
+func prettyJSON(data []byte) string {
+	if !json.Valid(data) {
+		return string(data)
+	}
+	text, err := jsonedit.Beautify(string(data))
+	if err != nil {
+		return string(data)
+	}
+	return text
+}
+
+// relativeTime describes t relative to now, such as "in 59m0s" or "2h0m0s
+// ago"; spans over two days are counted in days.
+func relativeTime(t time.Time) string {
+	d := time.Until(t).Round(time.Second)
+	span := d
+	if span < 0 {
+		span = -span
+	}
+	text := span.String()
+	if span >= 48*time.Hour {
+		text = fmt.Sprintf("%d days", span/(24*time.Hour))
+	}
+	if d >= 0 {
+		return "in " + text
+	}
+	return text + " ago"
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
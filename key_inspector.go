@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"io"
+	"joshu/pkg/keyinspect"
+	"unicode/utf8"
+)
+
+func makeKeyInspectorUI(w fyne.Window) fyne.CanvasObject {
+	header := makeHeader("Key Inspector")
+	footer := makeFooter()
+
+	subTitle := canvas.NewText("Paste or load a PEM, DER, OpenSSH or JWK key, certificate request or certificate.",
+		theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	results := container.NewVBox()
+
+	input := newKeyInput(w, "Paste a key, certificate request or certificate")
+
+	inspectButton := widget.NewButton("Inspect", func() {
+		results.Objects = nil
+		results.Refresh()
+		reports, err := keyinspect.Inspect(input.data())
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		for _, report := range reports {
+			results.Add(makeKeyReportCard(w, report))
+		}
+		setStatus(fmt.Sprintf("Found %d object(s)", len(reports)), true)
+	})
+	inspectButton.Importance = widget.HighImportance
+
+	return container.NewVScroll(container.NewPadded(container.NewBorder(header, footer, layout.NewSpacer(), layout.NewSpacer(),
+		container.NewVBox(
+			subTitle,
+			input.content,
+			inspectButton,
+			status,
+			results,
+			makeKeyMatchUI(w),
+		))))
+}
+
+// makeKeyReportCard shows the fields of report with a copy button for each
+// value, followed by its warnings.
+func makeKeyReportCard(w fyne.Window, report keyinspect.Report) fyne.CanvasObject {
+	rows := container.NewVBox()
+	for _, field := range report.Fields() {
+		value := field.Value
+		valueLabel := widget.NewLabel(value)
+		valueLabel.Wrapping = fyne.TextWrapBreak
+		copyButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+			w.Clipboard().SetContent(value)
+		})
+		rows.Add(container.NewBorder(nil, nil,
+			container.NewGridWrap(fyne.NewSize(180, 36), widget.NewLabelWithStyle(field.Name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})),
+			copyButton, valueLabel))
+	}
+	for _, warning := range report.Warnings {
+		text := canvas.NewText("Warning: "+warning, colornames.Red)
+		text.TextSize = 14
+		text.TextStyle = fyne.TextStyle{Italic: true}
+		rows.Add(text)
+	}
+	return widget.NewCard(report.Title(), "", rows)
+}
+
+// makeKeyMatchUI builds the key pair check.
+func makeKeyMatchUI(w fyne.Window) fyne.CanvasObject {
+	title := canvas.NewText("Key Pair Check", theme.ForegroundColor())
+	title.TextSize = 18
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	subTitle := canvas.NewText("Check that a private key belongs to a public key, certificate request or certificate.",
+		theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	privateInput := newKeyInput(w, "Private key")
+	publicInput := newKeyInput(w, "Public key, certificate request or certificate")
+
+	checkButton := widget.NewButton("Check Pair", func() {
+		err := keyinspect.Match(privateInput.data(), publicInput.data())
+		switch {
+		case errors.Is(err, keyinspect.ErrKeyMismatch):
+			setStatus("The keys do not form a pair", false)
+		case err != nil:
+			setStatus(err.Error(), false)
+		default:
+			setStatus("The keys form a pair", true)
+		}
+	})
+	checkButton.Importance = widget.HighImportance
+
+	return container.NewVBox(
+		title,
+		subTitle,
+		container.NewGridWithColumns(2, privateInput.content, publicInput.content),
+		checkButton,
+		status,
+	)
+}
+
+// keyInput is a text box for pasted keys with a button loading a file.
+// Binary DER files cannot be shown, so they are held aside until the text
+// is edited.
+type keyInput struct {
+	content *fyne.Container
+	entry   *widget.Entry
+	der     []byte
+}
+
+func newKeyInput(w fyne.Window, placeHolder string) *keyInput {
+	in := &keyInput{entry: widget.NewMultiLineEntry()}
+	in.entry.SetPlaceHolder(placeHolder)
+	in.entry.Wrapping = fyne.TextWrapBreak
+	in.entry.OnChanged = func(string) {
+		in.der = nil
+		in.entry.SetPlaceHolder(placeHolder)
+	}
+
+	loadButton := widget.NewButtonWithIcon("Load File", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if utf8.Valid(data) {
+				in.entry.SetText(string(data))
+				return
+			}
+			in.entry.SetText("")
+			in.entry.SetPlaceHolder(fmt.Sprintf("%s (%d bytes of binary DER)", reader.URI().Name(), len(data)))
+			in.der = data
+		}, w)
+	})
+
+	in.content = container.NewBorder(nil, loadButton,
+		container.NewGridWrap(fyne.NewSize(1, 200), layout.NewSpacer()), nil,
+		in.entry)
+	return in
+}
+
+// data returns the loaded DER file, or the text in the box.
+func (in *keyInput) data() []byte {
+	if in.der != nil {
+		return in.der
+	}
+	return []byte(in.entry.Text)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/keystore"
+	"joshu/pkg/rsakeys"
+)
+
+// rsaKeyType is the key type selector entry for the RSA tools; the other
+// entries are eckeys curve labels.
+const rsaKeyType = "RSA"
+
+// makeKeysUI builds the key generator tab, switching between the RSA tools
+// and the elliptic-curve tools with a key type selector, above the keystore.
+// It returns a function giving the key boxes of the selected key type, for
+// other tabs to use. Nothing is generated until the last returned function
+// is called, when the tab is first shown.
+func makeKeysUI(w fyne.Window) (fyne.CanvasObject, func() keyPairBoxes, func()) {
+	header := makeHeader("Key Generator")
+	footer := makeFooter()
+
+	rsaContent, rsaBoxes, generateInitialKeys := makeRSAUI(w)
+	ecContent, ecBoxes, setCurve := makeECUI(w)
+	ecContent.Hide()
+
+	keyTypes := []string{rsaKeyType}
+	for _, c := range eckeys.Curves {
+		keyTypes = append(keyTypes, c.Label())
+	}
+	keyTypeText := canvas.NewText("Key Type", theme.ForegroundColor())
+	keyType := widget.NewSelect(keyTypes, func(string) {})
+	keyType.SetSelected(rsaKeyType)
+	keyType.OnChanged = func(string) {
+		index := keyType.SelectedIndex()
+		if index == 0 {
+			ecContent.Hide()
+			rsaContent.Show()
+			return
+		}
+		setCurve(eckeys.Curves[index-1])
+		rsaContent.Hide()
+		ecContent.Show()
+	}
+
+	current := func() keyPairBoxes {
+		if keyType.SelectedIndex() == 0 {
+			return rsaBoxes
+		}
+		return ecBoxes
+	}
+	// recall switches to the key type of a stored pair before filling in
+	// its boxes, since switching curves generates new keys.
+	recall := func(entry keystore.Entry) error {
+		boxes := rsaBoxes
+		if _, err := rsakeys.ParsePublicKey(entry.PublicKey); err == nil {
+			keyType.SetSelected(rsaKeyType)
+		} else {
+			_, curve, err := eckeys.ParsePublicKey(entry.PublicKey)
+			if err != nil {
+				return err
+			}
+			keyType.SetSelected(curve.Label())
+			boxes = ecBoxes
+		}
+		boxes.private.SetText(entry.PrivateKey)
+		boxes.public.SetText(entry.PublicKey)
+		return nil
+	}
+
+	return container.NewVScroll(container.NewPadded(container.NewBorder(header, footer, layout.NewSpacer(), layout.NewSpacer(),
+		container.NewVBox(
+			container.NewHBox(keyTypeText, keyType),
+			rsaContent,
+			ecContent,
+			makeKeystoreUI(w, current, recall),
+		)))), current, generateInitialKeys
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"io"
+	"joshu/pkg/keystore"
+	"os"
+	"strings"
+)
+
+// keyPairBoxes are the private and public key text boxes of a generator.
+type keyPairBoxes struct {
+	private, public *widget.Entry
+}
+
+// makeKeyFileButtons returns buttons loading box from a file and saving it
+// to one. Saved private keys are made readable by the owner only.
+func makeKeyFileButtons(w fyne.Window, box *widget.Entry, fileName string, private bool) *fyne.Container {
+	openButton := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			box.SetText(string(data))
+		}, w)
+	})
+
+	saveButton := widget.NewButtonWithIcon("", theme.DocumentSaveIcon(), func() {
+		if strings.TrimSpace(box.Text) == "" {
+			dialog.ShowError(errors.New("there is no key to save"), w)
+			return
+		}
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+
+			if private && writer.URI().Scheme() == "file" {
+				if err := os.Chmod(writer.URI().Path(), 0600); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+			}
+			if _, err := io.WriteString(writer, box.Text); err != nil {
+				dialog.ShowError(err, w)
+			}
+		}, w)
+		save.SetFileName(fileName)
+		save.Show()
+	})
+	return container.NewHBox(openButton, saveButton)
+}
+
+// makeKeystoreUI builds the keystore panel. current returns the key pair
+// shown in the generator, which Store Current Keys saves; recall shows a
+// stored pair in the matching generator.
+func makeKeystoreUI(w fyne.Window, current func() keyPairBoxes, recall func(keystore.Entry) error) fyne.CanvasObject {
+	title := canvas.NewText("Keystore", theme.ForegroundColor())
+	title.TextSize = 24
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	path, err := keystore.DefaultPath()
+	if err != nil {
+		setStatus(err.Error(), false)
+		return container.NewVBox(title, status)
+	}
+
+	subTitle := canvas.NewText("Keys are kept in "+path+", encrypted with a master passphrase.",
+		theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	var store *keystore.Store
+	entries := container.NewVBox()
+
+	passphraseInput := widget.NewPasswordEntry()
+	passphraseInput.SetPlaceHolder("Master passphrase")
+	labelInput := widget.NewEntry()
+	labelInput.SetPlaceHolder("Label for the current keys")
+
+	var showEntries func()
+	showEntries = func() {
+		entries.Objects = nil
+		for _, entry := range store.Entries() {
+			entry := entry
+			loadButton := widget.NewButtonWithIcon("Load", theme.UploadIcon(), func() {
+				if err := recall(entry); err != nil {
+					setStatus(err.Error(), false)
+					return
+				}
+				setStatus(fmt.Sprintf("Loaded %q", entry.Label), true)
+			})
+			deleteButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				dialog.ShowConfirm("Delete Key", fmt.Sprintf("Delete %q from the keystore? This cannot be undone.", entry.Label),
+					func(ok bool) {
+						if !ok {
+							return
+						}
+						if err := store.Remove(entry.ID); err != nil {
+							setStatus(err.Error(), false)
+							return
+						}
+						showEntries()
+						setStatus(fmt.Sprintf("Deleted %q", entry.Label), true)
+					}, w)
+			})
+
+			kind := entry.Algorithm
+			if entry.Bits > 0 {
+				kind = fmt.Sprintf("%s %d bit", entry.Algorithm, entry.Bits)
+			}
+			details := widget.NewLabel(fmt.Sprintf("%s, created %s\n%s", kind,
+				entry.Created.Local().Format("2006-01-02 15:04"), entry.Fingerprint))
+			details.Wrapping = fyne.TextWrapBreak
+			entries.Add(container.NewBorder(nil, nil,
+				container.NewGridWrap(fyne.NewSize(240, 36), widget.NewLabelWithStyle(entry.Label, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})),
+				container.NewHBox(loadButton, deleteButton),
+				details))
+		}
+		if len(entries.Objects) == 0 {
+			entries.Add(widget.NewLabel("The keystore is empty."))
+		}
+		entries.Refresh()
+	}
+
+	storeButton := widget.NewButtonWithIcon("Store Current Keys", theme.DocumentSaveIcon(), func() {
+		boxes := current()
+		entry, err := store.Add(labelInput.Text, boxes.private.Text, boxes.public.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		labelInput.SetText("")
+		showEntries()
+		setStatus(fmt.Sprintf("Stored %q", entry.Label), true)
+	})
+	storeButton.Importance = widget.HighImportance
+
+	var unlockButton *widget.Button
+	var unlockedRow *fyne.Container
+	lockButton := widget.NewButtonWithIcon("Lock", theme.VisibilityOffIcon(), func() {
+		store = nil
+		entries.Objects = nil
+		entries.Refresh()
+		unlockedRow.Hide()
+		passphraseInput.Enable()
+		unlockButton.Enable()
+		setStatus("Locked", true)
+	})
+	unlockedRow = container.NewBorder(nil, nil, nil, container.NewHBox(storeButton, lockButton), labelInput)
+	unlockedRow.Hide()
+
+	unlockButton = widget.NewButtonWithIcon("Unlock", theme.LoginIcon(), func() {
+		var err error
+		if keystore.Exists(path) {
+			store, err = keystore.Open(path, passphraseInput.Text)
+		} else {
+			store, err = keystore.Create(path, passphraseInput.Text)
+		}
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		passphraseInput.SetText("")
+		passphraseInput.SetPlaceHolder("Master passphrase")
+		passphraseInput.Disable()
+		unlockButton.Disable()
+		unlockedRow.Show()
+		showEntries()
+		setStatus(fmt.Sprintf("Unlocked, %d key(s) stored", len(store.Entries())), true)
+	})
+	passphraseInput.OnSubmitted = func(string) { unlockButton.OnTapped() }
+	if !keystore.Exists(path) {
+		passphraseInput.SetPlaceHolder("Choose a master passphrase to create the keystore")
+	}
+
+	return container.NewVBox(
+		title,
+		subTitle,
+		container.NewBorder(nil, nil, nil, unlockButton, passphraseInput),
+		unlockedRow,
+		status,
+		entries,
+	)
+}
@@ -4,19 +4,39 @@ import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"os"
 )
 
 func main() {
-	a := app.New()
+	// Any argument selects a headless subcommand, see cliUsage.
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	a := app.NewWithID("me.toannv.joshu")
 	w := a.NewWindow("助手 - Developer's Assistant")
 
+	keysUI, generatedKeys, showKeys := makeKeysUI(w)
+	keysTab := container.NewTabItem("Key Generator", keysUI)
+
 	tabs := container.NewAppTabs(
 		container.NewTabItem("Json Editor", makeJsonEditorUI(w)),
 		container.NewTabItem("Base 64", makeBase64UI(w)),
 		container.NewTabItem("Password Generator", makeRandomPasswordUI(w)),
-		container.NewTabItem("Bcrypt", makeBcryptUI(w)),
-		container.NewTabItem("RSA Generator", makeRSAUI(w)),
+		container.NewTabItem("Password Hashing", makePasswordHashingUI(w)),
+		keysTab,
+		container.NewTabItem("Key Inspector", makeKeyInspectorUI(w)),
+		container.NewTabItem("Certificates", makeCertificatesUI(w)),
+		container.NewTabItem("JWK", makeJWKUI(w)),
+		container.NewTabItem("JWT", makeJWTUI(w, generatedKeys)),
+		container.NewTabItem("JWS / JWE", makeJOSEUI(w, generatedKeys)),
 	)
+	// Key generation is slow, so it waits until the tab is opened.
+	tabs.OnSelected = func(tab *container.TabItem) {
+		if tab == keysTab {
+			showKeys()
+		}
+	}
 
 	tabs.SetTabLocation(container.TabLocationLeading)
 	w.SetContent(tabs)
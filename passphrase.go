@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/passgen"
+	"strconv"
+)
+
+func makePassphraseUI(w fyne.Window) fyne.CanvasObject {
+	title := canvas.NewText("Passphrases", theme.ForegroundColor())
+	title.TextSize = 18
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	subTitle := canvas.NewText("Diceware style passphrases built from random words. Entropy assumes the wordlist is known.", theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	wordlist := passgen.DefaultWordlist()
+	wordlistText := canvas.NewText(fmt.Sprintf("Built-in wordlist (%d words)", len(wordlist)), theme.ForegroundColor())
+	wordlistText.TextSize = 14
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+	status.Color = colornames.Red
+
+	wordCountInput := widget.NewEntry()
+	wordCountInput.SetPlaceHolder("Number of words")
+	wordCountInput.Text = "6"
+
+	separatorInput := widget.NewEntry()
+	separatorInput.SetPlaceHolder("Separator")
+	separatorInput.Text = "-"
+
+	var capitalizations []string
+	for _, c := range passgen.Capitalizations {
+		capitalizations = append(capitalizations, string(c))
+	}
+	capitalizationSelect := widget.NewSelect(capitalizations, func(string) {})
+	capitalizationSelect.SetSelectedIndex(0)
+
+	digitCheck := widget.NewCheck("Add a digit", nil)
+	symbolCheck := widget.NewCheck("Add a symbol", nil)
+
+	phraseBlock := container.NewGridWithColumns(3)
+
+	generate := func() {
+		count, err := strconv.Atoi(wordCountInput.Text)
+		if err != nil {
+			status.Text = fmt.Sprintf("Invalid number of words: %v", err)
+			status.Refresh()
+			return
+		}
+		opts := passgen.PassphraseOptions{
+			Words:          count,
+			Separator:      separatorInput.Text,
+			Capitalization: passgen.Capitalization(capitalizationSelect.Selected),
+			AddDigit:       digitCheck.Checked,
+			AddSymbol:      symbolCheck.Checked,
+			Wordlist:       wordlist,
+		}
+
+		var phrases []fyne.CanvasObject
+		for i := 0; i < 3; i++ {
+			phrase, err := passgen.GeneratePassphrase(opts)
+			if err != nil {
+				status.Text = err.Error()
+				status.Refresh()
+				return
+			}
+
+			phraseText := widget.NewLabel(phrase.Phrase)
+			phraseText.Wrapping = fyne.TextWrapBreak
+			phraseText.Alignment = fyne.TextAlignCenter
+
+			entropyText := canvas.NewText(fmt.Sprintf("%.1f bits of entropy", phrase.Entropy), theme.ForegroundColor())
+			entropyText.TextSize = 12
+			entropyText.TextStyle = fyne.TextStyle{Italic: true}
+			entropyText.Alignment = fyne.TextAlignCenter
+
+			copyButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+				w.Clipboard().SetContent(phraseText.Text)
+			})
+
+			phrases = append(phrases, container.NewVBox(phraseText, entropyText, copyButton))
+		}
+		status.Text = ""
+		status.Refresh()
+		phraseBlock.Objects = phrases
+		phraseBlock.Refresh()
+	}
+
+	generateButton := widget.NewButton("Generate Passphrases", generate)
+	generateButton.Importance = widget.HighImportance
+
+	loadButton := widget.NewButtonWithIcon("Load Wordlist", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+
+			list, err := passgen.ParseWordlist(reader)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			wordlist = list
+			wordlistText.Text = fmt.Sprintf("%s (%d words)", reader.URI().Name(), len(wordlist))
+			wordlistText.Refresh()
+			generate()
+		}, w)
+	})
+
+	resetButton := widget.NewButtonWithIcon("Built-in Wordlist", theme.ViewRefreshIcon(), func() {
+		wordlist = passgen.DefaultWordlist()
+		wordlistText.Text = fmt.Sprintf("Built-in wordlist (%d words)", len(wordlist))
+		wordlistText.Refresh()
+		generate()
+	})
+
+	generate()
+
+	return container.NewVBox(
+		title,
+		subTitle,
+		container.NewGridWithColumns(4,
+			wordCountInput,
+			separatorInput,
+			capitalizationSelect,
+			container.NewGridWithColumns(2, digitCheck, symbolCheck),
+		),
+		container.NewHBox(wordlistText, loadButton, resetButton),
+		generateButton,
+		status,
+		phraseBlock,
+	)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/bcryptx"
+	"joshu/pkg/passgen"
+	"strconv"
+	"sync"
+)
+
+// maxBulkPasswords bounds a single bulk run to keep the preview responsive.
+const maxBulkPasswords = 100000
+
+// makeBulkPasswordUI builds the bulk generation section. The returned
+// function updates the policy choices after policies are edited.
+func makeBulkPasswordUI(w fyne.Window, policies []passgen.Policy) (fyne.CanvasObject, func([]passgen.Policy)) {
+	title := canvas.NewText("Bulk Generate", theme.ForegroundColor())
+	title.TextSize = 18
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	subTitle := canvas.NewText("Generate many credentials at once and export them, optionally with bcrypt hashes for seeding a database.", theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	policySelect := widget.NewSelect(policyNames(policies), func(string) {})
+	policySelect.PlaceHolder = "Policy"
+	if len(policies) > 0 {
+		policySelect.SetSelectedIndex(0)
+	}
+
+	countInput := widget.NewEntry()
+	countInput.SetPlaceHolder("Count")
+	countInput.Text = "100"
+
+	hashCheck := widget.NewCheck("Include bcrypt hashes", nil)
+	costInput := widget.NewEntry()
+	costInput.SetPlaceHolder("bcrypt cost")
+	costInput.Text = strconv.Itoa(bcryptx.DefaultCost)
+
+	var formats []string
+	for _, f := range passgen.ExportFormats {
+		formats = append(formats, string(f))
+	}
+	formatSelect := widget.NewSelect(formats, func(string) {})
+	formatSelect.SetSelectedIndex(0)
+
+	progress := widget.NewProgressBar()
+	progress.Hide()
+
+	// credentials is replaced by the hashing goroutine while the list and
+	// the export buttons read it, so it is only accessed under mu.
+	var (
+		mu          sync.Mutex
+		credentials []passgen.Credential
+	)
+	current := func() []passgen.Credential {
+		mu.Lock()
+		defer mu.Unlock()
+		return credentials
+	}
+	preview := widget.NewList(
+		func() int { return len(current()) },
+		func() fyne.CanvasObject {
+			return container.NewGridWithColumns(2, widget.NewLabel(""), widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			shown := current()
+			if id >= len(shown) {
+				return
+			}
+			row := item.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(shown[id].Password)
+			row.Objects[1].(*widget.Label).SetText(shown[id].Hash)
+		},
+	)
+	// publish replaces the credentials, which are not modified afterwards,
+	// and shows them.
+	publish := func(generated []passgen.Credential) {
+		mu.Lock()
+		credentials = generated
+		mu.Unlock()
+		preview.Refresh()
+	}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	var generateButton *widget.Button
+	generateButton = widget.NewButton("Generate", func() {
+		var policy passgen.Policy
+		found := false
+		for _, p := range policies {
+			if p.Name == policySelect.Selected {
+				policy, found = p, true
+			}
+		}
+		if !found {
+			setStatus("Select a policy", false)
+			return
+		}
+		count, err := strconv.Atoi(countInput.Text)
+		if err != nil || count < 1 || count > maxBulkPasswords {
+			setStatus(fmt.Sprintf("Count must be between 1 and %d", maxBulkPasswords), false)
+			return
+		}
+		cost, err := strconv.Atoi(costInput.Text)
+		if hashCheck.Checked && err != nil {
+			setStatus(fmt.Sprintf("Invalid bcrypt cost: %v", err), false)
+			return
+		}
+
+		passwords, err := passgen.GenerateBulk(policy, count)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+
+		generated := make([]passgen.Credential, len(passwords))
+		for i, p := range passwords {
+			generated[i].Password = p
+		}
+		if !hashCheck.Checked {
+			publish(generated)
+			setStatus(fmt.Sprintf("Generated %d passwords", count), true)
+			return
+		}
+
+		// Hashing hundreds of passwords takes a while, keep the window responsive.
+		generateButton.Disable()
+		progress.Max = float64(count)
+		progress.SetValue(0)
+		progress.Show()
+		setStatus("Hashing...", true)
+		go func() {
+			defer generateButton.Enable()
+			defer progress.Hide()
+
+			hashes, err := bcryptx.HashAll(passwords, cost, 0, func(done int) {
+				progress.SetValue(float64(done))
+			})
+			if err != nil {
+				setStatus(err.Error(), false)
+				return
+			}
+			for i := range generated {
+				generated[i].Hash = hashes[i]
+			}
+			publish(generated)
+			setStatus(fmt.Sprintf("Generated and hashed %d passwords", count), true)
+		}()
+	})
+	generateButton.Importance = widget.HighImportance
+
+	exportButton := widget.NewButtonWithIcon("Export", theme.DocumentSaveIcon(), func() {
+		snapshot := current()
+		if len(snapshot) == 0 {
+			setStatus("Generate some passwords first", false)
+			return
+		}
+		format := passgen.ExportFormat(formatSelect.Selected)
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+
+			if err := passgen.Export(writer, format, snapshot); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			setStatus(fmt.Sprintf("Exported %d credentials to %s", len(snapshot), writer.URI().Name()), true)
+		}, w)
+		save.SetFileName("passwords." + string(format))
+		save.SetFilter(storage.NewExtensionFileFilter([]string{"." + string(format)}))
+		save.Show()
+	})
+
+	copyButton := widget.NewButtonWithIcon("Copy", theme.ContentCopyIcon(), func() {
+		snapshot := current()
+		var buf bytes.Buffer
+		if err := passgen.Export(&buf, passgen.ExportFormat(formatSelect.Selected), snapshot); err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		w.Clipboard().SetContent(buf.String())
+		setStatus(fmt.Sprintf("Copied %d credentials", len(snapshot)), true)
+	})
+
+	updatePolicies := func(updated []passgen.Policy) {
+		policies = updated
+		policySelect.Options = policyNames(policies)
+		policySelect.ClearSelected()
+		if len(policies) > 0 {
+			policySelect.SetSelectedIndex(0)
+		}
+		policySelect.Refresh()
+	}
+
+	content := container.NewVBox(
+		title,
+		subTitle,
+		container.NewGridWithColumns(4,
+			policySelect,
+			countInput,
+			container.NewGridWithColumns(2, hashCheck, costInput),
+			generateButton,
+		),
+		progress,
+		status,
+		container.NewBorder(nil, nil,
+			container.NewGridWrap(fyne.NewSize(1, 250), layout.NewSpacer()), nil,
+			preview),
+		container.NewHBox(widget.NewLabel("Format"), formatSelect, exportButton, copyButton),
+	)
+	return content, updatePolicies
+}
@@ -1,74 +1,340 @@
 package main
 
 import (
-	"crypto/rand"
+	"encoding/json"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"math/big"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/passgen"
+	"strconv"
+	"strings"
 )
 
-const (
-	lowerCase = "abcdefghijklmnopqrstuvwxyz"
-	upperCase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	numbers   = "1234567890"
-	special   = "`~!@#$%^&*()-=_+[]{}|;':\",./<>?"
-	hexChars  = "123456789ABCDEF"
-)
+// passwordPoliciesKey is the preference holding the saved policies as JSON.
+const passwordPoliciesKey = "passwordPolicies"
+
+// loadPasswordPolicies returns the saved policies, or the built-in presets
+// when nothing has been saved yet.
+func loadPasswordPolicies() []passgen.Policy {
+	saved := fyne.CurrentApp().Preferences().String(passwordPoliciesKey)
+	if saved == "" {
+		return append([]passgen.Policy(nil), passgen.DefaultPolicies...)
+	}
+
+	var policies []passgen.Policy
+	if err := json.Unmarshal([]byte(saved), &policies); err != nil {
+		return append([]passgen.Policy(nil), passgen.DefaultPolicies...)
+	}
+	return policies
+}
 
-var passwordTypes = map[string]string{
-	"Memorable Passwords":         "memorable_pwd",
-	"Strong Passwords":            "strong_pwd",
-	"Fort Knox Passwords":         "ft_knox_pwd",
-	"CodeIgniter Encryption Keys": "ci_key",
-	"160-bit WPA Key":             "160_wpa",
-	"504-bit WPA Key":             "504_wpa",
-	"64-bit WEP Keys":             "64_wep",
-	"128-bit WEP Keys":            "128_wep",
-	"152-bit WEP Keys":            "152_wep",
-	"256-bit WEP Keys":            "256_wep",
+func savePasswordPolicies(policies []passgen.Policy) error {
+	data, err := json.Marshal(policies)
+	if err != nil {
+		return err
+	}
+	fyne.CurrentApp().Preferences().SetString(passwordPoliciesKey, string(data))
+	return nil
 }
 
-var supportPasswordTypes = []string{
-	"Memorable Passwords",
-	"Strong Passwords",
-	"Fort Knox Passwords",
-	"CodeIgniter Encryption Keys",
-	"160-bit WPA Key",
-	"504-bit WPA Key",
-	"64-bit WEP Keys",
-	"128-bit WEP Keys",
-	"152-bit WEP Keys",
-	"256-bit WEP Keys",
+func policyNames(policies []passgen.Policy) []string {
+	var names []string
+	for _, p := range policies {
+		names = append(names, p.Name)
+	}
+	return names
 }
 
 func makeRandomPasswordUI(w fyne.Window) fyne.CanvasObject {
 	header := makeHeader("Password Generator")
 	footer := makeFooter()
 
+	policies := loadPasswordPolicies()
+
 	var passwordBlock *fyne.Container
 	generateButton := widget.NewButton("Generate Passwords and Keys", func() {
-		generatePassword(passwordBlock)
+		generatePassword(passwordBlock, policies)
 		passwordBlock.Refresh()
 	})
 
-	passwordBlock = makePasswordUI(w)
+	passwordBlock = makePasswordUI(w, policies)
+
+	bulkGenerator, updateBulkPolicies := makeBulkPasswordUI(w, policies)
+
+	policyEditor := makePolicyEditorUI(func(updated []passgen.Policy) {
+		policies = updated
+		passwordBlock.Objects = makePasswordUI(w, policies).Objects
+		passwordBlock.Refresh()
+		updateBulkPolicies(policies)
+	})
 
 	content := container.NewBorder(header, footer, nil, nil,
-		container.NewVBox(generateButton, passwordBlock))
+		container.NewVBox(policyEditor, generateButton, passwordBlock, bulkGenerator, makePassphraseUI(w), makePasswordStrengthUI()))
 	paddedContent := container.NewPadded(content)
 	scrollable := container.NewVScroll(paddedContent)
 	return scrollable
 }
 
-func generateRandomPasswords(count int, passType string) []string {
+// makePolicyEditorUI builds the form used to create, edit and delete saved
+// policies. onChange is called with the new list after every save.
+func makePolicyEditorUI(onChange func([]passgen.Policy)) fyne.CanvasObject {
+	title := canvas.NewText("Password Policies", theme.ForegroundColor())
+	title.TextSize = 18
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	subTitle := canvas.NewText("Pick a policy to edit it, or type a new name to create one.", theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	nameInput := widget.NewEntry()
+	nameInput.SetPlaceHolder("Policy name")
+	lengthInput := widget.NewEntry()
+	lengthInput.SetPlaceHolder("Length")
+
+	lowerCheck := widget.NewCheck("Lowercase", nil)
+	upperCheck := widget.NewCheck("Uppercase", nil)
+	numbersCheck := widget.NewCheck("Numbers", nil)
+	specialCheck := widget.NewCheck("Special", nil)
+	hexCheck := widget.NewCheck("Hex", nil)
+
+	minLowerInput := widget.NewEntry()
+	minLowerInput.SetPlaceHolder("Min lowercase")
+	minUpperInput := widget.NewEntry()
+	minUpperInput.SetPlaceHolder("Min uppercase")
+	minNumbersInput := widget.NewEntry()
+	minNumbersInput.SetPlaceHolder("Min numbers")
+	minSpecialInput := widget.NewEntry()
+	minSpecialInput.SetPlaceHolder("Min special")
+
+	customInput := widget.NewEntry()
+	customInput.SetPlaceHolder("Custom characters")
+	excludeInput := widget.NewEntry()
+	excludeInput.SetPlaceHolder("Excluded characters")
+
+	ambiguousCheck := widget.NewCheck(fmt.Sprintf("Exclude ambiguous (%s)", passgen.AmbiguousChars), nil)
+	noRepeatCheck := widget.NewCheck("No repeated characters", nil)
+	startLetterCheck := widget.NewCheck("Must start with a letter", nil)
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	showPolicy := func(p passgen.Policy) {
+		nameInput.SetText(p.Name)
+		lengthInput.SetText(strconv.Itoa(p.Length))
+		lowerCheck.SetChecked(p.UseLower)
+		upperCheck.SetChecked(p.UseUpper)
+		numbersCheck.SetChecked(p.UseNumbers)
+		specialCheck.SetChecked(p.UseSpecial)
+		hexCheck.SetChecked(p.UseHex)
+		minLowerInput.SetText(formatMin(p.MinLower))
+		minUpperInput.SetText(formatMin(p.MinUpper))
+		minNumbersInput.SetText(formatMin(p.MinNumbers))
+		minSpecialInput.SetText(formatMin(p.MinSpecial))
+		customInput.SetText(p.Custom)
+
+		exclude := p.Exclude
+		ambiguous := containsAll(exclude, passgen.AmbiguousChars)
+		if ambiguous {
+			exclude = removeChars(exclude, passgen.AmbiguousChars)
+		}
+		ambiguousCheck.SetChecked(ambiguous)
+		excludeInput.SetText(exclude)
+		noRepeatCheck.SetChecked(p.NoRepeat)
+		startLetterCheck.SetChecked(p.StartWithLetter)
+	}
+
+	readPolicy := func() (passgen.Policy, error) {
+		p := passgen.Policy{
+			Name:            strings.TrimSpace(nameInput.Text),
+			UseLower:        lowerCheck.Checked,
+			UseUpper:        upperCheck.Checked,
+			UseNumbers:      numbersCheck.Checked,
+			UseSpecial:      specialCheck.Checked,
+			UseHex:          hexCheck.Checked,
+			Custom:          customInput.Text,
+			Exclude:         excludeInput.Text,
+			NoRepeat:        noRepeatCheck.Checked,
+			StartWithLetter: startLetterCheck.Checked,
+		}
+		if p.Name == "" {
+			return p, fmt.Errorf("policy name is required")
+		}
+		if ambiguousCheck.Checked {
+			p.Exclude += passgen.AmbiguousChars
+		}
+
+		var err error
+		if p.Length, err = strconv.Atoi(lengthInput.Text); err != nil {
+			return p, fmt.Errorf("invalid length: %v", err)
+		}
+		for _, field := range []struct {
+			input *widget.Entry
+			value *int
+		}{
+			{minLowerInput, &p.MinLower},
+			{minUpperInput, &p.MinUpper},
+			{minNumbersInput, &p.MinNumbers},
+			{minSpecialInput, &p.MinSpecial},
+		} {
+			if *field.value, err = parseMin(field.input.Text); err != nil {
+				return p, err
+			}
+		}
+		return p, p.Validate()
+	}
+
+	policies := loadPasswordPolicies()
+	policySelect := widget.NewSelect(policyNames(policies), func(name string) {
+		for _, p := range policies {
+			if p.Name == name {
+				showPolicy(p)
+				setStatus("", true)
+				return
+			}
+		}
+	})
+	policySelect.PlaceHolder = "Saved policies"
+
+	update := func(updated []passgen.Policy, message string) {
+		if err := savePasswordPolicies(updated); err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		policies = updated
+		policySelect.Options = policyNames(policies)
+		policySelect.Refresh()
+		setStatus(message, true)
+		onChange(policies)
+	}
+
+	saveButton := widget.NewButtonWithIcon("Save Policy", theme.DocumentSaveIcon(), func() {
+		p, err := readPolicy()
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+
+		updated := append([]passgen.Policy(nil), policies...)
+		replaced := false
+		for i := range updated {
+			if updated[i].Name == p.Name {
+				p.Key = updated[i].Key
+				updated[i] = p
+				replaced = true
+			}
+		}
+		if !replaced {
+			updated = append(updated, p)
+		}
+		update(updated, fmt.Sprintf("Saved policy %q", p.Name))
+		// Set directly to keep the status message, SetSelected would reload the form.
+		policySelect.Selected = p.Name
+		policySelect.Refresh()
+	})
+	saveButton.Importance = widget.HighImportance
+
+	deleteButton := widget.NewButtonWithIcon("Delete Policy", theme.DeleteIcon(), func() {
+		name := strings.TrimSpace(nameInput.Text)
+		var updated []passgen.Policy
+		for _, p := range policies {
+			if p.Name != name {
+				updated = append(updated, p)
+			}
+		}
+		if len(updated) == len(policies) {
+			setStatus(fmt.Sprintf("No saved policy named %q", name), false)
+			return
+		}
+		policySelect.ClearSelected()
+		update(updated, fmt.Sprintf("Deleted policy %q", name))
+	})
+	deleteButton.Importance = widget.WarningImportance
+
+	resetButton := widget.NewButtonWithIcon("Restore Defaults", theme.ViewRefreshIcon(), func() {
+		policySelect.ClearSelected()
+		update(append([]passgen.Policy(nil), passgen.DefaultPolicies...), "Restored the built-in policies")
+	})
+
+	if len(policies) > 0 {
+		showPolicy(policies[0])
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Policy", policySelect),
+		widget.NewFormItem("Name", nameInput),
+		widget.NewFormItem("Length", lengthInput),
+		widget.NewFormItem("Classes", container.NewGridWithColumns(5,
+			lowerCheck, upperCheck, numbersCheck, specialCheck, hexCheck)),
+		widget.NewFormItem("Minimums", container.NewGridWithColumns(5,
+			minLowerInput, minUpperInput, minNumbersInput, minSpecialInput)),
+		widget.NewFormItem("Custom", customInput),
+		widget.NewFormItem("Exclude", container.NewGridWithColumns(2, excludeInput, ambiguousCheck)),
+		widget.NewFormItem("Rules", container.NewGridWithColumns(2, noRepeatCheck, startLetterCheck)),
+	)
+
+	return container.NewVBox(
+		title,
+		subTitle,
+		form,
+		container.NewGridWithColumns(3, saveButton, deleteButton, resetButton),
+		status,
+	)
+}
+
+func formatMin(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func parseMin(s string) (int, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid minimum %q", s)
+	}
+	return n, nil
+}
+
+func containsAll(s, chars string) bool {
+	for _, c := range chars {
+		if !strings.ContainsRune(s, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func removeChars(s, chars string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(chars, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func generateRandomPasswords(count int, policy passgen.Policy) []string {
 	var passwords []string
 	for i := 0; i < count; i++ {
-		pt := passwordTypes[passType]
-		key, err := GetKey(pt)
+		key, err := passgen.Generate(policy)
 		if err != nil {
 			continue
 		}
@@ -77,13 +343,13 @@ func generateRandomPasswords(count int, passType string) []string {
 	return passwords
 }
 
-func makePasswordUI(w fyne.Window) *fyne.Container {
+func makePasswordUI(w fyne.Window, policies []passgen.Policy) *fyne.Container {
 	content := container.NewVBox()
-	// Generate 3 random passwords for each type
-	for _, pt := range supportPasswordTypes {
-		passwords := generateRandomPasswords(3, pt)
+	// Generate 3 random passwords for each policy
+	for _, policy := range policies {
+		passwords := generateRandomPasswords(3, policy)
 
-		blockTitle := canvas.NewText(pt, theme.ForegroundColor())
+		blockTitle := canvas.NewText(policy.Name, theme.ForegroundColor())
 		blockTitle.TextSize = 14
 		blockTitle.TextStyle = fyne.TextStyle{Bold: true}
 
@@ -100,7 +366,7 @@ func makePasswordUI(w fyne.Window) *fyne.Container {
 			})
 			coppyButton.Resize(fyne.NewSize(20, 20))
 
-			passwordTextContainer := container.NewVBox(passwordText, coppyButton)
+			passwordTextContainer := container.NewVBox(passwordText, makeStrengthText(pw), coppyButton)
 			passBlocks = append(passBlocks, passwordTextContainer)
 		}
 
@@ -115,77 +381,14 @@ func makePasswordUI(w fyne.Window) *fyne.Container {
 	return content
 }
 
-func generatePassword(content *fyne.Container) {
-	for _, block := range content.Objects {
-		blockTitle := block.(*fyne.Container).Objects[0].(*canvas.Text).Text
-		passwords := generateRandomPasswords(3, blockTitle)
-		for i, pw := range passwords {
-			passwordText := block.(*fyne.Container).Objects[1].(*fyne.Container).Objects[i].(*fyne.Container).Objects[0].(*widget.Label)
+func generatePassword(content *fyne.Container, policies []passgen.Policy) {
+	for i, block := range content.Objects {
+		passwords := generateRandomPasswords(3, policies[i])
+		for j, pw := range passwords {
+			passwordContainer := block.(*fyne.Container).Objects[1].(*fyne.Container).Objects[j].(*fyne.Container)
+			passwordText := passwordContainer.Objects[0].(*widget.Label)
 			passwordText.Text = pw
+			setStrengthText(passwordContainer.Objects[1].(*canvas.Text), pw)
 		}
 	}
 }
-
-// Random returns a random float64 between 0 and 1.
-func Random() float64 {
-	maxNumber := big.NewInt(1 << 53)
-	n, _ := rand.Int(rand.Reader, maxNumber)
-	return float64(n.Int64()) / (1 << 53)
-}
-
-// KeyGen generates a random key of the specified length.
-func KeyGen(length int, useLowerCase, useUpperCase, useNumbers, useSpecial, useHex bool) string {
-	var chars string
-	var key string
-
-	if useLowerCase {
-		chars += lowerCase
-	}
-	if useUpperCase {
-		chars += upperCase
-	}
-	if useNumbers {
-		chars += numbers
-	}
-	if useSpecial {
-		chars += special
-	}
-	if useHex {
-		chars += hexChars
-	}
-
-	for i := 0; i < length; i++ {
-		index := int(Random() * float64(len(chars)))
-		key += string(chars[index])
-	}
-
-	return key
-}
-
-// GetKey returns a key based on the strength specified.
-func GetKey(strength string) (string, error) {
-	switch strength {
-	case "memorable_pwd":
-		return KeyGen(10, true, true, true, false, false), nil
-	case "strong_pwd":
-		return KeyGen(15, true, true, true, true, false), nil
-	case "ft_knox_pwd":
-		return KeyGen(30, true, true, true, true, false), nil
-	case "ci_key":
-		return KeyGen(32, true, true, true, false, false), nil
-	case "160_wpa":
-		return KeyGen(20, true, true, true, true, false), nil
-	case "504_wpa":
-		return KeyGen(63, true, true, true, true, false), nil
-	case "64_wep":
-		return KeyGen(5, false, false, false, false, true), nil
-	case "128_wep":
-		return KeyGen(13, false, false, false, false, true), nil
-	case "152_wep":
-		return KeyGen(16, false, false, false, false, true), nil
-	case "256_wep":
-		return KeyGen(29, false, false, false, false, true), nil
-	default:
-		return "", fmt.Errorf("no such strength \"%s\"", strength)
-	}
-}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"joshu/pkg/bcryptx"
+	"joshu/pkg/pwhash"
+	"strings"
+	"time"
+)
+
+func makePasswordHashingUI(w fyne.Window) fyne.CanvasObject {
+	header := makeHeader("Password Hashing")
+	footer := makeFooter()
+
+	EncryptTile := canvas.NewText("Hash", theme.ForegroundColor())
+	EncryptTile.TextSize = 18
+	EncryptTile.TextStyle = fyne.TextStyle{Bold: true}
+
+	EncryptSubTitle := canvas.NewText("Hash some text. bcrypt gives a $2a$ hash, Argon2id, scrypt and PBKDF2 give a PHC string.", theme.ForegroundColor())
+	EncryptSubTitle.TextSize = 14
+	EncryptSubTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	EncryptInput := widget.NewEntry()
+	EncryptInput.SetPlaceHolder("Enter text to hash")
+
+	params := newHashParamsForm()
+
+	inputWarning := canvas.NewText("", colornames.Red)
+	inputWarning.TextSize = 14
+	inputWarning.TextStyle = fyne.TextStyle{Italic: true}
+
+	// bcrypt only uses the first 72 bytes, flag inputs it would reject or
+	// that other services may hash differently.
+	checkInput := func() {
+		inputWarning.Text = ""
+		if params.algorithm() == pwhash.Bcrypt && params.preHash() == bcryptx.NoPreHash {
+			inputWarning.Text = strings.Join(bcryptx.CheckPassword(EncryptInput.Text), "; ")
+		}
+		inputWarning.Refresh()
+	}
+	EncryptInput.OnChanged = func(string) { checkInput() }
+	params.onChange = checkInput
+
+	EncryptOutput := canvas.NewText("", theme.ForegroundColor())
+	EncryptOutput.TextSize = 14
+	EncryptOutput.TextStyle = fyne.TextStyle{Italic: true}
+	EncryptOutput.Color = colornames.Red
+
+	coppyButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		clipboard := w.Clipboard()
+		clipboard.SetContent(EncryptOutput.Text)
+	})
+
+	encryptTask := newHashTask(EncryptOutput, "Hashing...")
+	var EncryptButton *widget.Button
+	EncryptButton = widget.NewButton("Hash", func() {
+		password := EncryptInput.Text
+		alg := params.algorithm()
+		p, err := params.params()
+		if err == nil {
+			err = p.Validate(alg)
+		}
+		if err != nil {
+			setOutputText(EncryptOutput, err.Error(), false)
+			return
+		}
+
+		password, err = params.preHash().Apply(password)
+		if err != nil {
+			setOutputText(EncryptOutput, err.Error(), false)
+			return
+		}
+
+		encryptTask.run(EncryptButton, func(ctx context.Context) (string, bool) {
+			hash, err := pwhash.HashContext(ctx, alg, password, p)
+			if err != nil {
+				return bcryptx.Explain(err), false
+			}
+			return hash, true
+		})
+	})
+	EncryptButton.Importance = widget.HighImportance
+
+	encryptContent := container.NewVBox(
+		EncryptTile,
+		EncryptSubTitle,
+		params.content,
+		container.NewGridWithColumns(2, EncryptInput,
+			container.NewGridWithColumns(2,
+				EncryptButton,
+				encryptTask.cancelButton,
+			),
+		),
+		inputWarning,
+		container.NewGridWithColumns(2,
+			EncryptOutput,
+			coppyButton,
+		),
+	)
+
+	DecryptTile := canvas.NewText("Verify", theme.ForegroundColor())
+	DecryptTile.TextSize = 18
+	DecryptTile.TextStyle = fyne.TextStyle{Bold: true}
+
+	DecryptSubTitle := canvas.NewText("Test a bcrypt hash, PHC string or passlib or Django PBKDF2 hash against some plaintext. The algorithm is detected from the hash.", theme.ForegroundColor())
+	DecryptSubTitle.TextSize = 14
+	DecryptSubTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	DecryptHashInput := widget.NewEntry()
+	DecryptHashInput.SetPlaceHolder("Hash to check")
+
+	DecryptInput := widget.NewEntry()
+	DecryptInput.SetPlaceHolder("String to check against")
+
+	hashInfo := widget.NewLabel("")
+	hashInfo.TextStyle = fyne.TextStyle{Monospace: true}
+	hashInfo.Wrapping = fyne.TextWrapWord
+	hashInfo.Hide()
+
+	hashWarning := canvas.NewText("", colornames.Red)
+	hashWarning.TextSize = 14
+	hashWarning.TextStyle = fyne.TextStyle{Italic: true}
+
+	// bcrypt hashes from PHP and Node services may need a pre-hash mode or a
+	// different version prefix.
+	verifyPreHash := newPreHashSelect(func() {})
+	var convertSelect *widget.Select
+	convertSelect = widget.NewSelect(bcryptx.Prefixes, func(prefix string) {
+		if prefix == "" {
+			return
+		}
+		converted, err := bcryptx.ConvertPrefix(DecryptHashInput.Text, prefix)
+		convertSelect.ClearSelected()
+		if err != nil {
+			hashWarning.Text = err.Error()
+			hashWarning.Refresh()
+			return
+		}
+		DecryptHashInput.SetText(converted)
+	})
+	convertSelect.PlaceHolder = "Convert to version"
+	bcryptOptions := container.NewGridWithColumns(4,
+		widget.NewLabel("Pre-hash"), verifyPreHash,
+		widget.NewLabel("Version"), convertSelect,
+	)
+	bcryptOptions.Hide()
+
+	DecryptHashInput.OnChanged = func(hash string) {
+		info, err := pwhash.Inspect(hash)
+		bcryptOptions.Hide()
+		switch {
+		case strings.TrimSpace(hash) == "":
+			hashInfo.Hide()
+			hashWarning.Text = ""
+		case err != nil:
+			hashInfo.Hide()
+			hashWarning.Text = err.Error()
+		default:
+			hashInfo.SetText(describeHash(info))
+			hashInfo.Show()
+			hashWarning.Text = strings.Join(info.Warnings(), "; ")
+			if info.Algorithm == pwhash.Bcrypt {
+				bcryptOptions.Show()
+			}
+		}
+		hashWarning.Refresh()
+	}
+
+	DecryptOutput := canvas.NewText("", theme.ForegroundColor())
+	DecryptOutput.TextSize = 14
+	DecryptOutput.TextStyle = fyne.TextStyle{Italic: true}
+
+	decryptTask := newHashTask(DecryptOutput, "Checking...")
+	var DecryptButton *widget.Button
+	DecryptButton = widget.NewButton("Verify", func() {
+		hash := DecryptHashInput.Text
+		password := DecryptInput.Text
+		var warnings []string
+		if alg, err := pwhash.Detect(hash); err == nil && alg == pwhash.Bcrypt {
+			password, err = bcryptx.PreHash(verifyPreHash.Selected).Apply(password)
+			if err != nil {
+				setOutputText(DecryptOutput, err.Error(), false)
+				return
+			}
+			warnings = bcryptx.CheckPassword(password)
+		}
+
+		verify := func(maxMemory int) {
+			decryptTask.run(DecryptButton, func(ctx context.Context) (string, bool) {
+				err := pwhash.VerifyContext(ctx, hash, password, maxMemory)
+				if errors.Is(err, pwhash.ErrMismatch) {
+					return "Not a match!", false
+				}
+				if err != nil {
+					return "Not a match! " + bcryptx.Explain(err), false
+				}
+				if len(warnings) > 0 {
+					return fmt.Sprintf("Passwords match, but %s", strings.Join(warnings, "; ")), true
+				}
+				return "Passwords match", true
+			})
+		}
+		// A pasted Argon2id hash may ask for up to 2 GiB, so confirm first.
+		if info, err := pwhash.Inspect(hash); err == nil && info.Algorithm == pwhash.Argon2id && info.Params.Memory > pwhash.MaxVerifyArgon2Memory {
+			message := fmt.Sprintf("Verifying this hash needs %d MiB of memory.\nVerify it anyway?", info.Params.Memory>>10)
+			dialog.ShowConfirm("Large hash", message, func(ok bool) {
+				if ok {
+					verify(pwhash.MaxArgon2Memory)
+				}
+			}, w)
+			return
+		}
+		verify(pwhash.MaxVerifyArgon2Memory)
+	})
+	DecryptButton.Importance = widget.HighImportance
+
+	decryptContent := container.NewVBox(
+		DecryptTile,
+		DecryptSubTitle,
+		DecryptHashInput,
+		hashInfo,
+		hashWarning,
+		bcryptOptions,
+		DecryptInput,
+		container.NewGridWithColumns(2, DecryptButton, decryptTask.cancelButton),
+		DecryptOutput,
+	)
+
+	content := container.NewBorder(header, footer, nil, nil,
+		container.NewVBox(
+			encryptContent,
+			decryptContent,
+			makeBcryptBenchmarkUI(params.useBcryptCost),
+			makeBcryptBatchUI(w),
+		),
+	)
+	paddedContent := container.NewPadded(content)
+	scrollable := container.NewVScroll(paddedContent)
+	return scrollable
+}
+
+// hashTask runs slow password hashing off the UI goroutine, showing the elapsed
+// time on output while it runs and offering a cancel button.
+type hashTask struct {
+	output       *canvas.Text
+	busyText     string
+	cancelButton *widget.Button
+	cancel       context.CancelFunc
+}
+
+func newHashTask(output *canvas.Text, busyText string) *hashTask {
+	t := &hashTask{output: output, busyText: busyText}
+	t.cancelButton = widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		if t.cancel != nil {
+			t.cancel()
+		}
+	})
+	t.cancelButton.Disable()
+	return t
+}
+
+// run starts work in the background with start disabled until it finishes.
+// work returns the text to show and whether it succeeded.
+func (t *hashTask) run(start *widget.Button, work func(ctx context.Context) (string, bool)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	start.Disable()
+	t.cancelButton.Enable()
+
+	begin := time.Now()
+	setOutputText(t.output, t.busyText, false)
+
+	type result struct {
+		text string
+		ok   bool
+	}
+	done := make(chan result, 1)
+	go func() {
+		text, ok := work(ctx)
+		done <- result{text, ok}
+	}()
+
+	go func() {
+		defer cancel()
+		defer start.Enable()
+		defer t.cancelButton.Disable()
+
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				setOutputText(t.output, fmt.Sprintf("%s %.1fs", t.busyText, time.Since(begin).Seconds()), false)
+			case r := <-done:
+				if errors.Is(ctx.Err(), context.Canceled) {
+					r.text = fmt.Sprintf("Cancelled after %.1fs", time.Since(begin).Seconds())
+				}
+				setOutputText(t.output, r.text, r.ok)
+				return
+			}
+		}
+	}()
+}
+
+// setOutputText shows text on output in green on success and red otherwise.
+func setOutputText(output *canvas.Text, text string, ok bool) {
+	output.Text = text
+	output.Color = colornames.Red
+	if ok {
+		output.Color = colornames.Green
+	}
+	output.Refresh()
+}
+
+// describeHash lists the parameters, salt and checksum of a parsed hash.
+func describeHash(info pwhash.Info) string {
+	p := info.Params
+	lines := []string{"Algorithm:  " + string(info.Algorithm)}
+	switch info.Algorithm {
+	case pwhash.Bcrypt:
+		lines = append(lines, fmt.Sprintf("Version:    $%s$ %s", info.Version, bcryptx.PrefixNote(info.Version)), fmt.Sprintf("Cost:       %d", p.Cost))
+	case pwhash.Argon2id:
+		lines = append(lines, "Version:    "+info.Version,
+			fmt.Sprintf("Memory:     %d KiB", p.Memory),
+			fmt.Sprintf("Iterations: %d", p.Iterations),
+			fmt.Sprintf("Threads:    %d", p.Parallelism))
+	case pwhash.Scrypt:
+		lines = append(lines, fmt.Sprintf("N:          2^%d", p.LogN), fmt.Sprintf("r:          %d", p.R), fmt.Sprintf("p:          %d", p.Parallelism))
+	case pwhash.PBKDF2SHA256:
+		if info.Format != "" {
+			lines = append(lines, "Format:     "+info.Format)
+		}
+		lines = append(lines, fmt.Sprintf("Iterations: %d", p.Iterations))
+	}
+	if info.Algorithm != pwhash.Bcrypt {
+		lines = append(lines, fmt.Sprintf("Salt:       %s (%d bytes)", info.Salt, p.SaltLength),
+			fmt.Sprintf("Hash:       %s (%d bytes)", info.Checksum, p.KeyLength))
+	} else {
+		lines = append(lines, "Salt:       "+info.Salt, "Checksum:   "+info.Checksum)
+	}
+	return strings.Join(lines, "\n")
+}
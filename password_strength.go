@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"image/color"
+	"joshu/pkg/strength"
+	"strings"
+)
+
+// scoreColors maps a strength score to the colour used to display it.
+var scoreColors = []color.Color{
+	colornames.Red,
+	colornames.Orangered,
+	colornames.Orange,
+	colornames.Yellowgreen,
+	colornames.Green,
+}
+
+// makeStrengthText returns a small caption describing the strength of password.
+func makeStrengthText(password string) *canvas.Text {
+	strengthText := canvas.NewText("", theme.ForegroundColor())
+	strengthText.TextSize = 12
+	strengthText.TextStyle = fyne.TextStyle{Italic: true}
+	strengthText.Alignment = fyne.TextAlignCenter
+	setStrengthText(strengthText, password)
+	return strengthText
+}
+
+func setStrengthText(strengthText *canvas.Text, password string) {
+	result := strength.Estimate(password)
+	strengthText.Text = result.Summary()
+	strengthText.Color = scoreColors[result.Score]
+	strengthText.Refresh()
+}
+
+func makePasswordStrengthUI() fyne.CanvasObject {
+	title := canvas.NewText("Check My Password", theme.ForegroundColor())
+	title.TextSize = 18
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	subTitle := canvas.NewText("Estimate how long a password would survive an attack. Nothing leaves this machine.", theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	input := widget.NewPasswordEntry()
+	input.SetPlaceHolder("Enter a password to check")
+
+	userInputs := widget.NewEntry()
+	userInputs.SetPlaceHolder("Words an attacker may know: user name, site, company (comma separated)")
+
+	scoreBar := widget.NewProgressBar()
+	scoreBar.Max = 4
+	scoreBar.TextFormatter = func() string { return "" }
+
+	summary := canvas.NewText("", theme.ForegroundColor())
+	summary.TextSize = 14
+	summary.TextStyle = fyne.TextStyle{Bold: true}
+
+	warning := canvas.NewText("", colornames.Red)
+	warning.TextSize = 14
+	warning.TextStyle = fyne.TextStyle{Italic: true}
+
+	patterns := widget.NewLabel("")
+	patterns.Wrapping = fyne.TextWrapWord
+
+	crackTimes := container.NewGridWithColumns(2)
+
+	check := func(string) {
+		if input.Text == "" {
+			scoreBar.SetValue(0)
+			summary.Text = ""
+			summary.Refresh()
+			warning.Text = ""
+			warning.Refresh()
+			patterns.SetText("")
+			crackTimes.Objects = nil
+			crackTimes.Refresh()
+			return
+		}
+
+		var words []string
+		for _, word := range strings.Split(userInputs.Text, ",") {
+			if word = strings.TrimSpace(word); word != "" {
+				words = append(words, word)
+			}
+		}
+		result := strength.Estimate(input.Text, words...)
+
+		scoreBar.SetValue(float64(result.Score))
+		summary.Text = result.Summary()
+		summary.Color = scoreColors[result.Score]
+		summary.Refresh()
+		warning.Text = result.Warning
+		warning.Refresh()
+
+		var found []string
+		for _, m := range result.Sequence {
+			if m.Pattern == strength.BruteforcePattern {
+				continue
+			}
+			found = append(found, fmt.Sprintf("%s %q", m.Pattern, m.Token))
+		}
+		if len(found) > 0 {
+			patterns.SetText("Patterns found: " + strings.Join(found, ", "))
+		} else {
+			patterns.SetText("No common patterns found")
+		}
+
+		var rows []fyne.CanvasObject
+		for _, ct := range result.CrackTimes {
+			rows = append(rows, widget.NewLabel(ct.Scenario.Name), widget.NewLabelWithStyle(ct.String(), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		}
+		crackTimes.Objects = rows
+		crackTimes.Refresh()
+	}
+	input.OnChanged = check
+	userInputs.OnChanged = check
+
+	return container.NewVBox(
+		title,
+		subTitle,
+		input,
+		userInputs,
+		scoreBar,
+		summary,
+		warning,
+		patterns,
+		crackTimes,
+	)
+}
@@ -0,0 +1,154 @@
+// Package b64 encodes and decodes base64 text in the standard, URL-safe,
+// unpadded (raw) and MIME variants, and detects the variant of encoded text.
+package b64
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Variant is a base64 flavour.
+type Variant string
+
+// Supported variants.
+const (
+	Standard Variant = "standard"
+	URL      Variant = "url"
+	RawStd   Variant = "raw"
+	RawURL   Variant = "raw-url"
+	// MIME is standard base64 broken into lines (RFC 2045).
+	MIME Variant = "mime"
+)
+
+// Variants lists the variants in display order.
+var Variants = []Variant{Standard, URL, RawStd, RawURL, MIME}
+
+// MIMELineLength is the line length of MIME base64.
+const MIMELineLength = 76
+
+// Label returns a human readable name for the variant.
+func (v Variant) Label() string {
+	switch v {
+	case Standard:
+		return "Standard"
+	case URL:
+		return "URL-safe"
+	case RawStd:
+		return "Raw standard (unpadded)"
+	case RawURL:
+		return "Raw URL-safe (unpadded)"
+	case MIME:
+		return fmt.Sprintf("MIME (%d columns)", MIMELineLength)
+	}
+	return string(v)
+}
+
+// encoding returns the alphabet and padding of the variant.
+func (v Variant) encoding() (*base64.Encoding, error) {
+	switch v {
+	case Standard, MIME:
+		return base64.StdEncoding, nil
+	case URL:
+		return base64.URLEncoding, nil
+	case RawStd:
+		return base64.RawStdEncoding, nil
+	case RawURL:
+		return base64.RawURLEncoding, nil
+	}
+	return nil, fmt.Errorf("unknown base64 variant %q", v)
+}
+
+// Encode returns the standard, padded base64 encoding of data.
+func Encode(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// Decode decodes standard, padded base64. Surrounding whitespace is ignored.
+func Decode(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+}
+
+// EncodeVariant returns the encoding of data in the variant. MIME output is
+// broken into lines of MIMELineLength characters separated by CRLF, as
+// RFC 2045 requires.
+func EncodeVariant(data []byte, v Variant) (string, error) {
+	enc, err := v.encoding()
+	if err != nil {
+		return "", err
+	}
+	text := enc.EncodeToString(data)
+	if v != MIME {
+		return text, nil
+	}
+	var lines []string
+	for len(text) > MIMELineLength {
+		lines = append(lines, text[:MIMELineLength])
+		text = text[MIMELineLength:]
+	}
+	return strings.Join(append(lines, text), "\r\n"), nil
+}
+
+// DecodeVariant decodes text in the variant. Surrounding whitespace is
+// ignored, and for MIME all whitespace, such as line breaks.
+func DecodeVariant(s string, v Variant) ([]byte, error) {
+	enc, err := v.encoding()
+	if err != nil {
+		return nil, err
+	}
+	if v == MIME {
+		return enc.DecodeString(stripSpace(s))
+	}
+	return enc.DecodeString(strings.TrimSpace(s))
+}
+
+// DecodeAuto decodes base64 of any variant and reports which one matched.
+// Whitespace anywhere is ignored and missing padding is tolerated. Text
+// using neither the URL-safe nor the standard-only characters is reported
+// as standard; padded unless it is unpadded and needs padding, and MIME
+// when it spans several lines. Input that is only padding, or has more
+// padding than its length calls for, is rejected.
+func DecodeAuto(s string) ([]byte, Variant, error) {
+	multiLine := strings.Contains(strings.TrimSpace(s), "\n")
+	s = stripSpace(s)
+	if s != "" && strings.Trim(s, "=") == "" {
+		return nil, "", fmt.Errorf("only padding, no base64 data")
+	}
+	urlSafe := strings.ContainsAny(s, "-_")
+	if urlSafe && strings.ContainsAny(s, "+/") {
+		return nil, "", fmt.Errorf("mixes URL-safe (-_) and standard (+/) characters")
+	}
+	unpadded := strings.TrimRight(s, "=")
+	if padding := len(s) - len(unpadded); padding > 2 || padding > 0 && len(s)%4 != 0 {
+		return nil, "", fmt.Errorf("malformed padding")
+	}
+	raw := len(unpadded) == len(s) && len(s)%4 != 0
+
+	enc := base64.RawStdEncoding
+	variant := Standard
+	switch {
+	case urlSafe && raw:
+		enc, variant = base64.RawURLEncoding, RawURL
+	case urlSafe:
+		enc, variant = base64.RawURLEncoding, URL
+	case raw:
+		variant = RawStd
+	case multiLine:
+		variant = MIME
+	}
+	data, err := enc.DecodeString(unpadded)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, variant, nil
+}
+
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
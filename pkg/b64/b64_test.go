@@ -0,0 +1,118 @@
+package b64
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestVariantRoundTrip(t *testing.T) {
+	inputs := [][]byte{
+		nil,
+		[]byte("f"),
+		[]byte("fo"),
+		[]byte("foo"),
+		{0xfb, 0xff, 0xbf},
+		bytes.Repeat([]byte{0xfa, 0x01, 0x7e}, 100),
+	}
+	for _, v := range Variants {
+		t.Run(string(v), func(t *testing.T) {
+			for _, data := range inputs {
+				text, err := EncodeVariant(data, v)
+				if err != nil {
+					t.Fatalf("EncodeVariant: %v", err)
+				}
+				got, err := DecodeVariant(" "+text+"\n", v)
+				if err != nil || !bytes.Equal(got, data) {
+					t.Errorf("DecodeVariant(%q) = %x, %v, want %x", text, got, err, data)
+				}
+				if len(data) == 0 {
+					continue
+				}
+				// TestDecodeAuto covers which variant is reported.
+				if got, _, err := DecodeAuto(text); err != nil || !bytes.Equal(got, data) {
+					t.Errorf("DecodeAuto(%q) = %x, %v, want %x", text, got, err, data)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeVariantMIME(t *testing.T) {
+	text, err := EncodeVariant(bytes.Repeat([]byte("x"), 120), MIME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(text, "\r\n")
+	if len(lines) != 3 || len(lines[0]) != MIMELineLength || len(lines[1]) != MIMELineLength || len(lines[2]) != 8 {
+		t.Errorf("EncodeVariant() lines = %q, want 76, 76 and 8 characters split by CRLF", lines)
+	}
+	if _, variant, err := DecodeAuto(text); err != nil || variant != MIME {
+		t.Errorf("DecodeAuto() variant = %q, %v, want mime", variant, err)
+	}
+}
+
+func TestDecodeAuto(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		variant Variant
+		wantErr string
+	}{
+		{"standard", "Zm9vYmFy", "foobar", Standard, ""},
+		{"padded", "Zm9vYg==", "foob", Standard, ""},
+		{"unpadded", "Zm9vYg", "foob", RawStd, ""},
+		{"URL-safe", "-_-_", "\xfb\xff\xbf", URL, ""},
+		{"URL-safe unpadded", "-_8", "\xfb\xff", RawURL, ""},
+		{"spaces inside", " Zm9v\tYmFy ", "foobar", Standard, ""},
+		{"only padding", "==", "", "", "only padding"},
+		{"too much padding", "Zg===", "", "", "malformed padding"},
+		{"one padding character", "Zm9=", "fo", Standard, ""},
+		{"padding not at a quantum", "Zm9vY=", "", "", "malformed padding"},
+		{"mixed alphabets", "+/-_", "", "", "mixes URL-safe"},
+		{"not base64", "Zm9v!", "", "", "illegal base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, variant, err := DecodeAuto(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("DecodeAuto() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want || variant != tt.variant {
+				t.Errorf("DecodeAuto() = %q, %q, %v, want %q, %q", got, variant, err, tt.want, tt.variant)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		variant Variant
+	}{
+		{"unpadded as standard", "Zm9vYg", Standard},
+		{"URL-safe as standard", "-_-_", Standard},
+		{"standard as URL-safe", "+/+/", URL},
+		{"padded as raw", "Zm9vYg==", RawStd},
+		{"spaces outside MIME", "Zm9v YmFy", Standard},
+		{"unknown variant", "Zm9v", "base32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := DecodeVariant(tt.input, tt.variant); err == nil {
+				t.Errorf("DecodeVariant() = %q, want an error", got)
+			}
+		})
+	}
+	if _, err := EncodeVariant([]byte("x"), "base32"); err == nil {
+		t.Error("EncodeVariant accepted an unknown variant")
+	}
+	if got, err := Decode(" " + Encode([]byte("hello")) + "\n"); err != nil || string(got) != "hello" {
+		t.Errorf("Decode(Encode()) = %q, %v", got, err)
+	}
+}
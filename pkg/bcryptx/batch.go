@@ -0,0 +1,222 @@
+package bcryptx
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// BatchFormat selects how batch files are read and written.
+type BatchFormat string
+
+// Supported batch formats.
+const (
+	// CSVBatch has one plaintext per row, optionally followed by a hash column.
+	// A first row naming both columns, such as "plaintext,hash", is treated
+	// as a header.
+	CSVBatch BatchFormat = "csv"
+	// TextBatch has one plaintext per line, optionally followed by a tab and a hash.
+	TextBatch BatchFormat = "txt"
+)
+
+// FormatFromName picks the batch format from a file name's extension.
+func FormatFromName(name string) BatchFormat {
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		return CSVBatch
+	}
+	return TextBatch
+}
+
+// Record is one entry of a batch.
+type Record struct {
+	Plaintext string
+	Hash      string
+	// Verified and Match are set by VerifyBatch.
+	Verified bool
+	Match    bool
+	// Err holds a per record failure, such as a malformed hash.
+	Err error
+}
+
+// Result describes the outcome of the record for results files.
+func (r Record) Result() string {
+	switch {
+	case r.Err != nil:
+		return "error: " + r.Err.Error()
+	case !r.Verified:
+		return ""
+	case r.Match:
+		return "match"
+	default:
+		return "mismatch"
+	}
+}
+
+// ReadBatch reads plaintexts, or plaintext and hash pairs, from r. It also
+// returns the number of rows skipped: a header and blank lines.
+func ReadBatch(r io.Reader, format BatchFormat) ([]Record, int, error) {
+	var records []Record
+	skipped := 0
+	switch format {
+	case CSVBatch:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		rows, err := cr.ReadAll()
+		if err != nil {
+			return nil, 0, err
+		}
+		for i, row := range rows {
+			if len(row) == 0 || len(row) == 1 && row[0] == "" || i == 0 && isHeader(row) {
+				skipped++
+				continue
+			}
+			record := Record{Plaintext: row[0]}
+			if len(row) > 1 {
+				record.Hash = strings.TrimSpace(row[1])
+			}
+			records = append(records, record)
+		}
+	case TextBatch:
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := strings.TrimSuffix(scanner.Text(), "\r")
+			if line == "" {
+				skipped++
+				continue
+			}
+			plaintext, hash, _ := strings.Cut(line, "\t")
+			records = append(records, Record{Plaintext: plaintext, Hash: strings.TrimSpace(hash)})
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, 0, err
+		}
+	default:
+		return nil, 0, fmt.Errorf("unknown batch format %q", format)
+	}
+
+	if len(records) == 0 {
+		return nil, skipped, errors.New("no records found")
+	}
+	return records, skipped, nil
+}
+
+// isHeader reports whether row names the plaintext and hash columns, as
+// written by WriteBatch. A lone "password" is taken as a plaintext.
+func isHeader(row []string) bool {
+	if len(row) < 2 {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(row[0])) {
+	case "password", "plaintext":
+	default:
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(row[1])) {
+	case "hash", "bcrypt", "bcrypt hash":
+		return true
+	}
+	return false
+}
+
+// WriteBatch writes records with their outcome. CSV output has the columns
+// plaintext, hash and result; text output separates them with tabs and
+// leaves the result out after hashing.
+func WriteBatch(w io.Writer, format BatchFormat, records []Record) error {
+	switch format {
+	case CSVBatch:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"plaintext", "hash", "result"}); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := cw.Write([]string{r.Plaintext, r.Hash, r.Result()}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case TextBatch:
+		bw := bufio.NewWriter(w)
+		for _, r := range records {
+			fields := []string{r.Plaintext, r.Hash}
+			if result := r.Result(); result != "" {
+				fields = append(fields, result)
+			}
+			if _, err := bw.WriteString(strings.Join(fields, "\t") + "\n"); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	default:
+		return fmt.Errorf("unknown batch format %q", format)
+	}
+}
+
+// HashBatch sets the Hash of every record to the bcrypt hash of its
+// plaintext. See VerifyBatch for workers, progress and cancellation.
+func HashBatch(ctx context.Context, records []Record, cost, workers int, progress func(done int)) error {
+	return runPool(ctx, len(records), workers, progress, func(i int) {
+		records[i].Hash, records[i].Err = Hash(records[i].Plaintext, cost)
+		records[i].Verified, records[i].Match = false, false
+	})
+}
+
+// VerifyBatch checks every record's plaintext against its hash and sets
+// Match, or Err for hashes that cannot be parsed. Up to workers goroutines
+// are used (all CPUs when workers < 1), and progress, when not nil, is called
+// from them with the number of records done. If ctx is cancelled, the
+// records not yet started are left untouched and ctx.Err() is returned.
+func VerifyBatch(ctx context.Context, records []Record, workers int, progress func(done int)) error {
+	return runPool(ctx, len(records), workers, progress, func(i int) {
+		err := Verify(records[i].Hash, records[i].Plaintext)
+		records[i].Verified = true
+		records[i].Match = err == nil
+		records[i].Err = nil
+		if err != nil && !errors.Is(err, ErrMismatch) {
+			records[i].Err = err
+		}
+	})
+}
+
+// runPool calls job for every index in [0, n) on up to workers goroutines.
+func runPool(ctx context.Context, n, workers int, progress func(done int), job func(i int)) error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan int)
+	var done atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				job(i)
+				if d := done.Add(1); progress != nil {
+					progress(int(d))
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return ctx.Err()
+}
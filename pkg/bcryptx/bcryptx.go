@@ -0,0 +1,105 @@
+// Package bcryptx hashes and verifies passwords with bcrypt.
+package bcryptx
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrMismatch is returned by Verify when the password does not match the hash.
+var ErrMismatch = bcrypt.ErrMismatchedHashAndPassword
+
+// DefaultCost is the cost used by the UI and CLI when none is given.
+const DefaultCost = 12
+
+// ValidateCost reports whether cost is within bcrypt's supported range.
+func ValidateCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("cost %d is outside the supported range %d to %d", cost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
+
+// Hash returns the bcrypt hash of password at the given cost. Costs outside
+// the range accepted by ValidateCost are rejected rather than replaced with
+// the library default.
+func Hash(password string, cost int) (string, error) {
+	if err := ValidateCost(cost); err != nil {
+		return "", err
+	}
+	if len(password) > MaxPasswordBytes {
+		return "", fmt.Errorf("%w: it is %d bytes, shorten it or use a pre-hash mode", ErrPasswordTooLong, len(password))
+	}
+	// Generate a bcrypt hash of the password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+// Verify returns nil if password matches hash, or the bcrypt error explaining why not.
+func Verify(hash, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+// HashContext is Hash, returning ctx.Err() as soon as ctx is cancelled.
+// bcrypt cannot be interrupted, so a cancelled hash still finishes in the
+// background and its result is discarded.
+func HashContext(ctx context.Context, password string, cost int) (string, error) {
+	if err := ValidateCost(cost); err != nil {
+		return "", err
+	}
+	type result struct {
+		hash string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		hash, err := Hash(password, cost)
+		done <- result{hash, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.hash, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// VerifyContext is Verify, returning ctx.Err() as soon as ctx is cancelled.
+// See HashContext.
+func VerifyContext(ctx context.Context, hash, password string) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- Verify(hash, password)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HashAll hashes every password at the given cost using up to workers
+// goroutines (all CPUs when workers < 1), and returns the hashes in input
+// order. progress, when not nil, is called after each hash with the number
+// completed so far; it may be called from several goroutines.
+func HashAll(passwords []string, cost, workers int, progress func(done int)) ([]string, error) {
+	hashes := make([]string, len(passwords))
+	errs := make([]error, len(passwords))
+	runPool(context.Background(), len(passwords), workers, progress, func(i int) {
+		hashes[i], errs[i] = Hash(passwords[i], cost)
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
@@ -0,0 +1,348 @@
+package bcryptx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCost keeps the tests fast; it is bcrypt's minimum.
+const testCost = bcrypt.MinCost
+
+func TestHashVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{"ascii", "correct horse battery staple"},
+		{"empty", ""},
+		{"unicode", "pässwörd 密码"},
+		{"maximum length", strings.Repeat("a", MaxPasswordBytes)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := Hash(tt.password, testCost)
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if err := Verify(hash, tt.password); err != nil {
+				t.Errorf("Verify with the right password: %v", err)
+			}
+			if err := Verify(hash, "x"+tt.password); !errors.Is(err, ErrMismatch) {
+				t.Errorf("Verify with a wrong password = %v, want ErrMismatch", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIgnoresExcess(t *testing.T) {
+	password := strings.Repeat("a", MaxPasswordBytes)
+	hash, err := Hash(password, testCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(hash, password+"excess"); err != nil {
+		t.Errorf("Verify with bytes past %d: %v", MaxPasswordBytes, err)
+	}
+}
+
+func TestHashErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		cost     int
+		target   error
+	}{
+		{"cost too low", "pw", bcrypt.MinCost - 1, nil},
+		{"cost too high", "pw", bcrypt.MaxCost + 1, nil},
+		{"password too long", strings.Repeat("a", MaxPasswordBytes+1), testCost, ErrPasswordTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Hash(tt.password, tt.cost)
+			if err == nil {
+				t.Fatal("Hash succeeded")
+			}
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Errorf("Hash() error = %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestHashContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := HashContext(ctx, "pw", bcrypt.MaxCost); !errors.Is(err, context.Canceled) {
+		t.Errorf("HashContext() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	const saltAndChecksum = "N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
+	tests := []struct {
+		name    string
+		hash    string
+		want    Info
+		wantErr string
+	}{
+		{"2b", "$2b$12$" + saltAndChecksum, Info{"2b", 12, saltAndChecksum[:22], saltAndChecksum[22:]}, ""},
+		{"2y with spaces", " $2y$04$" + saltAndChecksum + "\n", Info{"2y", 4, saltAndChecksum[:22], saltAndChecksum[22:]}, ""},
+		{"not bcrypt", "hunter2", Info{}, "not a bcrypt hash"},
+		{"legacy version", "$2x$12$" + saltAndChecksum, Info{}, "legacy bcrypt version"},
+		{"unknown version", "$3a$12$" + saltAndChecksum, Info{}, "unknown bcrypt version"},
+		{"one digit cost", "$2b$4$" + saltAndChecksum, Info{}, "two digits"},
+		{"cost out of range", "$2b$32$" + saltAndChecksum, Info{}, "must be between"},
+		{"truncated", "$2b$12$" + saltAndChecksum[:50], Info{}, "53 characters"},
+		{"bad character", "$2b$12$" + saltAndChecksum[:52] + "!", Info{}, "invalid character"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Inspect(tt.hash)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Inspect() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Inspect: %v", err)
+			}
+			if info != tt.want {
+				t.Errorf("Inspect() = %+v, want %+v", info, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	if w := (Info{Cost: MinPolicyCost - 1}).Warnings(MinPolicyCost); len(w) != 1 {
+		t.Errorf("Warnings below the minimum = %q", w)
+	}
+	if w := (Info{Cost: MinPolicyCost}).Warnings(MinPolicyCost); len(w) != 0 {
+		t.Errorf("Warnings at the minimum = %q", w)
+	}
+}
+
+func TestConvertPrefix(t *testing.T) {
+	hash, err := Hash("pw", testCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range Prefixes {
+		t.Run(prefix, func(t *testing.T) {
+			converted, err := ConvertPrefix(hash, prefix)
+			if err != nil {
+				t.Fatalf("ConvertPrefix: %v", err)
+			}
+			if !strings.HasPrefix(converted, "$"+prefix+"$04$") || converted[7:] != hash[7:] {
+				t.Errorf("ConvertPrefix() = %q from %q", converted, hash)
+			}
+			if err := Verify(converted, "pw"); err != nil {
+				t.Errorf("Verify after conversion: %v", err)
+			}
+		})
+	}
+	if _, err := ConvertPrefix(hash, "2x"); err == nil {
+		t.Error("ConvertPrefix accepted 2x")
+	}
+}
+
+func TestPreHash(t *testing.T) {
+	long := strings.Repeat("long password ", 10)
+	tests := []struct {
+		mode    PreHash
+		want    string
+		wantErr bool
+	}{
+		{NoPreHash, "abc", false},
+		{"", "abc", false},
+		{SHA256Base64, "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0=", false},
+		{SHA256Hex, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", false},
+		{"md5", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			got, err := tt.mode.Apply("abc")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+			if tt.wantErr || tt.mode == NoPreHash || tt.mode == "" {
+				return
+			}
+			input, _ := tt.mode.Apply(long)
+			if _, err := Hash(input, testCost); err != nil {
+				t.Errorf("Hash of a pre-hashed long password: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		warnings int
+	}{
+		{"fine", "password", 0},
+		{"too long", strings.Repeat("a", MaxPasswordBytes+1), 1},
+		{"invalid UTF-8", "\xff\xfe", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := CheckPassword(tt.password); len(w) != tt.warnings {
+				t.Errorf("CheckPassword() = %q, want %d warnings", w, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{ErrMismatch, "does not match"},
+		{bcrypt.ErrHashTooShort, "too short"},
+		{ErrPasswordTooLong, "longer than 72 bytes"},
+		{bcrypt.InvalidCostError(40), "invalid cost 40"},
+	}
+	for _, tt := range tests {
+		if got := Explain(tt.err); !strings.Contains(got, tt.want) || (tt.want == "") != (got == "") {
+			t.Errorf("Explain(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestReadBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  BatchFormat
+		text    string
+		want    []Record
+		skipped int
+		wantErr bool
+	}{
+		{
+			name:    "csv with header",
+			format:  CSVBatch,
+			text:    "plaintext,hash\nalpha,$2b$x\n\nbravo\n",
+			want:    []Record{{Plaintext: "alpha", Hash: "$2b$x"}, {Plaintext: "bravo"}},
+			skipped: 1,
+		},
+		{
+			name:   "csv password column only",
+			format: CSVBatch,
+			text:   "password\nhunter2\n",
+			want:   []Record{{Plaintext: "password"}, {Plaintext: "hunter2"}},
+		},
+		{
+			name:    "text with tabs and blank lines",
+			format:  TextBatch,
+			text:    "alpha\t$2b$x\r\n\nbravo\n",
+			want:    []Record{{Plaintext: "alpha", Hash: "$2b$x"}, {Plaintext: "bravo"}},
+			skipped: 1,
+		},
+		{name: "empty", format: TextBatch, text: "\n\n", wantErr: true},
+		{name: "unknown format", format: "xml", text: "alpha", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, skipped, err := ReadBatch(strings.NewReader(tt.text), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if skipped != tt.skipped {
+				t.Errorf("skipped %d rows, want %d", skipped, tt.skipped)
+			}
+			if len(records) != len(tt.want) {
+				t.Fatalf("ReadBatch() = %+v, want %+v", records, tt.want)
+			}
+			for i := range records {
+				if records[i] != tt.want[i] {
+					t.Errorf("record %d = %+v, want %+v", i, records[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBatchRoundTrip(t *testing.T) {
+	for _, format := range []BatchFormat{CSVBatch, TextBatch} {
+		t.Run(string(format), func(t *testing.T) {
+			records := []Record{{Plaintext: "alpha"}, {Plaintext: "bravo, charlie"}}
+			if err := HashBatch(context.Background(), records, testCost, 2, nil); err != nil {
+				t.Fatalf("HashBatch: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := WriteBatch(&buf, format, records); err != nil {
+				t.Fatalf("WriteBatch: %v", err)
+			}
+			read, _, err := ReadBatch(&buf, format)
+			if err != nil {
+				t.Fatalf("ReadBatch: %v", err)
+			}
+			read = append(read, Record{Plaintext: "delta", Hash: read[0].Hash}, Record{Plaintext: "echo", Hash: "bad"})
+			if err := VerifyBatch(context.Background(), read, 0, nil); err != nil {
+				t.Fatalf("VerifyBatch: %v", err)
+			}
+			want := []string{"match", "match", "mismatch", "error: "}
+			for i, r := range read {
+				if !strings.HasPrefix(r.Result(), want[i]) {
+					t.Errorf("record %d (%q) result %q, want %q", i, r.Plaintext, r.Result(), want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFormatFromName(t *testing.T) {
+	tests := map[string]BatchFormat{
+		"passwords.csv": CSVBatch,
+		"PASSWORDS.CSV": CSVBatch,
+		"passwords.txt": TextBatch,
+		"passwords":     TextBatch,
+	}
+	for name, want := range tests {
+		if got := FormatFromName(name); got != want {
+			t.Errorf("FormatFromName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	timings := []Timing{
+		{10, 60 * time.Millisecond},
+		{11, 120 * time.Millisecond},
+		{12, 240 * time.Millisecond},
+		{13, 480 * time.Millisecond},
+	}
+	tests := []struct {
+		target time.Duration
+		want   int
+	}{
+		{DefaultTarget, 12},
+		{time.Second, 13},
+		{time.Millisecond, 10},
+		{150 * time.Millisecond, 11},
+	}
+	for _, tt := range tests {
+		if got := Recommend(timings, tt.target); got != tt.want {
+			t.Errorf("Recommend(%v) = %d, want %d", tt.target, got, tt.want)
+		}
+	}
+	if got := Recommend(nil, DefaultTarget); got != 0 {
+		t.Errorf("Recommend(nil) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,125 @@
+package bcryptx
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"unicode/utf8"
+)
+
+// MaxPasswordBytes is the longest input bcrypt uses. Hash rejects longer
+// passwords; Verify, like most other implementations, ignores the excess.
+const MaxPasswordBytes = 72
+
+// ErrPasswordTooLong is returned by Hash for passwords over MaxPasswordBytes.
+var ErrPasswordTooLong = bcrypt.ErrPasswordTooLong
+
+// CheckPassword lists problems with password as bcrypt input: a length over
+// MaxPasswordBytes, or bytes that are not valid UTF-8 and may be encoded
+// differently by other services.
+func CheckPassword(password string) []string {
+	var warnings []string
+	if !utf8.ValidString(password) {
+		warnings = append(warnings, "password is not valid UTF-8, other services may encode it differently")
+	}
+	if len(password) > MaxPasswordBytes {
+		warnings = append(warnings, fmt.Sprintf("password is %d bytes, bcrypt only uses the first %d", len(password), MaxPasswordBytes))
+	}
+	return warnings
+}
+
+// PreHash transforms a password before bcrypt so inputs of any length are
+// used in full.
+type PreHash string
+
+// Supported pre-hash modes.
+const (
+	NoPreHash PreHash = "none"
+	// SHA256Base64 is base64(sha256(password)), 44 bytes. Base64 keeps NUL
+	// bytes of the raw digest from truncating the input in C implementations.
+	SHA256Base64 PreHash = "sha256-base64"
+	// SHA256Hex is the lowercase hex SHA-256 digest, 64 bytes.
+	SHA256Hex PreHash = "sha256-hex"
+)
+
+// PreHashes lists the pre-hash modes in display order.
+var PreHashes = []PreHash{NoPreHash, SHA256Base64, SHA256Hex}
+
+// Apply returns the bcrypt input for password.
+func (m PreHash) Apply(password string) (string, error) {
+	switch m {
+	case "", NoPreHash:
+		return password, nil
+	case SHA256Base64:
+		sum := sha256.Sum256([]byte(password))
+		return base64.StdEncoding.EncodeToString(sum[:]), nil
+	case SHA256Hex:
+		sum := sha256.Sum256([]byte(password))
+		return hex.EncodeToString(sum[:]), nil
+	default:
+		return "", fmt.Errorf("unknown pre-hash mode %q", m)
+	}
+}
+
+// Prefixes lists the interchangeable bcrypt versions. They differ only in
+// which implementation produced them; the salt and checksum are computed
+// the same way, so a hash can be moved between them by rewriting the prefix.
+var Prefixes = []string{"2a", "2b", "2y"}
+
+// PrefixNote explains where hashes with the given version come from.
+func PrefixNote(prefix string) string {
+	switch prefix {
+	case "2a":
+		return "$2a$ is the original version, produced by Go and many older libraries. " +
+			"PHP before 5.3.7 also wrote $2a$ with a bug affecting non-ASCII passwords."
+	case "2b":
+		return "$2b$ is the current OpenBSD version, produced by Node's bcrypt and Python's bcrypt."
+	case "2y":
+		return "$2y$ is produced by PHP's password_hash and marks hashes made without the pre-5.3.7 bug."
+	default:
+		return ""
+	}
+}
+
+// ConvertPrefix returns hash with its version replaced by prefix, for
+// services that only accept one of the Prefixes.
+func ConvertPrefix(hash, prefix string) (string, error) {
+	info, err := Inspect(hash)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range Prefixes {
+		if p == prefix {
+			return fmt.Sprintf("$%s$%02d$%s%s", prefix, info.Cost, info.Salt, info.Checksum), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported bcrypt version %q", prefix)
+}
+
+// Explain turns errors from Hash and Verify into a sentence for display.
+func Explain(err error) string {
+	var versionErr bcrypt.HashVersionTooNewError
+	var prefixErr bcrypt.InvalidHashPrefixError
+	var costErr bcrypt.InvalidCostError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrMismatch):
+		return "The password does not match the hash."
+	case errors.Is(err, bcrypt.ErrHashTooShort):
+		return "The hash is too short to be a bcrypt hash, it should be 60 characters."
+	case errors.Is(err, ErrPasswordTooLong):
+		return fmt.Sprintf("The password is longer than %d bytes. Shorten it or use a pre-hash mode.", MaxPasswordBytes)
+	case errors.As(err, &versionErr):
+		return fmt.Sprintf("The hash version %q is not supported, expected $2a$, $2b$ or $2y$.", rune(versionErr))
+	case errors.As(err, &prefixErr):
+		return "The hash must start with $, as in $2b$12$..."
+	case errors.As(err, &costErr):
+		return fmt.Sprintf("The hash has an invalid cost %d.", int(costErr))
+	default:
+		return err.Error()
+	}
+}
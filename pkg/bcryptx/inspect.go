@@ -0,0 +1,127 @@
+package bcryptx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinPolicyCost is the lowest cost considered acceptable for new hashes.
+const MinPolicyCost = 10
+
+// Benchmark bounds, see Benchmark.
+const (
+	MinBenchmarkCost = 4
+	MaxBenchmarkCost = 16
+)
+
+// DefaultTarget is the hashing latency Recommend aims for when none is given.
+const DefaultTarget = 250 * time.Millisecond
+
+// bcryptAlphabet is the base64 alphabet bcrypt uses for salts and checksums.
+const bcryptAlphabet = "./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// Info describes the parts of a bcrypt hash.
+type Info struct {
+	// Prefix is the version identifier, such as "2b".
+	Prefix   string
+	Cost     int
+	Salt     string
+	Checksum string
+}
+
+// Inspect splits a modular crypt format bcrypt hash of the form
+// $2b$<cost>$<22 character salt><31 character checksum> into its parts.
+func Inspect(hash string) (Info, error) {
+	hash = strings.TrimSpace(hash)
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "" {
+		return Info{}, errors.New("not a bcrypt hash, expected $2b$<cost>$<salt and checksum>")
+	}
+
+	info := Info{Prefix: parts[1]}
+	switch info.Prefix {
+	case "2a", "2b", "2y":
+	case "2", "2x":
+		return Info{}, fmt.Errorf("legacy bcrypt version $%s$ is not supported", info.Prefix)
+	default:
+		return Info{}, fmt.Errorf("unknown bcrypt version $%s$", info.Prefix)
+	}
+
+	if len(parts[2]) != 2 {
+		return Info{}, fmt.Errorf("cost %q must be two digits", parts[2])
+	}
+	cost, err := strconv.Atoi(parts[2])
+	if err != nil || cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return Info{}, fmt.Errorf("cost %q must be between %d and %d", parts[2], bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	info.Cost = cost
+
+	rest := parts[3]
+	if len(rest) != 53 {
+		return Info{}, fmt.Errorf("salt and checksum must be 53 characters, got %d", len(rest))
+	}
+	if i := strings.IndexFunc(rest, func(r rune) bool { return !strings.ContainsRune(bcryptAlphabet, r) }); i >= 0 {
+		return Info{}, fmt.Errorf("invalid character %q in salt or checksum", rest[i])
+	}
+	info.Salt, info.Checksum = rest[:22], rest[22:]
+	return info, nil
+}
+
+// Warnings lists concerns about the hash, currently a cost below minCost.
+func (i Info) Warnings(minCost int) []string {
+	var warnings []string
+	if i.Cost < minCost {
+		warnings = append(warnings, fmt.Sprintf("cost %d is below the minimum of %d, rehash on next login", i.Cost, minCost))
+	}
+	return warnings
+}
+
+// Timing is the time one bcrypt hash took at Cost.
+type Timing struct {
+	Cost     int
+	Duration time.Duration
+}
+
+// Benchmark times bcrypt.GenerateFromPassword for every cost from
+// MinBenchmarkCost to MaxBenchmarkCost on this machine. progress, when not
+// nil, is called after each cost. If ctx is cancelled, the timings measured
+// so far are returned with ctx.Err().
+func Benchmark(ctx context.Context, progress func(Timing)) ([]Timing, error) {
+	var timings []Timing
+	password := []byte("benchmark password")
+	for cost := MinBenchmarkCost; cost <= MaxBenchmarkCost; cost++ {
+		if err := ctx.Err(); err != nil {
+			return timings, err
+		}
+		start := time.Now()
+		if _, err := bcrypt.GenerateFromPassword(password, cost); err != nil {
+			return timings, err
+		}
+		t := Timing{Cost: cost, Duration: time.Since(start)}
+		timings = append(timings, t)
+		if progress != nil {
+			progress(t)
+		}
+	}
+	return timings, nil
+}
+
+// Recommend returns the benchmarked cost whose duration is closest to
+// target. Each cost step doubles the work, so closeness is measured as a
+// ratio rather than a difference. It returns 0 when timings is empty.
+func Recommend(timings []Timing, target time.Duration) int {
+	best, bestDistance := 0, math.Inf(1)
+	for _, t := range timings {
+		distance := math.Abs(math.Log(float64(t.Duration) / float64(target)))
+		if distance < bestDistance {
+			best, bestDistance = t.Cost, distance
+		}
+	}
+	return best
+}
@@ -0,0 +1,395 @@
+// Package certs creates certificate signing requests, self-signed
+// certificates and throwaway certificate authorities for local testing, and
+// signs leaf certificates with them. Keys are given as text in any format
+// read by rsakeys or eckeys; certificates and requests are PEM.
+package certs
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/rsakeys"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PEM block types written by this package.
+const (
+	CertificateType = "CERTIFICATE"
+	RequestType     = "CERTIFICATE REQUEST"
+)
+
+// DefaultDays is the validity of certificates when Options.Days is zero.
+const DefaultDays = 365
+
+// KeyUsage names an X.509 key usage bit.
+type KeyUsage string
+
+// Supported key usages, named as in RFC 5280.
+const (
+	DigitalSignature  KeyUsage = "digitalSignature"
+	ContentCommitment KeyUsage = "contentCommitment"
+	KeyEncipherment   KeyUsage = "keyEncipherment"
+	DataEncipherment  KeyUsage = "dataEncipherment"
+	KeyAgreement      KeyUsage = "keyAgreement"
+	CertSign          KeyUsage = "keyCertSign"
+	CRLSign           KeyUsage = "cRLSign"
+)
+
+// KeyUsages lists the key usages in display order.
+var KeyUsages = []KeyUsage{DigitalSignature, ContentCommitment, KeyEncipherment, DataEncipherment, KeyAgreement, CertSign, CRLSign}
+
+var keyUsageBits = map[KeyUsage]x509.KeyUsage{
+	DigitalSignature:  x509.KeyUsageDigitalSignature,
+	ContentCommitment: x509.KeyUsageContentCommitment,
+	KeyEncipherment:   x509.KeyUsageKeyEncipherment,
+	DataEncipherment:  x509.KeyUsageDataEncipherment,
+	KeyAgreement:      x509.KeyUsageKeyAgreement,
+	CertSign:          x509.KeyUsageCertSign,
+	CRLSign:           x509.KeyUsageCRLSign,
+}
+
+// ExtKeyUsage names an X.509 extended key usage.
+type ExtKeyUsage string
+
+// Supported extended key usages.
+const (
+	ServerAuth      ExtKeyUsage = "serverAuth"
+	ClientAuth      ExtKeyUsage = "clientAuth"
+	CodeSigning     ExtKeyUsage = "codeSigning"
+	EmailProtection ExtKeyUsage = "emailProtection"
+	TimeStamping    ExtKeyUsage = "timeStamping"
+	OCSPSigning     ExtKeyUsage = "OCSPSigning"
+)
+
+// ExtKeyUsages lists the extended key usages in display order.
+var ExtKeyUsages = []ExtKeyUsage{ServerAuth, ClientAuth, CodeSigning, EmailProtection, TimeStamping, OCSPSigning}
+
+var extKeyUsages = map[ExtKeyUsage]x509.ExtKeyUsage{
+	ServerAuth:      x509.ExtKeyUsageServerAuth,
+	ClientAuth:      x509.ExtKeyUsageClientAuth,
+	CodeSigning:     x509.ExtKeyUsageCodeSigning,
+	EmailProtection: x509.ExtKeyUsageEmailProtection,
+	TimeStamping:    x509.ExtKeyUsageTimeStamping,
+	OCSPSigning:     x509.ExtKeyUsageOCSPSigning,
+}
+
+// Options describes a certificate or request.
+type Options struct {
+	Subject pkix.Name
+	// SANs are subject alternative names: IP addresses, e-mail addresses
+	// (containing @), URIs (containing ://) and otherwise DNS names. When
+	// empty, a leaf certificate gets the common name as its DNS name.
+	SANs []string
+	// Days is the validity period, DefaultDays when zero.
+	Days int
+	// IsCA makes the certificate a certificate authority.
+	IsCA bool
+	// KeyUsages and ExtKeyUsages default to the usual values for a CA or a
+	// TLS leaf certificate when nil.
+	KeyUsages    []KeyUsage
+	ExtKeyUsages []ExtKeyUsage
+}
+
+// KeyType is a kind of key GenerateKey can make for a certificate.
+type KeyType string
+
+// Supported key types.
+const (
+	ECDSAP256 KeyType = "ECDSA P-256"
+	ECDSAP384 KeyType = "ECDSA P-384"
+	Ed25519   KeyType = "Ed25519"
+	RSA2048   KeyType = "RSA 2048"
+	RSA3072   KeyType = "RSA 3072"
+	RSA4096   KeyType = "RSA 4096"
+)
+
+// KeyTypes lists the key types in display order, the default first.
+var KeyTypes = []KeyType{ECDSAP256, ECDSAP384, Ed25519, RSA2048, RSA3072, RSA4096}
+
+// GenerateKey returns a new unencrypted PKCS#8 private key of keyType.
+func GenerateKey(keyType KeyType) (string, error) {
+	var privateKey string
+	var err error
+	switch keyType {
+	case ECDSAP256:
+		privateKey, _, err = eckeys.Generate(eckeys.P256, rsakeys.PKCS8)
+	case ECDSAP384:
+		privateKey, _, err = eckeys.Generate(eckeys.P384, rsakeys.PKCS8)
+	case Ed25519:
+		privateKey, _, err = eckeys.Generate(eckeys.Ed25519, rsakeys.PKCS8)
+	case RSA2048:
+		privateKey, _, err = rsakeys.GenerateFormat(2048, rsakeys.PKCS8)
+	case RSA3072:
+		privateKey, _, err = rsakeys.GenerateFormat(3072, rsakeys.PKCS8)
+	case RSA4096:
+		privateKey, _, err = rsakeys.GenerateFormat(4096, rsakeys.PKCS8)
+	default:
+		return "", fmt.Errorf("unknown key type %q", keyType)
+	}
+	return privateKey, err
+}
+
+// ParseSANs splits a comma, space or newline separated list of names.
+func ParseSANs(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+}
+
+// CreateRequest returns a PEM certificate signing request for the subject
+// and SANs in opts, signed with the private key.
+func CreateRequest(opts Options, privateKeyText string) (string, error) {
+	key, err := parseSigner(privateKeyText)
+	if err != nil {
+		return "", err
+	}
+	template := &x509.CertificateRequest{Subject: opts.Subject}
+	if err := setSANs(opts, &template.DNSNames, &template.EmailAddresses, &template.IPAddresses, &template.URIs); err != nil {
+		return "", err
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return "", err
+	}
+	return encode(RequestType, der), nil
+}
+
+// SelfSign returns a PEM certificate for opts signed by its own private key.
+func SelfSign(opts Options, privateKeyText string) (string, error) {
+	key, err := parseSigner(privateKeyText)
+	if err != nil {
+		return "", err
+	}
+	template, err := newTemplate(opts, key.Public())
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return "", err
+	}
+	return encode(CertificateType, der), nil
+}
+
+// Sign returns a PEM certificate for opts and the public half of the leaf
+// private key, issued by the CA certificate and its private key.
+func Sign(opts Options, leafPrivateKeyText, caCertText, caKeyText string) (string, error) {
+	leafKey, err := parseSigner(leafPrivateKeyText)
+	if err != nil {
+		return "", fmt.Errorf("leaf key: %w", err)
+	}
+	return issue(opts, leafKey.Public(), caCertText, caKeyText)
+}
+
+// SignRequest returns a PEM certificate for the subject, SANs and key of a
+// certificate signing request, issued by the CA. The validity and usages
+// come from opts; its subject and SANs are used only where the request has
+// none.
+func SignRequest(requestText string, opts Options, caCertText, caKeyText string) (string, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(requestText)))
+	if block == nil || (block.Type != RequestType && block.Type != "NEW "+RequestType) {
+		return "", errors.New("failed to decode certificate request")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return "", fmt.Errorf("invalid certificate request signature: %w", err)
+	}
+
+	if len(csr.Subject.Names) > 0 {
+		opts.Subject = csr.Subject
+	}
+	if names := requestSANs(csr); len(names) > 0 {
+		opts.SANs = names
+	}
+	return issue(opts, csr.PublicKey, caCertText, caKeyText)
+}
+
+// CreateCA returns a self-signed CA certificate for opts and the private
+// key; opts.IsCA is implied.
+func CreateCA(opts Options, privateKeyText string) (string, error) {
+	opts.IsCA = true
+	return SelfSign(opts, privateKeyText)
+}
+
+// Bundle joins PEM objects into one file, each ending in a newline, for
+// example a certificate followed by its chain.
+func Bundle(objects ...string) string {
+	var b strings.Builder
+	for _, object := range objects {
+		object = strings.TrimSpace(object)
+		if object == "" {
+			continue
+		}
+		b.WriteString(object)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func issue(opts Options, publicKey crypto.PublicKey, caCertText, caKeyText string) (string, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(caCertText)))
+	if block == nil || block.Type != CertificateType {
+		return "", errors.New("failed to decode CA certificate")
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	if !caCert.IsCA {
+		return "", errors.New("the CA certificate is not a certificate authority")
+	}
+	caKey, err := parseSigner(caKeyText)
+	if err != nil {
+		return "", fmt.Errorf("CA key: %w", err)
+	}
+	if equal, ok := caKey.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !equal.Equal(caCert.PublicKey) {
+		return "", errors.New("the CA key does not match the CA certificate")
+	}
+
+	template, err := newTemplate(opts, publicKey)
+	if err != nil {
+		return "", err
+	}
+	if template.NotAfter.After(caCert.NotAfter) {
+		template.NotAfter = caCert.NotAfter
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, publicKey, caKey)
+	if err != nil {
+		return "", err
+	}
+	return encode(CertificateType, der), nil
+}
+
+func newTemplate(opts Options, publicKey crypto.PublicKey) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return nil, err
+	}
+	days := opts.Days
+	if days == 0 {
+		days = DefaultDays
+	}
+	if days < 0 {
+		return nil, fmt.Errorf("validity must be positive, got %d days", days)
+	}
+	// Backdate slightly so clocks running behind accept new certificates.
+	notBefore := time.Now().Add(-5 * time.Minute)
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               opts.Subject,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.AddDate(0, 0, days),
+		BasicConstraintsValid: true,
+		IsCA:                  opts.IsCA,
+	}
+	if err := setSANs(opts, &template.DNSNames, &template.EmailAddresses, &template.IPAddresses, &template.URIs); err != nil {
+		return nil, err
+	}
+
+	usages := opts.KeyUsages
+	if usages == nil {
+		usages = defaultKeyUsages(opts.IsCA, publicKey)
+	}
+	for _, usage := range usages {
+		bit, ok := keyUsageBits[usage]
+		if !ok {
+			return nil, fmt.Errorf("unknown key usage %q", usage)
+		}
+		template.KeyUsage |= bit
+	}
+	extUsages := opts.ExtKeyUsages
+	if extUsages == nil && !opts.IsCA {
+		extUsages = []ExtKeyUsage{ServerAuth, ClientAuth}
+	}
+	for _, usage := range extUsages {
+		eku, ok := extKeyUsages[usage]
+		if !ok {
+			return nil, fmt.Errorf("unknown extended key usage %q", usage)
+		}
+		template.ExtKeyUsage = append(template.ExtKeyUsage, eku)
+	}
+	return template, nil
+}
+
+func defaultKeyUsages(isCA bool, publicKey crypto.PublicKey) []KeyUsage {
+	if isCA {
+		return []KeyUsage{CertSign, CRLSign, DigitalSignature}
+	}
+	// RSA key exchange in TLS 1.2 encrypts to the certificate key.
+	if _, ok := publicKey.(*rsa.PublicKey); ok {
+		return []KeyUsage{DigitalSignature, KeyEncipherment}
+	}
+	return []KeyUsage{DigitalSignature}
+}
+
+// setSANs sorts opts.SANs into the typed name lists of a template.
+func setSANs(opts Options, dns, email *[]string, ips *[]net.IP, uris *[]*url.URL) error {
+	names := opts.SANs
+	if len(names) == 0 && !opts.IsCA && opts.Subject.CommonName != "" && !strings.Contains(opts.Subject.CommonName, " ") {
+		names = []string{opts.Subject.CommonName}
+	}
+	for _, name := range names {
+		switch {
+		case net.ParseIP(name) != nil:
+			*ips = append(*ips, net.ParseIP(name))
+		case strings.Contains(name, "://"):
+			uri, err := url.Parse(name)
+			if err != nil {
+				return fmt.Errorf("invalid URI SAN %q: %v", name, err)
+			}
+			*uris = append(*uris, uri)
+		case strings.Contains(name, "@"):
+			*email = append(*email, name)
+		default:
+			*dns = append(*dns, name)
+		}
+	}
+	return nil
+}
+
+func requestSANs(csr *x509.CertificateRequest) []string {
+	names := append(append([]string{}, csr.DNSNames...), csr.EmailAddresses...)
+	for _, ip := range csr.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, uri := range csr.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
+
+// parseSigner reads an RSA, ECDSA or Ed25519 private key.
+func parseSigner(text string) (crypto.Signer, error) {
+	if key, err := rsakeys.ParsePrivateKey(text); err == nil {
+		return key, nil
+	} else if errors.Is(err, rsakeys.ErrPassphraseRequired) {
+		return nil, err
+	}
+	key, curve, err := eckeys.ParsePrivateKey(text)
+	if err != nil {
+		return nil, errors.New("failed to decode private key, expected an unencrypted RSA, ECDSA or Ed25519 key")
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok || !curve.CanSign() {
+		return nil, fmt.Errorf("%s keys cannot sign certificates", curve)
+	}
+	return signer, nil
+}
+
+func encode(blockType string, der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
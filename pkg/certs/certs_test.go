@@ -0,0 +1,299 @@
+package certs
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/rsakeys"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseCertificate(t *testing.T, text string) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode([]byte(text))
+	if block == nil || block.Type != CertificateType {
+		t.Fatalf("not a certificate: %q", text)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func newKey(t *testing.T, keyType KeyType) string {
+	t.Helper()
+	key, err := GenerateKey(keyType)
+	if err != nil {
+		t.Fatalf("GenerateKey(%s): %v", keyType, err)
+	}
+	return key
+}
+
+func TestSelfSign(t *testing.T) {
+	tests := []struct {
+		keyType   KeyType
+		algorithm x509.PublicKeyAlgorithm
+		usage     x509.KeyUsage
+	}{
+		{ECDSAP256, x509.ECDSA, x509.KeyUsageDigitalSignature},
+		{ECDSAP384, x509.ECDSA, x509.KeyUsageDigitalSignature},
+		{Ed25519, x509.Ed25519, x509.KeyUsageDigitalSignature},
+		{RSA2048, x509.RSA, x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.keyType), func(t *testing.T) {
+			opts := Options{Subject: pkix.Name{CommonName: "example.test", Organization: []string{"Example"}}, Days: 30}
+			text, err := SelfSign(opts, newKey(t, tt.keyType))
+			if err != nil {
+				t.Fatalf("SelfSign: %v", err)
+			}
+			cert := parseCertificate(t, text)
+			if cert.PublicKeyAlgorithm != tt.algorithm {
+				t.Errorf("public key algorithm %v, want %v", cert.PublicKeyAlgorithm, tt.algorithm)
+			}
+			if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+				t.Errorf("the certificate is not signed by its own key: %v", err)
+			}
+			if cert.Subject.CommonName != "example.test" || len(cert.DNSNames) != 1 || cert.DNSNames[0] != "example.test" {
+				t.Errorf("subject %v with DNS names %q", cert.Subject, cert.DNSNames)
+			}
+			if cert.KeyUsage != tt.usage || cert.IsCA {
+				t.Errorf("key usage %v, CA %v", cert.KeyUsage, cert.IsCA)
+			}
+			if days := cert.NotAfter.Sub(cert.NotBefore).Hours() / 24; days != 30 {
+				t.Errorf("valid for %v days, want 30", days)
+			}
+		})
+	}
+}
+
+func TestSANs(t *testing.T) {
+	key := newKey(t, ECDSAP256)
+	opts := Options{
+		Subject: pkix.Name{CommonName: "Example Server"},
+		SANs:    ParseSANs("example.test, *.example.test 192.0.2.1\n::1\tadmin@example.test,spiffe://example.test/service"),
+	}
+	for _, create := range []struct {
+		name string
+		fn   func() (dns, email, ips, uris []string)
+	}{
+		{"certificate", func() (dns, email, ips, uris []string) {
+			text, err := SelfSign(opts, key)
+			if err != nil {
+				t.Fatalf("SelfSign: %v", err)
+			}
+			cert := parseCertificate(t, text)
+			for _, ip := range cert.IPAddresses {
+				ips = append(ips, ip.String())
+			}
+			for _, uri := range cert.URIs {
+				uris = append(uris, uri.String())
+			}
+			return cert.DNSNames, cert.EmailAddresses, ips, uris
+		}},
+		{"request", func() (dns, email, ips, uris []string) {
+			text, err := CreateRequest(opts, key)
+			if err != nil {
+				t.Fatalf("CreateRequest: %v", err)
+			}
+			block, _ := pem.Decode([]byte(text))
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, ip := range csr.IPAddresses {
+				ips = append(ips, ip.String())
+			}
+			for _, uri := range csr.URIs {
+				uris = append(uris, uri.String())
+			}
+			return csr.DNSNames, csr.EmailAddresses, ips, uris
+		}},
+	} {
+		t.Run(create.name, func(t *testing.T) {
+			dns, email, ips, uris := create.fn()
+			got := strings.Join([]string{strings.Join(dns, ","), strings.Join(email, ","), strings.Join(ips, ","), strings.Join(uris, ",")}, " | ")
+			want := "example.test,*.example.test | admin@example.test | 192.0.2.1,::1 | spiffe://example.test/service"
+			if got != want {
+				t.Errorf("SANs %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	caKey := newKey(t, ECDSAP384)
+	caCert, err := CreateCA(Options{Subject: pkix.Name{CommonName: "Test CA"}, Days: 10}, caKey)
+	if err != nil {
+		t.Fatalf("CreateCA: %v", err)
+	}
+	ca := parseCertificate(t, caCert)
+	if !ca.IsCA || ca.KeyUsage&x509.KeyUsageCertSign == 0 || len(ca.DNSNames) != 0 {
+		t.Fatalf("CA certificate: CA %v, key usage %v, DNS names %q", ca.IsCA, ca.KeyUsage, ca.DNSNames)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	leafKey := newKey(t, RSA2048)
+	request, err := CreateRequest(Options{Subject: pkix.Name{CommonName: "from-request.test"}, SANs: []string{"from-request.test", "127.0.0.1"}}, leafKey)
+	if err != nil {
+		t.Fatalf("CreateRequest: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		issue func() (string, error)
+		host  string
+	}{
+		{"sign a key", func() (string, error) {
+			return Sign(Options{Subject: pkix.Name{CommonName: "leaf.test"}, Days: 365}, leafKey, caCert, caKey)
+		}, "leaf.test"},
+		{"sign a request", func() (string, error) {
+			return SignRequest(request, Options{Subject: pkix.Name{CommonName: "ignored.test"}, Days: 5}, caCert, caKey)
+		}, "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := tt.issue()
+			if err != nil {
+				t.Fatalf("issuing: %v", err)
+			}
+			leaf := parseCertificate(t, text)
+			_, err = leaf.Verify(x509.VerifyOptions{DNSName: tt.host, Roots: roots})
+			if err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if leaf.NotAfter.After(ca.NotAfter) {
+				t.Errorf("the leaf outlives its CA: %v after %v", leaf.NotAfter, ca.NotAfter)
+			}
+			if leaf.Issuer.CommonName != "Test CA" {
+				t.Errorf("issuer %v", leaf.Issuer)
+			}
+		})
+	}
+
+	bundle := Bundle(caCert, "", "  \n", caCert)
+	if strings.Count(bundle, "-----BEGIN CERTIFICATE-----") != 2 || !strings.HasSuffix(bundle, "-----\n") {
+		t.Errorf("Bundle() = %q", bundle)
+	}
+}
+
+func TestIssueErrors(t *testing.T) {
+	caKey := newKey(t, ECDSAP256)
+	caCert, err := CreateCA(Options{Subject: pkix.Name{CommonName: "Test CA"}}, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafKey := newKey(t, ECDSAP256)
+	leafCert, err := SelfSign(Options{Subject: pkix.Name{CommonName: "leaf.test"}}, leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := CreateRequest(Options{Subject: pkix.Name{CommonName: "leaf.test"}}, leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode([]byte(request))
+	block.Bytes[len(block.Bytes)-1] ^= 1
+	tamperedRequest := string(pem.EncodeToMemory(block))
+	encryptedKey, _, err := rsakeys.GenerateWithPassphrase(2048, rsakeys.PKCS8, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x25519Key, _, err := eckeys.Generate(eckeys.X25519, rsakeys.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		issue   func() (string, error)
+		wantErr string
+	}{
+		{"CA is not a CA", func() (string, error) {
+			return Sign(Options{}, leafKey, leafCert, leafKey)
+		}, "not a certificate authority"},
+		{"CA key does not match", func() (string, error) {
+			return Sign(Options{}, leafKey, caCert, leafKey)
+		}, "does not match the CA certificate"},
+		{"CA certificate is not PEM", func() (string, error) {
+			return Sign(Options{}, leafKey, "garbage", caKey)
+		}, "failed to decode CA certificate"},
+		{"tampered request", func() (string, error) {
+			return SignRequest(tamperedRequest, Options{}, caCert, caKey)
+		}, "invalid certificate request signature"},
+		{"not a request", func() (string, error) {
+			return SignRequest(caCert, Options{}, caCert, caKey)
+		}, "failed to decode certificate request"},
+		{"encrypted key", func() (string, error) {
+			return SelfSign(Options{}, encryptedKey)
+		}, rsakeys.ErrPassphraseRequired.Error()},
+		{"X25519 key", func() (string, error) {
+			return SelfSign(Options{}, x25519Key)
+		}, "X25519 keys cannot sign"},
+		{"negative validity", func() (string, error) {
+			return SelfSign(Options{Days: -1}, leafKey)
+		}, "validity must be positive"},
+		{"unknown key usage", func() (string, error) {
+			return SelfSign(Options{KeyUsages: []KeyUsage{"teleport"}}, leafKey)
+		}, "unknown key usage"},
+		{"unknown extended key usage", func() (string, error) {
+			return SelfSign(Options{ExtKeyUsages: []ExtKeyUsage{"teleport"}}, leafKey)
+		}, "unknown extended key usage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.issue()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := SelfSign(Options{}, encryptedKey); !errors.Is(err, rsakeys.ErrPassphraseRequired) {
+		t.Errorf("SelfSign with an encrypted key = %v, want ErrPassphraseRequired", err)
+	}
+}
+
+func TestExplicitUsages(t *testing.T) {
+	opts := Options{
+		Subject:      pkix.Name{CommonName: "signer"},
+		KeyUsages:    []KeyUsage{DigitalSignature, ContentCommitment},
+		ExtKeyUsages: []ExtKeyUsage{CodeSigning, TimeStamping},
+	}
+	text, err := SelfSign(opts, newKey(t, Ed25519))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := parseCertificate(t, text)
+	if cert.KeyUsage != x509.KeyUsageDigitalSignature|x509.KeyUsageContentCommitment {
+		t.Errorf("key usage %v", cert.KeyUsage)
+	}
+	if len(cert.ExtKeyUsage) != 2 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageCodeSigning || cert.ExtKeyUsage[1] != x509.ExtKeyUsageTimeStamping {
+		t.Errorf("extended key usage %v", cert.ExtKeyUsage)
+	}
+	if until := time.Until(cert.NotAfter); until < (DefaultDays-1)*24*time.Hour {
+		t.Errorf("default validity ends in %v", until)
+	}
+}
+
+func TestParseSANs(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"a.test", "a.test"},
+		{"a.test,b.test", "a.test|b.test"},
+		{" a.test ,\r\nb.test\t\tc.test ", "a.test|b.test|c.test"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(ParseSANs(tt.text), "|"); got != tt.want {
+			t.Errorf("ParseSANs(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
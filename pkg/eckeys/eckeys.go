@@ -0,0 +1,290 @@
+// Package eckeys generates elliptic-curve key pairs (ECDSA P-256, P-384 and
+// P-521, Ed25519 and X25519), encodes them as PKCS#8/PKIX PEM or OpenSSH,
+// signs and verifies data, and derives ECDH shared secrets. Functions taking
+// a key as text accept either format, and SEC 1 "EC PRIVATE KEY" PEM.
+package eckeys
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"joshu/pkg/rsakeys"
+	"strings"
+)
+
+// Curve is an elliptic-curve key type.
+type Curve string
+
+// Supported curves.
+const (
+	P256    Curve = "P-256"
+	P384    Curve = "P-384"
+	P521    Curve = "P-521"
+	Ed25519 Curve = "Ed25519"
+	X25519  Curve = "X25519"
+)
+
+// Curves lists the curves in display order.
+var Curves = []Curve{P256, P384, P521, Ed25519, X25519}
+
+// Formats lists the key formats supported by Encode, in display order.
+var Formats = []rsakeys.KeyFormat{rsakeys.PKCS8, rsakeys.OpenSSH}
+
+// SEC1PrivateKeyType is the PEM block type of an OpenSSL "traditional" EC key.
+const SEC1PrivateKeyType = "EC PRIVATE KEY"
+
+var (
+	// ErrInvalidPublicKey is returned when the public key is not in a supported format.
+	ErrInvalidPublicKey = errors.New("failed to decode public key")
+	// ErrInvalidPrivateKey is returned when the private key is not in a supported format.
+	ErrInvalidPrivateKey = errors.New("failed to decode private key")
+	// ErrVerification is returned by Verify when the signature does not match.
+	ErrVerification = errors.New("signature does not match")
+)
+
+// Label returns a human readable name for the curve.
+func (c Curve) Label() string {
+	switch c {
+	case P256, P384, P521:
+		return "ECDSA " + string(c)
+	default:
+		return string(c)
+	}
+}
+
+// CanSign reports whether keys on the curve sign data. X25519 keys only
+// agree on shared secrets.
+func (c Curve) CanSign() bool {
+	return c != X25519
+}
+
+// CanAgree reports whether keys on the curve derive ECDH shared secrets.
+// Ed25519 keys only sign.
+func (c Curve) CanAgree() bool {
+	return c != Ed25519
+}
+
+// Hash returns the digest ECDSA signatures on the curve use. Ed25519 hashes
+// internally and returns 0.
+func (c Curve) Hash() crypto.Hash {
+	switch c {
+	case P256:
+		return crypto.SHA256
+	case P384:
+		return crypto.SHA384
+	case P521:
+		return crypto.SHA512
+	default:
+		return 0
+	}
+}
+
+func (c Curve) elliptic() (elliptic.Curve, bool) {
+	switch c {
+	case P256:
+		return elliptic.P256(), true
+	case P384:
+		return elliptic.P384(), true
+	case P521:
+		return elliptic.P521(), true
+	default:
+		return nil, false
+	}
+}
+
+// Generate creates a key pair on curve and returns the private and public
+// keys encoded in format.
+func Generate(curve Curve, format rsakeys.KeyFormat) (string, string, error) {
+	var privateKey crypto.PrivateKey
+	var err error
+	if ec, ok := curve.elliptic(); ok {
+		privateKey, err = ecdsa.GenerateKey(ec, rand.Reader)
+	} else {
+		switch curve {
+		case Ed25519:
+			_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+		case X25519:
+			privateKey, err = ecdh.X25519().GenerateKey(rand.Reader)
+		default:
+			err = fmt.Errorf("unknown curve %q", curve)
+		}
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	privateKeyText, err := EncodePrivateKey(privateKey, format)
+	if err != nil {
+		return "", "", err
+	}
+	publicKeyText, err := EncodePublicKey(publicOf(privateKey), format)
+	if err != nil {
+		return "", "", err
+	}
+	return privateKeyText, publicKeyText, nil
+}
+
+// EncodePrivateKey encodes an *ecdsa.PrivateKey, ed25519.PrivateKey or
+// X25519 *ecdh.PrivateKey in format. X25519 keys have no OpenSSH encoding.
+func EncodePrivateKey(key crypto.PrivateKey, format rsakeys.KeyFormat) (string, error) {
+	switch format {
+	case rsakeys.PKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: rsakeys.PKCS8PrivateKeyType, Bytes: der})), nil
+	case rsakeys.OpenSSH:
+		if _, ok := key.(*ecdh.PrivateKey); ok {
+			return "", errors.New("X25519 keys have no OpenSSH format, use PKCS#8")
+		}
+		block, err := ssh.MarshalPrivateKey(key, "")
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(block)), nil
+	default:
+		return "", fmt.Errorf("unsupported key format %q, use pkcs8 or openssh", format)
+	}
+}
+
+// EncodePublicKey encodes the public half of a key accepted by
+// EncodePrivateKey in format.
+func EncodePublicKey(key crypto.PublicKey, format rsakeys.KeyFormat) (string, error) {
+	switch format {
+	case rsakeys.PKCS8:
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: rsakeys.PKIXPublicKeyType, Bytes: der})), nil
+	case rsakeys.OpenSSH:
+		if _, ok := key.(*ecdh.PublicKey); ok {
+			return "", errors.New("X25519 keys have no OpenSSH format, use PKCS#8")
+		}
+		sshKey, err := ssh.NewPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		return string(ssh.MarshalAuthorizedKey(sshKey)), nil
+	default:
+		return "", fmt.Errorf("unsupported key format %q, use pkcs8 or openssh", format)
+	}
+}
+
+// ParsePrivateKey reads an elliptic-curve private key and returns it with
+// its curve.
+func ParsePrivateKey(text string) (crypto.PrivateKey, Curve, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(text)))
+	if block == nil {
+		return nil, "", ErrInvalidPrivateKey
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case rsakeys.PKCS8PrivateKeyType:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case SEC1PrivateKeyType:
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case rsakeys.OpenSSHPrivateKeyType:
+		key, err = ssh.ParseRawPrivateKey(pem.EncodeToMemory(block))
+	default:
+		return nil, "", fmt.Errorf("%w: unsupported PEM type %q", ErrInvalidPrivateKey, block.Type)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	// The ssh package returns Ed25519 keys by pointer.
+	if k, ok := key.(*ed25519.PrivateKey); ok {
+		key = *k
+	}
+	curve, err := curveOf(publicOf(key))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	return key, curve, nil
+}
+
+// ParsePublicKey reads an elliptic-curve public key and returns it with its
+// curve. A private key is accepted too, and its public half returned.
+func ParsePublicKey(text string) (crypto.PublicKey, Curve, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "ssh-") || strings.HasPrefix(text, "ecdsa-") {
+		sshKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(text))
+		if err != nil {
+			return nil, "", err
+		}
+		cryptoKey, ok := sshKey.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, "", fmt.Errorf("%w: unsupported SSH key", ErrInvalidPublicKey)
+		}
+		return checkPublicKey(cryptoKey.CryptoPublicKey())
+	}
+
+	block, _ := pem.Decode([]byte(text))
+	if block == nil {
+		return nil, "", ErrInvalidPublicKey
+	}
+	switch block.Type {
+	case rsakeys.PKIXPublicKeyType:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, "", err
+		}
+		return checkPublicKey(key)
+	case rsakeys.PKCS8PrivateKeyType, SEC1PrivateKeyType, rsakeys.OpenSSHPrivateKeyType:
+		key, curve, err := ParsePrivateKey(text)
+		if err != nil {
+			return nil, "", err
+		}
+		return publicOf(key), curve, nil
+	default:
+		return nil, "", fmt.Errorf("%w: unsupported PEM type %q", ErrInvalidPublicKey, block.Type)
+	}
+}
+
+func checkPublicKey(key crypto.PublicKey) (crypto.PublicKey, Curve, error) {
+	curve, err := curveOf(key)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	return key, curve, nil
+}
+
+// publicOf returns the public half of a private key, or nil.
+func publicOf(key crypto.PrivateKey) crypto.PublicKey {
+	signer, ok := key.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return nil
+	}
+	return signer.Public()
+}
+
+func curveOf(key crypto.PublicKey) (Curve, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return P256, nil
+		case elliptic.P384():
+			return P384, nil
+		case elliptic.P521():
+			return P521, nil
+		}
+	case ed25519.PublicKey:
+		return Ed25519, nil
+	case *ecdh.PublicKey:
+		if k.Curve() == ecdh.X25519() {
+			return X25519, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported key type %T", key)
+}
@@ -0,0 +1,244 @@
+package eckeys
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"joshu/pkg/rsakeys"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestGenerateRoundTrip(t *testing.T) {
+	for _, curve := range Curves {
+		for _, format := range Formats {
+			t.Run(string(curve)+" "+string(format), func(t *testing.T) {
+				privateText, publicText, err := Generate(curve, format)
+				if curve == X25519 && format == rsakeys.OpenSSH {
+					if err == nil {
+						t.Fatal("Generate made an OpenSSH X25519 key")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Generate: %v", err)
+				}
+				key, got, err := ParsePrivateKey(privateText)
+				if err != nil || got != curve {
+					t.Fatalf("ParsePrivateKey() curve %q, %v", got, err)
+				}
+				public, got, err := ParsePublicKey(publicText)
+				if err != nil || got != curve {
+					t.Fatalf("ParsePublicKey() curve %q, %v", got, err)
+				}
+				if fromPrivate, _, err := ParsePublicKey(privateText); err != nil || !equalPublic(fromPrivate, public) {
+					t.Errorf("ParsePublicKey of the private key: %v", err)
+				}
+				if !equalPublic(publicOf(key), public) {
+					t.Error("the keys are not a pair")
+				}
+
+				for _, other := range Formats {
+					reencoded, err := EncodePrivateKey(key, other)
+					if curve == X25519 && other == rsakeys.OpenSSH {
+						continue
+					}
+					if err != nil {
+						t.Fatalf("EncodePrivateKey(%s): %v", other, err)
+					}
+					if again, _, err := ParsePrivateKey(reencoded); err != nil || !equalPublic(publicOf(again), public) {
+						t.Errorf("round trip through %s: %v", other, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func equalPublic(a, b any) bool {
+	k, ok := a.(interface{ Equal(x crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+func TestSignVerify(t *testing.T) {
+	data := []byte("signed data")
+	for _, curve := range Curves {
+		t.Run(string(curve), func(t *testing.T) {
+			privateText, publicText, err := Generate(curve, rsakeys.PKCS8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature, err := Sign(data, privateText)
+			if !curve.CanSign() {
+				if err == nil {
+					t.Error("Sign succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			if err := Verify(data, signature, publicText); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if err := Verify(data, " "+signature+"\n", publicText); err != nil {
+				t.Errorf("Verify of a signature pasted with whitespace: %v", err)
+			}
+			if err := Verify([]byte("other data"), signature, publicText); !errors.Is(err, ErrVerification) {
+				t.Errorf("Verify of other data = %v, want ErrVerification", err)
+			}
+			raw, _ := base64.StdEncoding.DecodeString(signature)
+			raw[len(raw)-1] ^= 1
+			if err := Verify(data, base64.StdEncoding.EncodeToString(raw), publicText); !errors.Is(err, ErrVerification) {
+				t.Errorf("Verify of a tampered signature = %v, want ErrVerification", err)
+			}
+			_, otherPublic, _ := Generate(curve, rsakeys.OpenSSH)
+			if err := Verify(data, signature, otherPublic); !errors.Is(err, ErrVerification) {
+				t.Errorf("Verify with another key = %v, want ErrVerification", err)
+			}
+		})
+	}
+}
+
+// TestVerifyOpenSSLSignature checks a signature made with
+// "openssl dgst -sha256 -sign" over "signed by openssl".
+func TestVerifyOpenSSLSignature(t *testing.T) {
+	signature := strings.TrimSpace(readTestdata(t, "openssl_signature.txt"))
+	public := readTestdata(t, "openssl_public.pem")
+	if err := Verify([]byte("signed by openssl"), signature, public); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if err := Verify([]byte("signed by someone else"), signature, public); !errors.Is(err, ErrVerification) {
+		t.Errorf("Verify of other data = %v, want ErrVerification", err)
+	}
+}
+
+func TestSharedSecret(t *testing.T) {
+	for _, curve := range Curves {
+		t.Run(string(curve), func(t *testing.T) {
+			alicePrivate, alicePublic, err := Generate(curve, rsakeys.PKCS8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bobPrivate, bobPublic, err := Generate(curve, rsakeys.PKCS8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			alice, err := SharedSecret(alicePrivate, bobPublic)
+			if !curve.CanAgree() {
+				if err == nil {
+					t.Error("SharedSecret succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SharedSecret: %v", err)
+			}
+			bob, err := SharedSecret(bobPrivate, alicePublic)
+			if err != nil {
+				t.Fatalf("SharedSecret: %v", err)
+			}
+			if !bytes.Equal(alice, bob) {
+				t.Error("the two sides derived different secrets")
+			}
+		})
+	}
+
+	p256, _, _ := Generate(P256, rsakeys.PKCS8)
+	_, x25519, _ := Generate(X25519, rsakeys.PKCS8)
+	if _, err := SharedSecret(p256, x25519); err == nil {
+		t.Error("SharedSecret accepted keys on different curves")
+	}
+}
+
+// TestEncryptedKeys unlocks keys encrypted by OpenSSL and ssh-keygen with the
+// passphrase "secret" through rsakeys.UnlockPrivateKey.
+func TestEncryptedKeys(t *testing.T) {
+	tests := []struct {
+		file  string
+		curve Curve
+	}{
+		{"openssl_pkcs8.pem", P256},
+		{"openssl_sec1_encrypted.pem", P256},
+		{"ssh_ed25519", Ed25519},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			text := readTestdata(t, tt.file)
+			if _, _, err := ParsePrivateKey(text); err == nil {
+				t.Error("ParsePrivateKey read an encrypted key")
+			}
+			if _, err := rsakeys.UnlockPrivateKey(text, "wrong"); !errors.Is(err, rsakeys.ErrIncorrectPassphrase) {
+				t.Errorf("wrong passphrase error = %v, want ErrIncorrectPassphrase", err)
+			}
+			unlocked, err := rsakeys.UnlockPrivateKey(text, "secret")
+			if err != nil {
+				t.Fatalf("UnlockPrivateKey: %v", err)
+			}
+			_, curve, err := ParsePrivateKey(unlocked)
+			if err != nil || curve != tt.curve {
+				t.Fatalf("ParsePrivateKey() curve %q, %v", curve, err)
+			}
+			signature, err := Sign([]byte("data"), unlocked)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			if err := Verify([]byte("data"), signature, unlocked); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+		})
+	}
+
+	plain, _, err := ParsePrivateKey(readTestdata(t, "openssl_sec1.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, _, err := ParsePublicKey(readTestdata(t, "openssl_public.pem"))
+	if err != nil || !equalPublic(publicOf(plain), public) {
+		t.Errorf("the OpenSSL key pair does not match: %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	rsaPrivate, rsaPublic, err := rsakeys.GenerateFormat(2048, rsakeys.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		text   string
+		public bool
+		target error
+	}{
+		{"garbage private", "not a key", false, ErrInvalidPrivateKey},
+		{"garbage public", "not a key", true, ErrInvalidPublicKey},
+		{"RSA private key", rsaPrivate, false, ErrInvalidPrivateKey},
+		{"RSA public key", rsaPublic, true, ErrInvalidPublicKey},
+		{"unsupported PEM", "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----", true, ErrInvalidPublicKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.public {
+				_, _, err = ParsePublicKey(tt.text)
+			} else {
+				_, _, err = ParsePrivateKey(tt.text)
+			}
+			if !errors.Is(err, tt.target) {
+				t.Errorf("error = %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package eckeys
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Sign signs data with an ECDSA or Ed25519 private key and returns the
+// signature encoded as standard base64. ECDSA signatures are ASN.1 DER over
+// the digest returned by Curve.Hash.
+func Sign(data []byte, privateKeyText string) (string, error) {
+	key, curve, err := ParsePrivateKey(privateKeyText)
+	if err != nil {
+		return "", err
+	}
+
+	var signature []byte
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		h := curve.Hash().New()
+		h.Write(data)
+		signature, err = ecdsa.SignASN1(rand.Reader, k, h.Sum(nil))
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, data)
+	default:
+		err = fmt.Errorf("%s keys cannot sign", curve)
+	}
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks a base64 signature made by Sign against data with the
+// public key. It returns ErrVerification if the signature does not match.
+func Verify(data []byte, signatureBase64, publicKeyText string) error {
+	key, curve, err := ParsePublicKey(publicKeyText)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signatureBase64))
+	if err != nil {
+		return err
+	}
+
+	var ok bool
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		h := curve.Hash().New()
+		h.Write(data)
+		ok = ecdsa.VerifyASN1(k, h.Sum(nil), signature)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, data, signature)
+	default:
+		return fmt.Errorf("%s keys cannot verify signatures", curve)
+	}
+	if !ok {
+		return ErrVerification
+	}
+	return nil
+}
+
+// SharedSecret derives the ECDH shared secret between an ECDSA or X25519
+// private key and a peer public key on the same curve. Both sides of an
+// exchange derive the same bytes; use them through a KDF such as HKDF rather
+// than directly as a key.
+func SharedSecret(privateKeyText, peerPublicKeyText string) ([]byte, error) {
+	key, curve, err := ParsePrivateKey(privateKeyText)
+	if err != nil {
+		return nil, err
+	}
+	peer, peerCurve, err := ParsePublicKey(peerPublicKeyText)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.CanAgree() {
+		return nil, fmt.Errorf("%s keys cannot derive shared secrets, use X25519", curve)
+	}
+	if curve != peerCurve {
+		return nil, fmt.Errorf("the private key is %s but the peer key is %s", curve, peerCurve)
+	}
+
+	var private *ecdh.PrivateKey
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		if private, err = k.ECDH(); err != nil {
+			return nil, err
+		}
+	case *ecdh.PrivateKey:
+		private = k
+	}
+	var public *ecdh.PublicKey
+	switch k := peer.(type) {
+	case *ecdsa.PublicKey:
+		if public, err = k.ECDH(); err != nil {
+			return nil, err
+		}
+	case *ecdh.PublicKey:
+		public = k
+	}
+	return private.ECDH(public)
+}
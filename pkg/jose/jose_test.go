@@ -0,0 +1,388 @@
+package jose
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/jwk"
+	"joshu/pkg/rsakeys"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	rsaOnce sync.Once
+	rsaText string
+	rsaErr  error
+)
+
+// testKey returns a new private key or secret suiting alg. The RSA key is
+// made once and shared.
+func testKey(t *testing.T, alg Algorithm) jwk.Key {
+	t.Helper()
+	var text string
+	var err error
+	switch alg {
+	case HS256, HS384, HS512, Dir:
+		return jwk.Key{Key: []byte("a 32 byte secret for the tests!!")}
+	case RS256, RS384, RS512, PS256, PS384, PS512, RSAOAEP256:
+		rsaOnce.Do(func() { rsaText, _, rsaErr = rsakeys.GenerateFormat(2048, rsakeys.PKCS8) })
+		text, err = rsaText, rsaErr
+	case ES256, ECDHES:
+		text, _, err = eckeys.Generate(eckeys.P256, rsakeys.PKCS8)
+	case ES384:
+		text, _, err = eckeys.Generate(eckeys.P384, rsakeys.PKCS8)
+	case ES512:
+		text, _, err = eckeys.Generate(eckeys.P521, rsakeys.PKCS8)
+	case EdDSA:
+		text, _, err = eckeys.Generate(eckeys.Ed25519, rsakeys.PKCS8)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseKeys(text, "")
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	return keys[0]
+}
+
+func publicOnly(key jwk.Key) jwk.Key {
+	return jwk.Key{Key: jwk.Public(key.Key), Params: key.Params}
+}
+
+func TestSignVerifyToken(t *testing.T) {
+	payload := []byte(`{"sub": "alice", "admin": true}`)
+	for _, alg := range Algorithms {
+		t.Run(string(alg), func(t *testing.T) {
+			key := testKey(t, alg)
+			key.KeyID = "key-1"
+			token, err := SignToken([]byte(`{"typ":"JWT"}`), payload, alg, []jwk.Key{key})
+			if err != nil {
+				t.Fatalf("SignToken: %v", err)
+			}
+			decoded, err := DecodeToken(token)
+			if err != nil {
+				t.Fatalf("DecodeToken: %v", err)
+			}
+			if decoded.Alg != alg || decoded.KeyID != "key-1" || string(decoded.Payload) != `{"sub":"alice","admin":true}` {
+				t.Errorf("DecodeToken() = alg %q kid %q payload %s", decoded.Alg, decoded.KeyID, decoded.Payload)
+			}
+
+			verifier := publicOnly(key)
+			if _, secret := key.Key.([]byte); secret {
+				verifier = key
+			}
+			if got, err := decoded.Verify([]jwk.Key{testKey(t, EdDSA), verifier}); err != nil || got.KeyID != "key-1" {
+				t.Errorf("Verify() = %q, %v", got.KeyID, err)
+			}
+
+			parts := strings.Split(token, ".")
+			forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory","admin":true}`)) + "." + parts[2]
+			if tampered, err := DecodeToken(forged); err != nil {
+				t.Fatal(err)
+			} else if _, err := tampered.Verify([]jwk.Key{verifier}); !errors.Is(err, ErrSignature) {
+				t.Errorf("Verify of a tampered payload = %v, want ErrSignature", err)
+			}
+
+			other := publicOnly(testKey(t, EdDSA))
+			switch k := key.Key.(type) {
+			case []byte:
+				other.Key = append([]byte("x"), k...)
+			case *rsa.PrivateKey:
+				// The RSA test key is shared, so flip the modulus of a copy.
+				n := new(big.Int).Xor(k.N, big.NewInt(2))
+				other.Key = &rsa.PublicKey{N: n, E: k.E}
+			default:
+				other = publicOnly(testKey(t, alg))
+			}
+			if _, err := decoded.Verify([]jwk.Key{other}); !errors.Is(err, ErrSignature) {
+				t.Errorf("Verify with another key = %v, want ErrSignature", err)
+			}
+		})
+	}
+}
+
+// TestRFC7515 verifies the HS256 example of RFC 7515 appendix A.1.
+func TestRFC7515(t *testing.T) {
+	const token = "eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9" +
+		".eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ" +
+		".dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	keys, err := ParseKeys(`{"kty":"oct","k":"AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow"}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeToken(token)
+	if err != nil {
+		t.Fatalf("DecodeToken: %v", err)
+	}
+	if _, err := decoded.Verify(keys); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	claims := TimeClaims(decoded.Payload)
+	if len(claims) != 1 || claims[0].Name != "exp" || claims[0].Time.Unix() != 1300819380 {
+		t.Errorf("TimeClaims() = %v", claims)
+	}
+}
+
+func TestVerifyRejectsUnsigned(t *testing.T) {
+	secret := testKey(t, HS256)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory"}`))
+	tests := []struct {
+		name    string
+		header  string
+		wantErr string
+	}{
+		{"none", `{"alg":"none"}`, "unsigned"},
+		{"None", `{"alg":"None"}`, "unsigned"},
+		{"missing alg", `{"typ":"JWT"}`, "unsigned"},
+		{"unknown alg", `{"alg":"HS1"}`, "unsupported algorithm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := base64.RawURLEncoding.EncodeToString([]byte(tt.header)) + "." + payload + "."
+			decoded, err := DecodeToken(token)
+			if err != nil {
+				t.Fatalf("DecodeToken: %v", err)
+			}
+			if _, err := decoded.Verify([]jwk.Key{secret}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Token.Verify() error = %v, want %q", err, tt.wantErr)
+			}
+			object, err := ParseObject(token)
+			if err != nil {
+				t.Fatalf("ParseObject: %v", err)
+			}
+			if _, _, err := object.Verify([]jwk.Key{secret}); err == nil {
+				t.Error("Object.Verify accepted an unsigned JWS")
+			}
+		})
+	}
+}
+
+func TestSignJSON(t *testing.T) {
+	first, second := testKey(t, ES256), testKey(t, ES256)
+	first.KeyID, second.KeyID = "first", "second"
+	text, err := SignJSON(nil, []byte("hello"), ES256, []jwk.Key{first, second, testKey(t, EdDSA)})
+	if err != nil {
+		t.Fatalf("SignJSON: %v", err)
+	}
+	object, err := ParseObject(text)
+	if err != nil {
+		t.Fatalf("ParseObject: %v", err)
+	}
+	if object.Serialization != GeneralJSON || len(object.Algorithms()) != 2 {
+		t.Fatalf("ParseObject() = %s with %v", object.Serialization, object.Algorithms())
+	}
+
+	payload, verified, err := object.Verify([]jwk.Key{publicOnly(second)})
+	if err != nil || string(payload) != "hello" {
+		t.Fatalf("Verify() = %q, %v", payload, err)
+	}
+	if verified[0] != nil || verified[1] == nil || verified[1].KeyID != "second" {
+		t.Errorf("Verify() verified %v, want only the second signature", verified)
+	}
+	if _, _, err := object.Verify([]jwk.Key{testKey(t, ES256)}); !errors.Is(err, ErrSignature) {
+		t.Errorf("Verify with another key = %v, want ErrSignature", err)
+	}
+
+	// The flattened form of the first signature.
+	var general struct {
+		Payload    string            `json:"payload"`
+		Signatures []json.RawMessage `json:"signatures"`
+	}
+	if err := json.Unmarshal([]byte(text), &general); err != nil {
+		t.Fatal(err)
+	}
+	flattened := `{"payload":"` + general.Payload + `",` + strings.TrimPrefix(string(general.Signatures[0]), "{")
+	object, err = ParseObject(flattened)
+	if err != nil {
+		t.Fatalf("ParseObject of the flattened JWS: %v", err)
+	}
+	if _, _, err := object.Verify([]jwk.Key{publicOnly(first)}); object.Serialization != FlattenedJSON || err != nil {
+		t.Errorf("Verify of the flattened JWS (%s): %v", object.Serialization, err)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	x25519Text, _, err := eckeys.Generate(eckeys.X25519, rsakeys.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x25519, err := ParseKeys(x25519Text, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		alg  Algorithm
+		key  jwk.Key
+	}{
+		{"RSA-OAEP-256", RSAOAEP256, testKey(t, RSAOAEP256)},
+		{"dir", Dir, testKey(t, Dir)},
+		{"ECDH-ES P-256", ECDHES, testKey(t, ECDHES)},
+		{"ECDH-ES X25519", ECDHES, x25519[0]},
+	}
+	plaintext := []byte("attack at dawn")
+	for _, tt := range tests {
+		for _, serialization := range []Serialization{Compact, GeneralJSON} {
+			t.Run(tt.name+" "+string(serialization), func(t *testing.T) {
+				if got := KeyAlgorithmFor(tt.key.Key); got != tt.alg {
+					t.Errorf("KeyAlgorithmFor() = %q, want %q", got, tt.alg)
+				}
+				encrypter := publicOnly(tt.key)
+				if tt.alg == Dir {
+					encrypter = tt.key
+				}
+				encrypt := Encrypt
+				if serialization == GeneralJSON {
+					encrypt = EncryptJSON
+				}
+				text, err := encrypt([]byte(`{"cty":"text/plain"}`), plaintext, tt.alg, []jwk.Key{encrypter})
+				if err != nil {
+					t.Fatalf("encrypt: %v", err)
+				}
+				object, err := ParseObject(text)
+				if err != nil {
+					t.Fatalf("ParseObject: %v", err)
+				}
+				if !object.Encrypted || object.Serialization != serialization {
+					t.Fatalf("ParseObject() = %s, encrypted %v", object.Serialization, object.Encrypted)
+				}
+				got, _, err := object.Decrypt([]jwk.Key{tt.key})
+				if err != nil || string(got) != string(plaintext) {
+					t.Fatalf("Decrypt() = %q, %v", got, err)
+				}
+
+				other := testKey(t, tt.alg)
+				if tt.alg == Dir {
+					other.Key = []byte("another 32 byte secret for tests")
+				}
+				// The RSA test key is shared; TestEncryptJSONRecipients
+				// covers a second RSA key.
+				if tt.alg != RSAOAEP256 {
+					if _, _, err := object.Decrypt([]jwk.Key{other}); err == nil {
+						t.Error("Decrypt succeeded with another key")
+					}
+				}
+
+				object.ciphertext[0] ^= 1
+				if _, _, err := object.Decrypt([]jwk.Key{tt.key}); !errors.Is(err, ErrDecrypt) {
+					t.Errorf("Decrypt of tampered ciphertext = %v, want ErrDecrypt", err)
+				}
+			})
+		}
+	}
+}
+
+func TestEncryptJSONRecipients(t *testing.T) {
+	rsaKey := testKey(t, RSAOAEP256)
+	otherText, _, err := rsakeys.GenerateFormat(2048, rsakeys.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ParseKeys(otherText, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey.KeyID, other[0].KeyID = "first", "second"
+
+	text, err := EncryptJSON(nil, []byte("for both"), RSAOAEP256, []jwk.Key{publicOnly(rsaKey), publicOnly(other[0])})
+	if err != nil {
+		t.Fatalf("EncryptJSON: %v", err)
+	}
+	object, err := ParseObject(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []jwk.Key{rsaKey, other[0]} {
+		plaintext, used, err := object.Decrypt([]jwk.Key{key})
+		if err != nil || string(plaintext) != "for both" || used.KeyID != key.KeyID {
+			t.Errorf("Decrypt with %s = %q, %v", key.KeyID, plaintext, err)
+		}
+	}
+
+	secrets := []jwk.Key{testKey(t, Dir), {Key: []byte("another 32 byte secret for tests")}}
+	if _, err := EncryptJSON(nil, []byte("x"), Dir, secrets); err == nil {
+		t.Error("EncryptJSON accepted two dir recipients")
+	}
+	if _, err := Encrypt(nil, []byte("x"), RSAOAEP256, []jwk.Key{testKey(t, EdDSA)}); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Encrypt without a suitable key = %v, want ErrNoKey", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	jwe, err := Encrypt(nil, []byte("x"), Dir, []jwk.Key{testKey(t, Dir)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"one segment", "abc", "not a JWT"},
+		{"JWE", jwe, "this is a JWE"},
+		{"bad base64", "a!b.c.d", "not base64url"},
+		{"header not JSON", "YWJj.e30.", "not JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeToken(tt.token); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DecodeToken() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	objects := []string{"a.b", `{"payload":"e30"}`, `{"foo":1}`, "YWJj.e30.", `{"payload":"e30","signature":"!"}`}
+	for _, text := range objects {
+		if _, err := ParseObject(text); err == nil {
+			t.Errorf("ParseObject(%q) succeeded", text)
+		}
+	}
+}
+
+func TestCheckTimes(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name     string
+		payload  string
+		problems int
+	}{
+		{"valid", `{"iat":1699999000,"nbf":1699999000,"exp":1700001000}`, 0},
+		{"expired", `{"exp":1699999999}`, 1},
+		{"expires now", `{"exp":1700000000}`, 1},
+		{"not yet valid, issued in the future", `{"nbf":1700000100,"iat":1700000100}`, 2},
+		{"fractional", `{"exp":1700000000.5}`, 0},
+		{"not a number", `{"exp":"tomorrow"}`, 0},
+		{"not JSON", `exp`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if problems := CheckTimes([]byte(tt.payload), now); len(problems) != tt.problems {
+				t.Errorf("CheckTimes() = %q, want %d problems", problems, tt.problems)
+			}
+		})
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(" secret with spaces ", "")
+	if err != nil || string(keys[0].Key.([]byte)) != " secret with spaces " {
+		t.Errorf("ParseKeys() = %v, %v, want the secret as typed", keys, err)
+	}
+	if _, err := ParseKeys("  \n", ""); err == nil {
+		t.Error("ParseKeys accepted a blank key")
+	}
+	encrypted, _, err := rsakeys.GenerateWithPassphrase(2048, rsakeys.PKCS8, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseKeys(encrypted, "wrong"); !errors.Is(err, rsakeys.ErrIncorrectPassphrase) {
+		t.Errorf("ParseKeys with a wrong passphrase = %v, want ErrIncorrectPassphrase", err)
+	}
+}
@@ -0,0 +1,366 @@
+package jose
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"joshu/pkg/jwk"
+)
+
+// Supported key management algorithms.
+const (
+	RSAOAEP256 Algorithm = "RSA-OAEP-256"
+	Dir        Algorithm = "dir"
+	ECDHES     Algorithm = "ECDH-ES"
+)
+
+// KeyAlgorithms lists the key management algorithms in display order.
+var KeyAlgorithms = []Algorithm{RSAOAEP256, Dir, ECDHES}
+
+// A256GCM is the supported content encryption algorithm, the enc header.
+const A256GCM = "A256GCM"
+
+// contentKeySize is the size of an A256GCM key.
+const contentKeySize = 32
+
+// ErrDecrypt is returned when no key decrypts a JWE.
+var ErrDecrypt = errors.New("cannot decrypt: wrong key or tampered content")
+
+// KeyAlgorithmFor returns the usual key management algorithm for key.
+func KeyAlgorithmFor(key any) Algorithm {
+	for _, alg := range KeyAlgorithms {
+		if alg.Suits(key) {
+			return alg
+		}
+	}
+	return ""
+}
+
+// direct reports whether the algorithm agrees on the content key itself
+// rather than wrapping a random one, allowing a single recipient.
+func (a Algorithm) direct() bool {
+	return a == Dir || a == ECDHES
+}
+
+// Encrypt returns a compact JWE of plaintext for the first of keys suiting
+// alg, encrypted with A256GCM. The header is a JSON object, which may be
+// empty; its alg and enc are set, and its kid when the key has one and the
+// header does not.
+func Encrypt(header, plaintext []byte, alg Algorithm, keys []jwk.Key) (string, error) {
+	candidates := selectKeys(keys, alg, "")
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w %s", ErrNoKey, alg)
+	}
+	key := candidates[0]
+	fields, err := headerFields(header)
+	if err != nil {
+		return "", err
+	}
+	fields["alg"], fields["enc"] = alg, A256GCM
+	if _, ok := fields["kid"]; !ok && key.KeyID != "" {
+		fields["kid"] = key.KeyID
+	}
+
+	cek, encryptedKey, err := wrapKey(alg, key.Key, nil, fields)
+	if err != nil {
+		return "", err
+	}
+	protected, err := encodeHeader(fields)
+	if err != nil {
+		return "", err
+	}
+	iv, ciphertext, tag, err := sealContent(cek, plaintext, []byte(protected))
+	if err != nil {
+		return "", err
+	}
+	return protected + "." + encode(encryptedKey) + "." + encode(iv) + "." + encode(ciphertext) + "." + encode(tag), nil
+}
+
+// EncryptJSON returns a JWE of plaintext in general JSON serialization
+// with a recipient for every key suiting alg. The header goes into the
+// protected header with enc; each recipient's alg and kid go into its own
+// header. dir and ECDH-ES use the agreed key as content key, so they allow
+// one recipient.
+func EncryptJSON(header, plaintext []byte, alg Algorithm, keys []jwk.Key) (string, error) {
+	candidates := selectKeys(keys, alg, "")
+	switch {
+	case len(candidates) == 0:
+		return "", fmt.Errorf("%w %s", ErrNoKey, alg)
+	case len(candidates) > 1 && alg.direct():
+		return "", fmt.Errorf("%s allows one recipient, %d keys suit it", alg, len(candidates))
+	}
+	fields, err := headerFields(header)
+	if err != nil {
+		return "", err
+	}
+	fields["enc"] = A256GCM
+	protected, err := encodeHeader(fields)
+	if err != nil {
+		return "", err
+	}
+
+	var cek []byte
+	if !alg.direct() {
+		cek = make([]byte, contentKeySize)
+		if _, err := rand.Read(cek); err != nil {
+			return "", err
+		}
+	}
+	var recipients []jsonRecipient
+	for _, key := range candidates {
+		recipient := map[string]any{"alg": alg}
+		if key.KeyID != "" {
+			recipient["kid"] = key.KeyID
+		}
+		// The KDF of ECDH-ES reads enc from the shared header.
+		recipient["enc"] = A256GCM
+		agreed, encryptedKey, err := wrapKey(alg, key.Key, cek, recipient)
+		if err != nil {
+			return "", err
+		}
+		delete(recipient, "enc")
+		if alg.direct() {
+			cek = agreed
+		}
+		header := jsonHeader{}
+		for name, value := range recipient {
+			if header[name], err = json.Marshal(value); err != nil {
+				return "", err
+			}
+		}
+		recipients = append(recipients, jsonRecipient{header, encode(encryptedKey)})
+	}
+
+	iv, ciphertext, tag, err := sealContent(cek, plaintext, []byte(protected))
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(struct {
+		Protected  string          `json:"protected"`
+		Recipients []jsonRecipient `json:"recipients"`
+		IV         string          `json:"iv"`
+		Ciphertext string          `json:"ciphertext"`
+		Tag        string          `json:"tag"`
+	}{protected, recipients, encode(iv), encode(ciphertext), encode(tag)}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// wrapKey returns the content key for a recipient and its encrypted key.
+// RSA-OAEP-256 encrypts cek, or a new random key when cek is nil; dir uses
+// the secret and ECDH-ES a key agreed with an ephemeral key, which is added
+// to header as epk.
+func wrapKey(alg Algorithm, key any, cek []byte, header map[string]any) ([]byte, []byte, error) {
+	if !alg.Suits(key) {
+		return nil, nil, fmt.Errorf("a %s key cannot encrypt with %s", keyKind(key), alg)
+	}
+	switch alg {
+	case RSAOAEP256:
+		if cek == nil {
+			cek = make([]byte, contentKeySize)
+			if _, err := rand.Read(cek); err != nil {
+				return nil, nil, err
+			}
+		}
+		encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, jwk.Public(key).(*rsa.PublicKey), cek, nil)
+		return cek, encryptedKey, err
+	case Dir:
+		return key.([]byte), nil, nil
+	case ECDHES:
+		public, err := ecdhPublicKey(jwk.Public(key))
+		if err != nil {
+			return nil, nil, err
+		}
+		ephemeral, ephemeralPublic, err := generateEphemeral(public.Curve())
+		if err != nil {
+			return nil, nil, err
+		}
+		z, err := ephemeral.ECDH(public)
+		if err != nil {
+			return nil, nil, err
+		}
+		epk, err := jwk.Marshal(ephemeralPublic, jwk.Params{})
+		if err != nil {
+			return nil, nil, err
+		}
+		header["epk"] = json.RawMessage(epk)
+		enc, _ := header["enc"].(string)
+		return concatKDF(z, enc, nil, nil), nil, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported key management algorithm %q", alg)
+}
+
+// unwrapKey returns the content key of a recipient with alg and the
+// recipient's header, using a secret or private key.
+func unwrapKey(alg Algorithm, key any, encryptedKey []byte, header jsonHeader) ([]byte, error) {
+	switch k := key.(type) {
+	case []byte:
+		if alg == Dir && len(encryptedKey) == 0 {
+			return k, nil
+		}
+	case *rsa.PrivateKey:
+		if alg == RSAOAEP256 {
+			cek, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, k, encryptedKey, nil)
+			if err != nil || len(cek) != contentKeySize {
+				return nil, ErrDecrypt
+			}
+			return cek, nil
+		}
+	case *ecdsa.PrivateKey, *ecdh.PrivateKey:
+		if alg != ECDHES || len(encryptedKey) != 0 {
+			break
+		}
+		private, err := ecdhPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		epks, err := jwk.Parse(header["epk"])
+		if err != nil {
+			return nil, fmt.Errorf("epk: %w", err)
+		}
+		peer, err := ecdhPublicKey(epks[0].Key)
+		if err != nil {
+			return nil, fmt.Errorf("epk: %w", err)
+		}
+		if peer.Curve() != private.Curve() {
+			return nil, errors.New("the epk is not on the curve of the key")
+		}
+		z, err := private.ECDH(peer)
+		if err != nil {
+			return nil, err
+		}
+		apu, err := header.bytes("apu")
+		if err != nil {
+			return nil, err
+		}
+		apv, err := header.bytes("apv")
+		if err != nil {
+			return nil, err
+		}
+		return concatKDF(z, header.str("enc"), apu, apv), nil
+	}
+	return nil, fmt.Errorf("a %s key cannot decrypt %s", keyKind(key), alg)
+}
+
+// concatKDF derives an A256GCM key from the shared secret z as ECDH-ES in
+// direct key agreement mode does (RFC 7518 section 4.6.2): the algorithm id
+// is enc and the key length 256 bits, one round of SHA-256.
+func concatKDF(z []byte, enc string, apu, apv []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0, 0, 0, 1})
+	h.Write(z)
+	for _, info := range [][]byte{[]byte(enc), apu, apv} {
+		binary.Write(h, binary.BigEndian, uint32(len(info)))
+		h.Write(info)
+	}
+	binary.Write(h, binary.BigEndian, uint32(contentKeySize*8))
+	return h.Sum(nil)
+}
+
+// generateEphemeral returns a new key pair on curve, the public half in the
+// form jwk marshals: ECDSA for the NIST curves, ECDH for X25519.
+func generateEphemeral(curve ecdh.Curve) (*ecdh.PrivateKey, any, error) {
+	var ellipticCurve elliptic.Curve
+	switch curve {
+	case ecdh.X25519():
+		private, err := curve.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return private, private.PublicKey(), nil
+	case ecdh.P256():
+		ellipticCurve = elliptic.P256()
+	case ecdh.P384():
+		ellipticCurve = elliptic.P384()
+	case ecdh.P521():
+		ellipticCurve = elliptic.P521()
+	}
+	private, err := ecdsa.GenerateKey(ellipticCurve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	agreement, err := private.ECDH()
+	if err != nil {
+		return nil, nil, err
+	}
+	return agreement, &private.PublicKey, nil
+}
+
+func ecdhPublicKey(key any) (*ecdh.PublicKey, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return k.ECDH()
+	case *ecdh.PublicKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("a %s key cannot agree keys", keyKind(key))
+}
+
+func ecdhPrivateKey(key any) (*ecdh.PrivateKey, error) {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return k.ECDH()
+	case *ecdh.PrivateKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("a %s key cannot agree keys", keyKind(key))
+}
+
+// sealContent encrypts plaintext with A256GCM under a random IV.
+func sealContent(cek, plaintext, aad []byte) (iv, ciphertext, tag []byte, err error) {
+	aead, err := newGCM(cek)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	iv = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, nil, nil, err
+	}
+	sealed := aead.Seal(nil, iv, plaintext, aad)
+	split := len(sealed) - aead.Overhead()
+	return iv, sealed[:split], sealed[split:], nil
+}
+
+// openContent decrypts and authenticates A256GCM content.
+func openContent(cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	aead, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aead.NonceSize() || len(tag) != aead.Overhead() {
+		return nil, errors.New("the iv or tag has the wrong size for A256GCM")
+	}
+	plaintext, err := aead.Open(nil, iv, append(bytes.Clone(ciphertext), tag...), aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newGCM(cek []byte) (cipher.AEAD, error) {
+	if len(cek) != contentKeySize {
+		return nil, fmt.Errorf("A256GCM needs a %d-byte key, not %d bytes", contentKeySize, len(cek))
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
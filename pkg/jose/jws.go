@@ -0,0 +1,256 @@
+// Package jose signs and verifies JSON Web Signatures (RFC 7515) and JSON
+// Web Tokens (RFC 7519) with the HMAC, RSA, RSA-PSS, ECDSA and EdDSA
+// algorithms of RFC 7518 and RFC 8037, and encrypts and decrypts JSON Web
+// Encryption (RFC 7516) with RSA-OAEP-256, dir and ECDH-ES and A256GCM.
+// Both kinds are read and written in compact and JSON serialization. Keys
+// are those read by package jwk.
+package jose
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"errors"
+	"fmt"
+	"joshu/pkg/jwk"
+	"math/big"
+	"strings"
+)
+
+// Algorithm is a JWS signature algorithm or a JWE key management algorithm.
+type Algorithm string
+
+// Supported signature algorithms.
+const (
+	HS256 Algorithm = "HS256"
+	HS384 Algorithm = "HS384"
+	HS512 Algorithm = "HS512"
+	RS256 Algorithm = "RS256"
+	RS384 Algorithm = "RS384"
+	RS512 Algorithm = "RS512"
+	PS256 Algorithm = "PS256"
+	PS384 Algorithm = "PS384"
+	PS512 Algorithm = "PS512"
+	ES256 Algorithm = "ES256"
+	ES384 Algorithm = "ES384"
+	ES512 Algorithm = "ES512"
+	EdDSA Algorithm = "EdDSA"
+)
+
+// Algorithms lists the signature algorithms in display order.
+var Algorithms = []Algorithm{HS256, HS384, HS512, RS256, RS384, RS512, PS256, PS384, PS512, ES256, ES384, ES512, EdDSA}
+
+var (
+	// ErrSignature is returned when a signature does not verify.
+	ErrSignature = errors.New("signature does not match")
+	// ErrNoKey is returned when none of the given keys suits the algorithm.
+	ErrNoKey = errors.New("no key suits the algorithm")
+)
+
+// signs reports whether the algorithm is a signature algorithm.
+func (a Algorithm) signs() bool {
+	return a.hash() != 0 || a == EdDSA
+}
+
+// hash returns the digest of a signature algorithm, or 0 for EdDSA and
+// other algorithms.
+func (a Algorithm) hash() crypto.Hash {
+	switch a {
+	case HS256, RS256, PS256, ES256:
+		return crypto.SHA256
+	case HS384, RS384, PS384, ES384:
+		return crypto.SHA384
+	case HS512, RS512, PS512, ES512:
+		return crypto.SHA512
+	}
+	return 0
+}
+
+// curve returns the curve an ES algorithm is defined for.
+func (a Algorithm) curve() elliptic.Curve {
+	switch a {
+	case ES256:
+		return elliptic.P256()
+	case ES384:
+		return elliptic.P384()
+	case ES512:
+		return elliptic.P521()
+	}
+	return nil
+}
+
+// Suits reports whether key can be used with the algorithm: a secret for
+// HS*, an RSA key for RS*, PS* and RSA-OAEP-256, an ECDSA key on the
+// matching curve for ES*, an Ed25519 key for EdDSA, a 256-bit secret for
+// dir, and an ECDSA or X25519 key for ECDH-ES. Private and public keys
+// both suit.
+func (a Algorithm) Suits(key any) bool {
+	switch k := jwk.Public(key).(type) {
+	case []byte:
+		return strings.HasPrefix(string(a), "HS") || a == Dir && len(k) == contentKeySize
+	case *rsa.PublicKey:
+		return a.signs() && (strings.HasPrefix(string(a), "RS") || strings.HasPrefix(string(a), "PS")) ||
+			a == RSAOAEP256
+	case *ecdsa.PublicKey:
+		return a.curve() != nil && k.Curve == a.curve() || a == ECDHES
+	case ed25519.PublicKey:
+		return a == EdDSA
+	case *ecdh.PublicKey:
+		return a == ECDHES
+	}
+	return false
+}
+
+// AlgorithmFor returns the usual signature algorithm for key.
+func AlgorithmFor(key any) Algorithm {
+	alg := Algorithm(jwk.DefaultParams(key).Alg)
+	if !alg.signs() {
+		return ""
+	}
+	return alg
+}
+
+// Sign returns the signature of input with a secret or private key.
+func Sign(alg Algorithm, key any, input []byte) ([]byte, error) {
+	if !alg.signs() {
+		return nil, fmt.Errorf("%s is not a signature algorithm", alg)
+	}
+	if !alg.Suits(key) {
+		return nil, fmt.Errorf("a %s key cannot sign %s", keyKind(key), alg)
+	}
+	var digest []byte
+	if h := alg.hash(); h != 0 {
+		hasher := h.New()
+		hasher.Write(input)
+		digest = hasher.Sum(nil)
+	}
+
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(alg.hash().New, k)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(string(alg), "PS") {
+			return rsa.SignPSS(rand.Reader, k, alg.hash(), digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.SignPKCS1v15(rand.Reader, k, alg.hash(), digest)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses the fixed size R || S form rather than ASN.1.
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...), nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(k, input), nil
+	}
+	return nil, fmt.Errorf("%s needs a private key", alg)
+}
+
+// Verify checks the signature of input with a secret, or a public or
+// private key.
+func Verify(alg Algorithm, key any, input, signature []byte) error {
+	if !alg.signs() {
+		return fmt.Errorf("%s is not a signature algorithm", alg)
+	}
+	if !alg.Suits(key) {
+		return fmt.Errorf("a %s key cannot verify %s", keyKind(key), alg)
+	}
+	var digest []byte
+	if h := alg.hash(); h != 0 {
+		hasher := h.New()
+		hasher.Write(input)
+		digest = hasher.Sum(nil)
+	}
+
+	var ok bool
+	switch k := jwk.Public(key).(type) {
+	case []byte:
+		mac := hmac.New(alg.hash().New, k)
+		mac.Write(input)
+		ok = hmac.Equal(mac.Sum(nil), signature)
+	case *rsa.PublicKey:
+		if strings.HasPrefix(string(alg), "PS") {
+			ok = rsa.VerifyPSS(k, alg.hash(), digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}) == nil
+		} else {
+			ok = rsa.VerifyPKCS1v15(k, alg.hash(), digest, signature) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			ok = ecdsa.Verify(k, digest, r, s)
+		}
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, input, signature)
+	}
+	if !ok {
+		return ErrSignature
+	}
+	return nil
+}
+
+// ParseKeys reads signing or verification keys: a JWK or JWK Set, a key
+// or certificate in any format read by jwk.ParseKeyText, decrypted with
+// passphrase when it is encrypted, or otherwise an HMAC secret taken as the
+// raw bytes of text.
+func ParseKeys(text, passphrase string) ([]jwk.Key, error) {
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case trimmed == "":
+		return nil, errors.New("no key or secret given")
+	case strings.HasPrefix(trimmed, "{"):
+		return jwk.Parse([]byte(trimmed))
+	case strings.Contains(trimmed, "-----BEGIN"), strings.HasPrefix(trimmed, "ssh-"), strings.HasPrefix(trimmed, "ecdsa-"):
+		key, err := jwk.ParseKeyText(trimmed, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []jwk.Key{{Key: key}}, nil
+	}
+	// Secrets are used exactly as typed, surrounding spaces included.
+	return []jwk.Key{{Key: []byte(text)}}, nil
+}
+
+// selectKeys returns the keys suiting alg, those with kid first when kid
+// is set.
+func selectKeys(keys []jwk.Key, alg Algorithm, kid string) []jwk.Key {
+	var matching, others []jwk.Key
+	for _, key := range keys {
+		if !alg.Suits(key.Key) {
+			continue
+		}
+		if kid != "" && key.KeyID == kid {
+			matching = append(matching, key)
+		} else {
+			others = append(others, key)
+		}
+	}
+	return append(matching, others...)
+}
+
+func keyKind(key any) string {
+	switch jwk.Public(key).(type) {
+	case []byte:
+		return "secret"
+	case *rsa.PublicKey:
+		return "RSA"
+	case *ecdsa.PublicKey:
+		return "ECDSA " + jwk.Public(key).(*ecdsa.PublicKey).Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	case *ecdh.PublicKey:
+		return "X25519"
+	}
+	return fmt.Sprintf("%T", key)
+}
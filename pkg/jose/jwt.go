@@ -0,0 +1,190 @@
+package jose
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"joshu/pkg/jwk"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrMalformed is returned for text that is not a compact JWS.
+var ErrMalformed = errors.New("not a JWT: expected three base64url segments separated by dots")
+
+// Token is a decoded compact JWS, such as a signed JWT.
+type Token struct {
+	// Header and Payload are the decoded segments; the header is JSON, the
+	// payload JSON claims for a JWT.
+	Header  []byte
+	Payload []byte
+	// Signature is the decoded third segment.
+	Signature []byte
+	// Alg and KeyID are the alg and kid header parameters.
+	Alg   Algorithm
+	KeyID string
+
+	signingInput string
+}
+
+// DecodeToken splits and decodes a compact JWS without verifying it.
+// Whitespace, such as line breaks from copying, is ignored.
+func DecodeToken(token string) (*Token, error) {
+	token = strings.Join(strings.Fields(token), "")
+	token = strings.TrimPrefix(token, "Bearer")
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		if len(parts) == 5 {
+			return nil, errors.New("this is a JWE, it is encrypted rather than signed")
+		}
+		return nil, ErrMalformed
+	}
+	t := &Token{signingInput: parts[0] + "." + parts[1]}
+	var err error
+	if t.Header, err = decodeSegment("header", parts[0]); err != nil {
+		return nil, err
+	}
+	if t.Payload, err = decodeSegment("payload", parts[1]); err != nil {
+		return nil, err
+	}
+	if t.Signature, err = decodeSegment("signature", parts[2]); err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(t.Header, &header); err != nil {
+		return nil, fmt.Errorf("the header is not JSON: %v", err)
+	}
+	t.Alg, t.KeyID = Algorithm(header.Alg), header.Kid
+	return t, nil
+}
+
+// Verify checks the signature with the first of keys that verifies it,
+// trying keys with the token's kid first, and returns that key. Unsigned
+// tokens (alg "none") are rejected.
+func (t *Token) Verify(keys []jwk.Key) (jwk.Key, error) {
+	switch {
+	case t.Alg == "" || strings.EqualFold(string(t.Alg), "none"):
+		return jwk.Key{}, errors.New("the token is unsigned (alg none)")
+	case !t.Alg.signs():
+		return jwk.Key{}, fmt.Errorf("unsupported algorithm %q", t.Alg)
+	}
+	candidates := selectKeys(keys, t.Alg, t.KeyID)
+	if len(candidates) == 0 {
+		return jwk.Key{}, fmt.Errorf("%w %s", ErrNoKey, t.Alg)
+	}
+	for _, key := range candidates {
+		if Verify(t.Alg, key.Key, []byte(t.signingInput), t.Signature) == nil {
+			return key, nil
+		}
+	}
+	return jwk.Key{}, ErrSignature
+}
+
+// SignToken returns a compact JWS of payload signed with the first of keys
+// suiting alg. The header is a JSON object, which may be empty; its alg is
+// set, and its kid when the key has one and the header does not. Compact
+// JSON payloads are re-encoded without spaces.
+func SignToken(header, payload []byte, alg Algorithm, keys []jwk.Key) (string, error) {
+	candidates := selectKeys(keys, alg, "")
+	var key *jwk.Key
+	for i := range candidates {
+		if _, secret := candidates[i].Key.([]byte); secret || jwk.IsPrivate(candidates[i].Key) {
+			key = &candidates[i]
+			break
+		}
+	}
+	if key == nil {
+		return "", fmt.Errorf("%w %s, signing needs a secret or private key", ErrNoKey, alg)
+	}
+
+	fields, err := headerFields(header)
+	if err != nil {
+		return "", err
+	}
+	fields["alg"] = alg
+	if _, ok := fields["kid"]; !ok && key.KeyID != "" {
+		fields["kid"] = key.KeyID
+	}
+	headerJSON, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	var compact bytes.Buffer
+	if json.Valid(payload) {
+		json.Compact(&compact, payload)
+		payload = compact.Bytes()
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := Sign(alg, key.Key, []byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// TimeClaim is a NumericDate claim of a JWT.
+type TimeClaim struct {
+	Name string
+	Time time.Time
+}
+
+// timeClaims are the registered NumericDate claims, in display order.
+var timeClaims = []string{"iat", "nbf", "exp"}
+
+// TimeClaims returns the iat, nbf and exp claims of a JWT payload.
+func TimeClaims(payload []byte) []TimeClaim {
+	var claims map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if decoder.Decode(&claims) != nil {
+		return nil
+	}
+	var result []TimeClaim
+	for _, name := range timeClaims {
+		number, ok := claims[name].(json.Number)
+		if !ok {
+			continue
+		}
+		seconds, err := number.Float64()
+		if err != nil || math.IsInf(seconds, 0) || math.Abs(seconds) > 1e11 {
+			continue
+		}
+		whole, fraction := math.Modf(seconds)
+		result = append(result, TimeClaim{name, time.Unix(int64(whole), int64(fraction*1e9))})
+	}
+	return result
+}
+
+// CheckTimes returns problems with the validity period of a JWT payload at
+// now: an exp in the past, or an nbf or iat in the future.
+func CheckTimes(payload []byte, now time.Time) []string {
+	var problems []string
+	for _, claim := range TimeClaims(payload) {
+		switch {
+		case claim.Name == "exp" && !now.Before(claim.Time):
+			problems = append(problems, fmt.Sprintf("expired %s ago", now.Sub(claim.Time).Round(time.Second)))
+		case claim.Name == "nbf" && now.Before(claim.Time):
+			problems = append(problems, fmt.Sprintf("not valid for another %s", claim.Time.Sub(now).Round(time.Second)))
+		case claim.Name == "iat" && now.Before(claim.Time):
+			problems = append(problems, fmt.Sprintf("issued %s in the future", claim.Time.Sub(now).Round(time.Second)))
+		}
+	}
+	return problems
+}
+
+func decodeSegment(name, segment string) ([]byte, error) {
+	// Tolerate padding, which some encoders add.
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return nil, fmt.Errorf("the %s is not base64url: %v", name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,437 @@
+package jose
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"joshu/pkg/jwk"
+	"strings"
+)
+
+// Serialization is the form a JWS or JWE is written in.
+type Serialization string
+
+// Serializations of RFC 7515 and RFC 7516. Flattened JSON is read but not
+// written.
+const (
+	Compact       Serialization = "Compact"
+	GeneralJSON   Serialization = "General JSON"
+	FlattenedJSON Serialization = "Flattened JSON"
+)
+
+// Segment is one part of a JWS or JWE.
+type Segment struct {
+	// Name describes the part, such as "Protected header" or "Signature 2".
+	Name string
+	// Encoded is the base64url form, empty for unprotected headers, which
+	// are plain JSON.
+	Encoded string
+	// Decoded holds the bytes of the part.
+	Decoded []byte
+	// Header is set for the JSON header parts.
+	Header bool
+}
+
+// Object is a parsed JWS or JWE in any serialization.
+type Object struct {
+	// Encrypted is set for a JWE.
+	Encrypted     bool
+	Serialization Serialization
+	// Segments are the parts in the order they are written.
+	Segments []Segment
+
+	payload    []byte
+	signatures []signature
+
+	recipients          []recipient
+	aad                 string
+	iv, ciphertext, tag []byte
+}
+
+// signature is one signature of a JWS with its combined header.
+type signature struct {
+	header jsonHeader
+	input  string
+	value  []byte
+}
+
+// recipient is one recipient of a JWE with its combined header.
+type recipient struct {
+	header       jsonHeader
+	encryptedKey []byte
+}
+
+// jsonHeader is a JOSE header, its members left as JSON.
+type jsonHeader map[string]json.RawMessage
+
+// str returns a string member, or "" if it is missing or not a string.
+func (h jsonHeader) str(name string) string {
+	var s string
+	json.Unmarshal(h[name], &s)
+	return s
+}
+
+// bytes returns a base64url member, or nil if it is missing.
+func (h jsonHeader) bytes(name string) ([]byte, error) {
+	if _, ok := h[name]; !ok {
+		return nil, nil
+	}
+	return decodeSegment(name, h.str(name))
+}
+
+// merge returns the members of h and headers together.
+func (h jsonHeader) merge(headers ...jsonHeader) jsonHeader {
+	merged := jsonHeader{}
+	for _, header := range append([]jsonHeader{h}, headers...) {
+		for name, value := range header {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// ParseObject reads a JWS or JWE in compact, general JSON or flattened
+// JSON serialization without verifying or decrypting it.
+func ParseObject(text string) (*Object, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") {
+		return parseJSONObject([]byte(text))
+	}
+	text = strings.TrimPrefix(strings.Join(strings.Fields(text), ""), "Bearer")
+	parts := strings.Split(text, ".")
+	var names []string
+	switch len(parts) {
+	case 3:
+		names = []string{"Protected header", "Payload", "Signature"}
+	case 5:
+		names = []string{"Protected header", "Encrypted key", "Initialization vector", "Ciphertext", "Authentication tag"}
+	default:
+		return nil, errors.New("not a JWS or JWE: expected three or five base64url segments separated by dots, or JSON")
+	}
+	o := &Object{Encrypted: len(parts) == 5, Serialization: Compact}
+	decoded := make([][]byte, len(parts))
+	for i, part := range parts {
+		data, err := decodeSegment(strings.ToLower(names[i]), part)
+		if err != nil {
+			return nil, err
+		}
+		decoded[i] = data
+		o.Segments = append(o.Segments, Segment{Name: names[i], Encoded: part, Decoded: data, Header: i == 0})
+	}
+	header, err := parseHeader("protected header", decoded[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if !o.Encrypted {
+		o.payload = decoded[1]
+		o.signatures = []signature{{header, parts[0] + "." + parts[1], decoded[2]}}
+		return o, nil
+	}
+	o.recipients = []recipient{{header, decoded[1]}}
+	o.aad, o.iv, o.ciphertext, o.tag = parts[0], decoded[2], decoded[3], decoded[4]
+	return o, nil
+}
+
+// jsonSignature and jsonRecipient are the entries of the signatures and
+// recipients arrays of the general JSON serialization.
+type (
+	jsonSignature struct {
+		Protected string     `json:"protected,omitempty"`
+		Header    jsonHeader `json:"header,omitempty"`
+		Signature string     `json:"signature"`
+	}
+	jsonRecipient struct {
+		Header       jsonHeader `json:"header,omitempty"`
+		EncryptedKey string     `json:"encrypted_key,omitempty"`
+	}
+)
+
+func parseJSONObject(data []byte) (*Object, error) {
+	var raw struct {
+		Payload    *string         `json:"payload"`
+		Signatures []jsonSignature `json:"signatures"`
+		Signature  *string         `json:"signature"`
+
+		Unprotected  jsonHeader      `json:"unprotected"`
+		Recipients   []jsonRecipient `json:"recipients"`
+		EncryptedKey string          `json:"encrypted_key"`
+		AAD          string          `json:"aad"`
+		IV           string          `json:"iv"`
+		Ciphertext   *string         `json:"ciphertext"`
+		Tag          string          `json:"tag"`
+
+		// Shared by the flattened forms.
+		Protected string     `json:"protected"`
+		Header    jsonHeader `json:"header"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("not a JWS or JWE: %v", err)
+	}
+
+	o := &Object{Serialization: GeneralJSON}
+	switch {
+	case raw.Payload != nil:
+		if raw.Signatures == nil {
+			if raw.Signature == nil {
+				return nil, errors.New("the JWS has no signatures")
+			}
+			o.Serialization = FlattenedJSON
+			raw.Signatures = []jsonSignature{{raw.Protected, raw.Header, *raw.Signature}}
+		}
+		payload, err := o.addSegment("Payload", *raw.Payload, false)
+		if err != nil {
+			return nil, err
+		}
+		o.payload = payload
+		for i, s := range raw.Signatures {
+			name := "Signature"
+			if o.Serialization == GeneralJSON {
+				name = fmt.Sprintf("Signature %d", i+1)
+			}
+			header, err := o.addHeaders(name, s.Protected, s.Header)
+			if err != nil {
+				return nil, err
+			}
+			value, err := o.addSegment(name, s.Signature, false)
+			if err != nil {
+				return nil, err
+			}
+			o.signatures = append(o.signatures, signature{header, s.Protected + "." + *raw.Payload, value})
+		}
+		return o, nil
+
+	case raw.Ciphertext != nil:
+		o.Encrypted = true
+		if raw.Recipients == nil {
+			o.Serialization = FlattenedJSON
+			raw.Recipients = []jsonRecipient{{raw.Header, raw.EncryptedKey}}
+		}
+		shared, err := o.addHeaders("Shared", raw.Protected, raw.Unprotected)
+		if err != nil {
+			return nil, err
+		}
+		for i, r := range raw.Recipients {
+			name := "Recipient"
+			if o.Serialization == GeneralJSON {
+				name = fmt.Sprintf("Recipient %d", i+1)
+			}
+			if r.Header != nil {
+				o.Segments = append(o.Segments, Segment{Name: name + " header", Decoded: marshalHeader(r.Header), Header: true})
+			}
+			encryptedKey, err := o.addSegment(name+" encrypted key", r.EncryptedKey, false)
+			if err != nil {
+				return nil, err
+			}
+			o.recipients = append(o.recipients, recipient{shared.merge(r.Header), encryptedKey})
+		}
+		o.aad = raw.Protected
+		if raw.AAD != "" {
+			if _, err := o.addSegment("Additional authenticated data", raw.AAD, false); err != nil {
+				return nil, err
+			}
+			o.aad += "." + raw.AAD
+		}
+		for _, part := range []struct {
+			name    string
+			encoded string
+			decoded *[]byte
+		}{
+			{"Initialization vector", raw.IV, &o.iv},
+			{"Ciphertext", *raw.Ciphertext, &o.ciphertext},
+			{"Authentication tag", raw.Tag, &o.tag},
+		} {
+			if *part.decoded, err = o.addSegment(part.name, part.encoded, false); err != nil {
+				return nil, err
+			}
+		}
+		return o, nil
+	}
+	return nil, errors.New("not a JWS or JWE: JSON without a payload or ciphertext")
+}
+
+// addHeaders adds the protected and unprotected headers prefixed by name
+// and returns them combined.
+func (o *Object) addHeaders(name, protected string, unprotected jsonHeader) (jsonHeader, error) {
+	header := jsonHeader{}
+	if protected != "" {
+		data, err := o.addSegment(name+" protected header", protected, true)
+		if err != nil {
+			return nil, err
+		}
+		if header, err = parseHeader(strings.ToLower(name)+" protected header", data); err != nil {
+			return nil, err
+		}
+	}
+	if unprotected != nil {
+		o.Segments = append(o.Segments, Segment{Name: name + " unprotected header", Decoded: marshalHeader(unprotected), Header: true})
+	}
+	return header.merge(unprotected), nil
+}
+
+func (o *Object) addSegment(name, encoded string, header bool) ([]byte, error) {
+	data, err := decodeSegment(strings.ToLower(name), encoded)
+	if err != nil {
+		return nil, err
+	}
+	o.Segments = append(o.Segments, Segment{Name: name, Encoded: encoded, Decoded: data, Header: header})
+	return data, nil
+}
+
+// Algorithms returns the alg of each signature or recipient.
+func (o *Object) Algorithms() []Algorithm {
+	var algs []Algorithm
+	for _, s := range o.signatures {
+		algs = append(algs, Algorithm(s.header.str("alg")))
+	}
+	for _, r := range o.recipients {
+		algs = append(algs, Algorithm(r.header.str("alg")))
+	}
+	return algs
+}
+
+// Verify checks each signature of a JWS with keys, trying keys with the
+// signature's kid first. It returns the payload and, for each signature,
+// the key verifying it or nil. At least one signature must verify.
+func (o *Object) Verify(keys []jwk.Key) ([]byte, []*jwk.Key, error) {
+	if o.Encrypted {
+		return nil, nil, errors.New("this is a JWE, it is encrypted rather than signed")
+	}
+	verified := make([]*jwk.Key, len(o.signatures))
+	ok := false
+	var lastErr error = ErrSignature
+	for i, s := range o.signatures {
+		alg := Algorithm(s.header.str("alg"))
+		if !alg.signs() {
+			lastErr = fmt.Errorf("unsupported signature algorithm %q", alg)
+			continue
+		}
+		candidates := selectKeys(keys, alg, s.header.str("kid"))
+		if len(candidates) == 0 {
+			lastErr = fmt.Errorf("%w %s", ErrNoKey, alg)
+			continue
+		}
+		for _, key := range candidates {
+			if Verify(alg, key.Key, []byte(s.input), s.value) == nil {
+				verified[i], ok = &key, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, nil, lastErr
+	}
+	return o.payload, verified, nil
+}
+
+// Decrypt decrypts a JWE with the first of keys that opens one of its
+// recipients, trying keys with the recipient's kid first, and returns the
+// plaintext and that key.
+func (o *Object) Decrypt(keys []jwk.Key) ([]byte, jwk.Key, error) {
+	if !o.Encrypted {
+		return nil, jwk.Key{}, errors.New("this is a JWS, it is signed rather than encrypted")
+	}
+	var lastErr error = ErrDecrypt
+	for _, r := range o.recipients {
+		alg := Algorithm(r.header.str("alg"))
+		if enc := r.header.str("enc"); enc != A256GCM {
+			return nil, jwk.Key{}, fmt.Errorf("unsupported content encryption %q, only %s is", enc, A256GCM)
+		}
+		candidates := selectKeys(keys, alg, r.header.str("kid"))
+		if len(candidates) == 0 {
+			lastErr = fmt.Errorf("%w %s", ErrNoKey, alg)
+			continue
+		}
+		for _, key := range candidates {
+			cek, err := unwrapKey(alg, key.Key, r.encryptedKey, r.header)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			plaintext, err := openContent(cek, o.iv, o.ciphertext, o.tag, []byte(o.aad))
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			return plaintext, key, nil
+		}
+	}
+	return nil, jwk.Key{}, lastErr
+}
+
+// SignJSON returns a JWS of payload in general JSON serialization with a
+// signature by every secret or private key suiting alg. Each signature has
+// a protected header made of header with its alg and kid.
+func SignJSON(header, payload []byte, alg Algorithm, keys []jwk.Key) (string, error) {
+	encodedPayload := encode(payload)
+	var signatures []jsonSignature
+	for _, key := range selectKeys(keys, alg, "") {
+		if _, secret := key.Key.([]byte); !secret && !jwk.IsPrivate(key.Key) {
+			continue
+		}
+		fields, err := headerFields(header)
+		if err != nil {
+			return "", err
+		}
+		fields["alg"] = alg
+		if key.KeyID != "" {
+			fields["kid"] = key.KeyID
+		}
+		protected, err := encodeHeader(fields)
+		if err != nil {
+			return "", err
+		}
+		value, err := Sign(alg, key.Key, []byte(protected+"."+encodedPayload))
+		if err != nil {
+			return "", err
+		}
+		signatures = append(signatures, jsonSignature{Protected: protected, Signature: encode(value)})
+	}
+	if len(signatures) == 0 {
+		return "", fmt.Errorf("%w %s, signing needs a secret or private key", ErrNoKey, alg)
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Payload    string          `json:"payload"`
+		Signatures []jsonSignature `json:"signatures"`
+	}{encodedPayload, signatures}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// headerFields reads a JSON object header to add members to; a blank header
+// is empty.
+func headerFields(header []byte) (map[string]any, error) {
+	fields := map[string]any{}
+	if len(bytes.TrimSpace(header)) > 0 {
+		if err := json.Unmarshal(header, &fields); err != nil {
+			return nil, fmt.Errorf("the header is not a JSON object: %v", err)
+		}
+	}
+	return fields, nil
+}
+
+// encodeHeader returns the base64url encoded JSON of a header.
+func encodeHeader(fields map[string]any) (string, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return encode(data), nil
+}
+
+func parseHeader(name string, data []byte) (jsonHeader, error) {
+	var header jsonHeader
+	if err := json.Unmarshal(data, &header); err != nil || header == nil {
+		return nil, fmt.Errorf("the %s is not a JSON object", name)
+	}
+	return header, nil
+}
+
+func marshalHeader(header jsonHeader) []byte {
+	data, _ := json.Marshal(header)
+	return data
+}
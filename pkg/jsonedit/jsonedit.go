@@ -0,0 +1,45 @@
+// Package jsonedit formats and repairs JSON documents.
+package jsonedit
+
+import (
+	"encoding/json"
+	jsonrepair "github.com/RealAlexandreAI/json-repair"
+)
+
+// Beautify returns input re-encoded with two space indentation.
+func Beautify(input string) (string, error) {
+	var jsonObj interface{}
+	err := json.Unmarshal([]byte(input), &jsonObj)
+	if err != nil {
+		return "", err
+	}
+
+	prettyJSONBytes, err := json.MarshalIndent(jsonObj, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(prettyJSONBytes), nil
+}
+
+// OneLine returns input re-encoded on a single line without insignificant whitespace.
+func OneLine(input string) (string, error) {
+	var jsonObj interface{}
+	err := json.Unmarshal([]byte(input), &jsonObj)
+	if err != nil {
+		return "", err
+	}
+
+	oneLineJSONBytes, err := json.Marshal(jsonObj)
+	if err != nil {
+		return "", err
+	}
+
+	return string(oneLineJSONBytes), nil
+}
+
+// Repair fixes common mistakes in hand written or truncated JSON, such as
+// unquoted keys, trailing commas and missing closing brackets.
+func Repair(input string) (string, error) {
+	return jsonrepair.RepairJSON(input)
+}
@@ -0,0 +1,79 @@
+package jsonedit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBeautifyOneLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		pretty   string
+		oneLine  string
+		notValid bool
+	}{
+		{"object", `{"b": [1, 2], "a": {"c": null}}`, "{\n  \"a\": {\n    \"c\": null\n  },\n  \"b\": [\n    1,\n    2\n  ]\n}", `{"a":{"c":null},"b":[1,2]}`, false},
+		{"array", " [true,\n\"x\"] ", "[\n  true,\n  \"x\"\n]", `[true,"x"]`, false},
+		{"scalar", "42", "42", "42", false},
+		{"empty object", "{ }", "{}", "{}", false},
+		{"trailing comma", `{"a": 1,}`, "", "", true},
+		{"empty", "", "", "", true},
+		{"two values", `{} {}`, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pretty, err := Beautify(tt.input)
+			if (err != nil) != tt.notValid {
+				t.Fatalf("Beautify() error = %v, want error %v", err, tt.notValid)
+			}
+			if pretty != tt.pretty {
+				t.Errorf("Beautify() = %q, want %q", pretty, tt.pretty)
+			}
+			oneLine, err := OneLine(tt.input)
+			if (err != nil) != tt.notValid {
+				t.Fatalf("OneLine() error = %v, want error %v", err, tt.notValid)
+			}
+			if oneLine != tt.oneLine {
+				t.Errorf("OneLine() = %q, want %q", oneLine, tt.oneLine)
+			}
+			if tt.notValid {
+				return
+			}
+			if again, err := OneLine(pretty); err != nil || again != oneLine {
+				t.Errorf("OneLine(Beautify()) = %q, %v, want %q", again, err, oneLine)
+			}
+		})
+	}
+}
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"valid", `{"a": [1, 2]}`, `{"a":[1,2]}`},
+		{"unquoted key", `{a: 1}`, `{"a":1}`},
+		{"single quotes", `{'a': 'x'}`, `{"a":"x"}`},
+		{"trailing comma", `{"a": true,}`, `{"a":true}`},
+		{"truncated", `{"a": [1, 2`, `{"a":[1,2]}`},
+		{"truncated array", `[1, 2, 3`, `[1,2,3]`},
+		{"comment", `{"a": 1} // note`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repaired, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair: %v", err)
+			}
+			got, err := OneLine(repaired)
+			if err != nil {
+				t.Fatalf("Repair() = %q, not valid JSON: %v", repaired, err)
+			}
+			if got != tt.want {
+				t.Errorf("Repair() = %q, want %q", strings.TrimSpace(repaired), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+// Package jwk converts keys to and from JSON Web Keys (RFC 7517): RSA, EC
+// (P-256, P-384, P-521), OKP (Ed25519, X25519, RFC 8037) and symmetric oct
+// keys. It computes RFC 7638 thumbprints for key ids and reads and writes
+// JWK Sets.
+package jwk
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/rsakeys"
+	"math/big"
+	"strings"
+)
+
+// Key is a parsed JSON Web Key.
+type Key struct {
+	// Key is *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey or
+	// *ecdh.PrivateKey, the matching public key type, or []byte for a
+	// symmetric key.
+	Key any
+	Params
+}
+
+// Params are the optional JWK members describing a key.
+type Params struct {
+	KeyID string
+	// Use is "sig" or "enc".
+	Use string
+	// Alg is the JOSE algorithm the key is meant for, such as "RS256".
+	Alg string
+}
+
+// Key uses.
+const (
+	UseSignature  = "sig"
+	UseEncryption = "enc"
+)
+
+// ErrInvalid is returned for JSON that is not a supported JWK or JWK Set.
+// It is rsakeys.ErrInvalidJWK, which RSA keys are read with.
+var ErrInvalid = rsakeys.ErrInvalidJWK
+
+// jsonKey is the JSON form of any supported JWK, members ordered the way
+// they are usually written.
+type jsonKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	// JWKMembers holds n, e and the private members of RSA keys, and d of
+	// EC and OKP keys.
+	rsakeys.JWKMembers
+	K string `json:"k,omitempty"`
+}
+
+type jsonSet struct {
+	Keys []json.RawMessage `json:"keys"`
+}
+
+// ParseKeyText reads a private or public key in any format understood by
+// rsakeys or eckeys, a JWK, or the public key of an X.509 certificate.
+// Encrypted private keys are decrypted with passphrase, which is ignored
+// otherwise. Private keys are returned as is; use Public for the public half.
+func ParseKeyText(text, passphrase string) (any, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") {
+		keys, err := Parse([]byte(text))
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) != 1 {
+			return nil, fmt.Errorf("expected one key, the set has %d", len(keys))
+		}
+		return keys[0].Key, nil
+	}
+	if block, _ := pem.Decode([]byte(text)); block != nil && block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	text, err := rsakeys.UnlockPrivateKey(text, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := rsakeys.ParsePrivateKey(text); err == nil {
+		return key, nil
+	}
+	if key, _, err := eckeys.ParsePrivateKey(text); err == nil {
+		return key, nil
+	}
+	if key, err := rsakeys.ParsePublicKey(text); err == nil {
+		return key, nil
+	}
+	if key, _, err := eckeys.ParsePublicKey(text); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("failed to decode key, expected an RSA or elliptic-curve key in PEM, OpenSSH or JWK form, or a certificate")
+}
+
+// Public returns the public half of a private key, or key itself. Symmetric
+// keys have no public half and are returned as is.
+func Public(key any) any {
+	if private, ok := key.(interface{ Public() crypto.PublicKey }); ok {
+		return private.Public()
+	}
+	return key
+}
+
+// IsPrivate reports whether key is a private key.
+func IsPrivate(key any) bool {
+	_, ok := key.(interface{ Public() crypto.PublicKey })
+	return ok
+}
+
+// DefaultParams returns the usual use and alg for key: RS256, ES256,
+// ES384, ES512, EdDSA or HS256 for signing, ECDH-ES for X25519.
+func DefaultParams(key any) Params {
+	switch k := Public(key).(type) {
+	case *rsa.PublicKey:
+		return Params{Use: UseSignature, Alg: "RS256"}
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P384():
+			return Params{Use: UseSignature, Alg: "ES384"}
+		case elliptic.P521():
+			return Params{Use: UseSignature, Alg: "ES512"}
+		default:
+			return Params{Use: UseSignature, Alg: "ES256"}
+		}
+	case ed25519.PublicKey:
+		return Params{Use: UseSignature, Alg: "EdDSA"}
+	case *ecdh.PublicKey:
+		return Params{Use: UseEncryption, Alg: "ECDH-ES"}
+	case []byte:
+		return Params{Use: UseSignature, Alg: "HS256"}
+	}
+	return Params{}
+}
+
+// Marshal returns key as an indented JWK with params.
+func Marshal(key any, params Params) (string, error) {
+	k, err := toJSON(key, params)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// MarshalSet returns the keys as an indented JWK Set.
+func MarshalSet(keys []Key) (string, error) {
+	set := struct {
+		Keys []jsonKey `json:"keys"`
+	}{Keys: []jsonKey{}}
+	for _, key := range keys {
+		k, err := toJSON(key.Key, key.Params)
+		if err != nil {
+			return "", err
+		}
+		set.Keys = append(set.Keys, k)
+	}
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// Parse reads a JWK or a JWK Set.
+func Parse(data []byte) ([]Key, error) {
+	data = bytes.TrimSpace(data)
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if _, ok := probe["keys"]; !ok {
+		key, err := parseKey(data)
+		if err != nil {
+			return nil, err
+		}
+		return []Key{key}, nil
+	}
+
+	var set jsonSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("%w: the set has no keys", ErrInvalid)
+	}
+	var keys []Key
+	for i, raw := range set.Keys {
+		key, err := parseKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i+1, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Thumbprint returns the RFC 7638 SHA-256 thumbprint of the public half of
+// key, base64url encoded, as commonly used for kid.
+func Thumbprint(key any) (string, error) {
+	k, err := toJSON(Public(key), Params{})
+	if err != nil {
+		return "", err
+	}
+	// The required members only, in lexicographic order, without spaces.
+	var canonical string
+	switch k.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Crv, k.X, k.Y)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, k.Crv, k.X)
+	case "oct":
+		canonical = fmt.Sprintf(`{"k":%q,"kty":"oct"}`, k.K)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// EncodePEM returns key as PKCS#8 PEM if it is private, PKIX PEM otherwise.
+func EncodePEM(key any) (string, error) {
+	if IsPrivate(key) {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: rsakeys.PKCS8PrivateKeyType, Bytes: der})), nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: rsakeys.PKIXPublicKeyType, Bytes: der})), nil
+}
+
+func toJSON(key any, params Params) (jsonKey, error) {
+	k := jsonKey{Kid: params.KeyID, Use: params.Use, Alg: params.Alg}
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		k.Kty = "RSA"
+		k.JWKMembers = rsakeys.PublicJWKMembers(key)
+	case *rsa.PrivateKey:
+		k.Kty = "RSA"
+		k.JWKMembers = rsakeys.PrivateJWKMembers(key)
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		k.Kty = "EC"
+		k.Crv = key.Curve.Params().Name
+		k.X = encodeInt(key.X, size)
+		k.Y = encodeInt(key.Y, size)
+	case *ecdsa.PrivateKey:
+		k, _ = toJSON(&key.PublicKey, params)
+		k.D = encodeInt(key.D, (key.Curve.Params().BitSize+7)/8)
+	case ed25519.PublicKey:
+		k.Kty = "OKP"
+		k.Crv = string(eckeys.Ed25519)
+		k.X = base64.RawURLEncoding.EncodeToString(key)
+	case ed25519.PrivateKey:
+		k, _ = toJSON(key.Public(), params)
+		k.D = base64.RawURLEncoding.EncodeToString(key.Seed())
+	case *ecdh.PublicKey:
+		if key.Curve() != ecdh.X25519() {
+			return k, fmt.Errorf("unsupported ECDH curve %v", key.Curve())
+		}
+		k.Kty = "OKP"
+		k.Crv = string(eckeys.X25519)
+		k.X = base64.RawURLEncoding.EncodeToString(key.Bytes())
+	case *ecdh.PrivateKey:
+		var err error
+		if k, err = toJSON(key.PublicKey(), params); err != nil {
+			return k, err
+		}
+		k.D = base64.RawURLEncoding.EncodeToString(key.Bytes())
+	case []byte:
+		k.Kty = "oct"
+		k.K = base64.RawURLEncoding.EncodeToString(key)
+	default:
+		return k, fmt.Errorf("unsupported key type %T", key)
+	}
+	return k, nil
+}
+
+func parseKey(data []byte) (Key, error) {
+	var k jsonKey
+	if err := json.Unmarshal(data, &k); err != nil {
+		return Key{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	key := Key{Params: Params{KeyID: k.Kid, Use: k.Use, Alg: k.Alg}}
+	var err error
+	switch k.Kty {
+	case "RSA":
+		key.Key, err = parseRSA(k)
+	case "EC":
+		key.Key, err = parseEC(k)
+	case "OKP":
+		key.Key, err = parseOKP(k)
+	case "oct":
+		key.Key, err = base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || k.K == "" {
+			err = fmt.Errorf("%w: \"k\" is not base64url", ErrInvalid)
+		}
+	default:
+		err = fmt.Errorf("%w: unsupported key type %q", ErrInvalid, k.Kty)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+	return key, nil
+}
+
+func parseRSA(k jsonKey) (any, error) {
+	if k.D == "" {
+		return k.JWKMembers.PublicKey()
+	}
+	return k.JWKMembers.PrivateKey()
+}
+
+func parseEC(k jsonKey) (any, error) {
+	var curve elliptic.Curve
+	var ecdhCurve ecdh.Curve
+	switch eckeys.Curve(k.Crv) {
+	case eckeys.P256:
+		curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+	case eckeys.P384:
+		curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+	case eckeys.P521:
+		curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, fmt.Errorf("%w: unsupported EC curve %q", ErrInvalid, k.Crv)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	x, err := decodeFixed("x", k.X, size)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeFixed("y", k.Y, size)
+	if err != nil {
+		return nil, err
+	}
+	// crypto/ecdh checks that the point is on the curve.
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("%w: the point is not on %s", ErrInvalid, k.Crv)
+	}
+	publicKey := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if k.D == "" {
+		return publicKey, nil
+	}
+
+	d, err := decodeFixed("d", k.D, size)
+	if err != nil {
+		return nil, err
+	}
+	private, err := ecdhCurve.NewPrivateKey(d)
+	if err != nil || !bytes.Equal(private.PublicKey().Bytes(), point) {
+		return nil, fmt.Errorf("%w: the private key does not match x and y", ErrInvalid)
+	}
+	return &ecdsa.PrivateKey{PublicKey: *publicKey, D: new(big.Int).SetBytes(d)}, nil
+}
+
+func parseOKP(k jsonKey) (any, error) {
+	switch eckeys.Curve(k.Crv) {
+	case eckeys.Ed25519:
+		x, err := decodeFixed("x", k.X, ed25519.PublicKeySize)
+		if err != nil {
+			return nil, err
+		}
+		if k.D == "" {
+			return ed25519.PublicKey(x), nil
+		}
+		d, err := decodeFixed("d", k.D, ed25519.SeedSize)
+		if err != nil {
+			return nil, err
+		}
+		key := ed25519.NewKeyFromSeed(d)
+		if !bytes.Equal(key.Public().(ed25519.PublicKey), x) {
+			return nil, fmt.Errorf("%w: the private key does not match x", ErrInvalid)
+		}
+		return key, nil
+	case eckeys.X25519:
+		x, err := decodeFixed("x", k.X, 32)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := ecdh.X25519().NewPublicKey(x)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		if k.D == "" {
+			return publicKey, nil
+		}
+		d, err := decodeFixed("d", k.D, 32)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ecdh.X25519().NewPrivateKey(d)
+		if err != nil || !key.PublicKey().Equal(publicKey) {
+			return nil, fmt.Errorf("%w: the private key does not match x", ErrInvalid)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported OKP curve %q", ErrInvalid, k.Crv)
+	}
+}
+
+// encodeInt returns n as unpadded base64url, left padded with zeros to size
+// bytes.
+func encodeInt(n *big.Int, size int) string {
+	return base64.RawURLEncoding.EncodeToString(n.FillBytes(make([]byte, size)))
+}
+
+func decodeFixed(name, value string, size int) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not base64url", ErrInvalid, name)
+	}
+	if len(data) != size {
+		return nil, fmt.Errorf("%w: %q must be %d bytes, got %d", ErrInvalid, name, size, len(data))
+	}
+	return data, nil
+}
@@ -0,0 +1,232 @@
+package jwk
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/rsakeys"
+	"strings"
+	"testing"
+)
+
+func equalKeys(a, b any) bool {
+	if secret, ok := a.([]byte); ok {
+		other, ok := b.([]byte)
+		return ok && bytes.Equal(secret, other)
+	}
+	switch k := a.(type) {
+	case interface{ Equal(crypto.PrivateKey) bool }:
+		return k.Equal(b)
+	case interface{ Equal(crypto.PublicKey) bool }:
+		return k.Equal(b)
+	}
+	return false
+}
+
+func TestMarshalParseRoundTrip(t *testing.T) {
+	rsaText, _, err := rsakeys.GenerateFormat(2048, rsakeys.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]string{"RSA": rsaText}
+	for _, curve := range eckeys.Curves {
+		text, _, err := eckeys.Generate(curve, rsakeys.PKCS8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[string(curve)] = text
+	}
+
+	tests := []struct {
+		name string
+		kty  string
+		alg  string
+	}{
+		{"RSA", "RSA", "RS256"},
+		{string(eckeys.P256), "EC", "ES256"},
+		{string(eckeys.P384), "EC", "ES384"},
+		{string(eckeys.P521), "EC", "ES512"},
+		{string(eckeys.Ed25519), "OKP", "EdDSA"},
+		{string(eckeys.X25519), "OKP", "ECDH-ES"},
+		{"oct", "oct", "HS256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var private any = []byte("a shared secret of 32 bytes!!!!!")
+			if text, ok := keys[tt.name]; ok {
+				if private, err = ParseKeyText(text, ""); err != nil {
+					t.Fatalf("ParseKeyText: %v", err)
+				}
+			}
+			params := DefaultParams(private)
+			if params.Alg != tt.alg {
+				t.Errorf("DefaultParams() alg = %q, want %q", params.Alg, tt.alg)
+			}
+			params.KeyID = "key-1"
+
+			for _, key := range []any{private, Public(private)} {
+				text, err := Marshal(key, params)
+				if err != nil {
+					t.Fatalf("Marshal: %v", err)
+				}
+				if !strings.Contains(text, `"kty": "`+tt.kty+`"`) {
+					t.Errorf("Marshal() = %s, want kty %s", text, tt.kty)
+				}
+				parsed, err := Parse([]byte(text))
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				if len(parsed) != 1 || parsed[0].Params != params {
+					t.Fatalf("Parse() = %+v, want one key with %+v", parsed, params)
+				}
+				if !equalKeys(key, parsed[0].Key) {
+					t.Errorf("Parse() returned a different key")
+				}
+				if IsPrivate(parsed[0].Key) != IsPrivate(key) {
+					t.Errorf("IsPrivate() = %v after the round trip", IsPrivate(parsed[0].Key))
+				}
+				if again, err := ParseKeyText(text, ""); err != nil || !equalKeys(key, again) {
+					t.Errorf("ParseKeyText of the JWK: %v", err)
+				}
+			}
+
+			privateThumbprint, err := Thumbprint(private)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if publicThumbprint, _ := Thumbprint(Public(private)); publicThumbprint != privateThumbprint {
+				t.Error("the private and public thumbprints differ")
+			}
+		})
+	}
+}
+
+func TestEncodePEMRoundTrip(t *testing.T) {
+	text, _, err := eckeys.Generate(eckeys.Ed25519, rsakeys.OpenSSH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := ParseKeyText(text, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []any{private, Public(private)} {
+		encoded, err := EncodePEM(key)
+		if err != nil {
+			t.Fatalf("EncodePEM: %v", err)
+		}
+		if again, err := ParseKeyText(encoded, ""); err != nil || !equalKeys(key, again) {
+			t.Errorf("ParseKeyText of %q: %v", encoded, err)
+		}
+	}
+}
+
+func TestParseKeyTextEncrypted(t *testing.T) {
+	text, _, err := rsakeys.GenerateWithPassphrase(2048, rsakeys.PKCS8, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseKeyText(text, "wrong"); !errors.Is(err, rsakeys.ErrIncorrectPassphrase) {
+		t.Errorf("ParseKeyText() error = %v, want ErrIncorrectPassphrase", err)
+	}
+	key, err := ParseKeyText(text, "secret")
+	if err != nil {
+		t.Fatalf("ParseKeyText: %v", err)
+	}
+	if !IsPrivate(key) {
+		t.Errorf("ParseKeyText() = %T, want a private key", key)
+	}
+}
+
+// TestRFC8037 checks the Ed25519 example key and thumbprint from RFC 8037
+// appendix A.
+func TestRFC8037(t *testing.T) {
+	const text = `{"kty":"OKP","crv":"Ed25519",
+		"d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+		"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+	keys, err := Parse([]byte(text))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, ok := keys[0].Key.(ed25519.PrivateKey); !ok {
+		t.Fatalf("Parse() = %T, want ed25519.PrivateKey", keys[0].Key)
+	}
+	thumbprint, err := Thumbprint(keys[0].Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"; thumbprint != want {
+		t.Errorf("Thumbprint() = %q, want %q", thumbprint, want)
+	}
+}
+
+func TestSetRoundTrip(t *testing.T) {
+	var keys []Key
+	for _, curve := range []eckeys.Curve{eckeys.P256, eckeys.Ed25519} {
+		text, _, err := eckeys.Generate(curve, rsakeys.PKCS8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := ParseKeyText(text, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, Key{Key: Public(key), Params: Params{KeyID: string(curve)}})
+	}
+	text, err := MarshalSet(keys)
+	if err != nil {
+		t.Fatalf("MarshalSet: %v", err)
+	}
+	parsed, err := Parse([]byte(text))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(parsed) != len(keys) {
+		t.Fatalf("Parse() found %d keys, want %d", len(parsed), len(keys))
+	}
+	for i := range keys {
+		if parsed[i].KeyID != keys[i].KeyID || !equalKeys(keys[i].Key, parsed[i].Key) {
+			t.Errorf("key %d changed in the round trip", i)
+		}
+	}
+	if _, err := ParseKeyText(text, ""); err == nil {
+		t.Error("ParseKeyText accepted a set of two keys")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	const x = "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{"not JSON", `{"kty":`, ""},
+		{"empty set", `{"keys":[]}`, "the set has no keys"},
+		{"bad key in set", `{"keys":[{"kty":"oct","k":"AAAA"},{"kty":"XYZ"}]}`, "key 2"},
+		{"unknown kty", `{"kty":"XYZ"}`, "unsupported key type"},
+		{"empty oct", `{"kty":"oct","k":""}`, `"k" is not base64url`},
+		{"RSA without n", `{"kty":"RSA","e":"AQAB"}`, `"n" is not base64url`},
+		{"huge exponent", `{"kty":"RSA","n":"AQAB","e":"AQAAAAAAAAAA"}`, "exponent too large"},
+		{"RSA without primes", `{"kty":"RSA","n":"AQAB","e":"AQAB","d":"AQAB"}`, "no primes"},
+		{"unknown curve", `{"kty":"EC","crv":"P-192","x":"AA","y":"AA"}`, "unsupported EC curve"},
+		{"short coordinate", `{"kty":"EC","crv":"P-256","x":"AAAA","y":"AAAA"}`, `"x" must be 32 bytes`},
+		{"point off the curve", `{"kty":"EC","crv":"P-256","x":"` + x + `","y":"` + x + `"}`, "not on P-256"},
+		{"unknown OKP curve", `{"kty":"OKP","crv":"Ed448","x":"` + x + `"}`, "unsupported OKP curve"},
+		{"mismatched Ed25519 d", `{"kty":"OKP","crv":"Ed25519","x":"` + x + `","d":"` + x + `"}`, "does not match x"},
+		{"padded base64", `{"kty":"OKP","crv":"Ed25519","x":"` + x + `="}`, `"x" is not base64url`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.json))
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("Parse() error = %v, want ErrInvalid", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,452 @@
+// Package keyinspect identifies keys, certificate signing requests and
+// certificates given as PEM, DER, OpenSSH or JWK, and describes them:
+// algorithm, size, fingerprints and, for certificates, the subject, issuer
+// and validity. It also checks whether a private and a public key belong
+// together.
+package keyinspect
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/jwk"
+	"joshu/pkg/rsakeys"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Kinds of objects Inspect recognizes.
+const (
+	PrivateKey         = "Private key"
+	PublicKey          = "Public key"
+	Certificate        = "Certificate"
+	CertificateRequest = "Certificate request"
+	SymmetricKey       = "Symmetric key"
+)
+
+// ErrUnrecognized is returned when the input is not a supported key,
+// certificate request or certificate.
+var ErrUnrecognized = errors.New("no key, certificate request or certificate found")
+
+// Field is a named value in a Report.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Report describes one object found by Inspect.
+type Report struct {
+	// Kind is PrivateKey, PublicKey, SymmetricKey, Certificate or CertificateRequest.
+	Kind string
+	// Format is the container, such as "PKCS#8", "PKCS#1", "X.509" or "OpenSSH".
+	Format string
+	// Encoding is "PEM", "DER" or "text".
+	Encoding string
+	// Encrypted is set for passphrase protected private keys, whose details
+	// other than the format are unknown.
+	Encrypted bool
+	// Algorithm names the key algorithm and curve, such as "RSA" or "ECDSA P-256".
+	Algorithm string
+	// Bits is the key size; Exponent is the public exponent of RSA keys.
+	Bits     int
+	Exponent int
+	// PublicKey is the public key, or the public half of a private key.
+	// It is nil for encrypted keys without a public part.
+	PublicKey crypto.PublicKey
+	// Fingerprints lists the key and certificate digests.
+	Fingerprints []Field
+	// Details lists certificate and request fields.
+	Details []Field
+	// Warnings lists weaknesses such as small keys or expired certificates.
+	Warnings []string
+}
+
+// Title returns a one line summary such as "RSA 2048 bit private key (PKCS#8 PEM)".
+func (r Report) Title() string {
+	var parts []string
+	if r.Algorithm != "" {
+		parts = append(parts, r.Algorithm)
+	}
+	if r.Bits > 0 {
+		parts = append(parts, fmt.Sprintf("%d bit", r.Bits))
+	}
+	kind := r.Kind
+	if len(parts) > 0 {
+		kind = strings.ToLower(kind)
+	}
+	if r.Encrypted {
+		kind = "encrypted " + strings.ToLower(kind)
+	}
+	parts = append(parts, kind)
+	return fmt.Sprintf("%s (%s %s)", strings.Join(parts, " "), r.Format, r.Encoding)
+}
+
+// Fields returns everything known about the object in display order.
+func (r Report) Fields() []Field {
+	fields := []Field{{"Type", r.Kind}, {"Format", r.Format + " " + r.Encoding}}
+	if r.Encrypted {
+		fields = append(fields, Field{"Encrypted", "yes, a passphrase is needed to read the private key"})
+	}
+	if r.Algorithm != "" {
+		fields = append(fields, Field{"Algorithm", r.Algorithm})
+	}
+	if r.Bits > 0 {
+		fields = append(fields, Field{"Size", fmt.Sprintf("%d bits", r.Bits)})
+	}
+	if r.Exponent > 0 {
+		fields = append(fields, Field{"Exponent", fmt.Sprintf("%d (0x%x)", r.Exponent, r.Exponent)})
+	}
+	fields = append(fields, r.Details...)
+	return append(fields, r.Fingerprints...)
+}
+
+// Inspect describes every object in data: any number of PEM blocks, a single
+// DER object, an OpenSSH public key line, or a JWK or JWK Set.
+func Inspect(data []byte) ([]Report, error) {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return inspectJWK(string(trimmed))
+	case bytes.HasPrefix(trimmed, []byte("ssh-")), bytes.HasPrefix(trimmed, []byte("ecdsa-")):
+		return inspectAuthorizedKeys(trimmed)
+	}
+
+	var reports []Report
+	rest := trimmed
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		report, err := inspectPEM(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s block: %w", block.Type, err)
+		}
+		reports = append(reports, report)
+	}
+	if len(reports) > 0 {
+		return reports, nil
+	}
+
+	report, err := inspectDER(trimmed)
+	if err != nil {
+		return nil, err
+	}
+	return []Report{report}, nil
+}
+
+func inspectPEM(block *pem.Block) (Report, error) {
+	r := Report{Encoding: "PEM"}
+	if block.Headers["Proc-Type"] == "4,ENCRYPTED" {
+		r.Kind, r.Format, r.Encrypted = PrivateKey, "PKCS#1", true
+		return r, nil
+	}
+	switch block.Type {
+	case rsakeys.EncryptedPKCS8Type:
+		r.Kind, r.Format, r.Encrypted = PrivateKey, "PKCS#8", true
+		return r, nil
+	case rsakeys.OpenSSHPrivateKeyType:
+		r.Kind, r.Format = PrivateKey, "OpenSSH"
+		key, err := ssh.ParseRawPrivateKey(pem.EncodeToMemory(block))
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			r.Encrypted = true
+			if cryptoKey, ok := missing.PublicKey.(ssh.CryptoPublicKey); ok {
+				r.describeKey(cryptoKey.CryptoPublicKey())
+			}
+			return r, nil
+		}
+		if err != nil {
+			return r, err
+		}
+		return r, r.describePrivateKey(key)
+	}
+
+	r, err := parseDER(block.Type, block.Bytes)
+	r.Encoding = "PEM"
+	return r, err
+}
+
+// derParsers are tried in order on DER input; they are keyed by the PEM
+// type that holds the same structure.
+var derParsers = []string{
+	"CERTIFICATE",
+	"CERTIFICATE REQUEST",
+	rsakeys.PKCS8PrivateKeyType,
+	rsakeys.PrivateKeyType,
+	eckeys.SEC1PrivateKeyType,
+	rsakeys.PKIXPublicKeyType,
+	rsakeys.PublicKeyType,
+}
+
+func inspectDER(der []byte) (Report, error) {
+	for _, blockType := range derParsers {
+		if r, err := parseDER(blockType, der); err == nil {
+			r.Encoding = "DER"
+			return r, nil
+		}
+	}
+	return Report{}, ErrUnrecognized
+}
+
+// parseDER describes der as the structure named by the PEM type blockType.
+func parseDER(blockType string, der []byte) (Report, error) {
+	var r Report
+	switch blockType {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return r, err
+		}
+		r.Kind, r.Format = Certificate, "X.509"
+		r.describeCertificate(cert, der)
+	case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			return r, err
+		}
+		r.Kind, r.Format = CertificateRequest, "PKCS#10"
+		r.describeRequest(csr)
+	case rsakeys.PKCS8PrivateKeyType:
+		key, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return r, err
+		}
+		r.Kind, r.Format = PrivateKey, "PKCS#8"
+		return r, r.describePrivateKey(key)
+	case rsakeys.PrivateKeyType:
+		key, err := x509.ParsePKCS1PrivateKey(der)
+		if err != nil {
+			return r, err
+		}
+		r.Kind, r.Format = PrivateKey, "PKCS#1"
+		return r, r.describePrivateKey(key)
+	case eckeys.SEC1PrivateKeyType:
+		key, err := x509.ParseECPrivateKey(der)
+		if err != nil {
+			return r, err
+		}
+		r.Kind, r.Format = PrivateKey, "SEC 1"
+		return r, r.describePrivateKey(key)
+	case rsakeys.PKIXPublicKeyType:
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			return r, err
+		}
+		r.Kind, r.Format = PublicKey, "PKIX"
+		r.describeKey(key)
+	case rsakeys.PublicKeyType:
+		key, err := x509.ParsePKCS1PublicKey(der)
+		if err != nil {
+			return r, err
+		}
+		r.Kind, r.Format = PublicKey, "PKCS#1"
+		r.describeKey(key)
+	default:
+		return r, fmt.Errorf("unsupported PEM type %q", blockType)
+	}
+	return r, nil
+}
+
+func inspectAuthorizedKeys(data []byte) ([]Report, error) {
+	var reports []Report
+	for len(bytes.TrimSpace(data)) > 0 {
+		sshKey, comment, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, err
+		}
+		r := Report{Kind: PublicKey, Format: "OpenSSH", Encoding: "text"}
+		if cryptoKey, ok := sshKey.(ssh.CryptoPublicKey); ok {
+			r.describeKey(cryptoKey.CryptoPublicKey())
+		}
+		if comment != "" {
+			r.Details = append(r.Details, Field{"Comment", comment})
+		}
+		reports = append(reports, r)
+		data = rest
+	}
+	return reports, nil
+}
+
+func inspectJWK(text string) ([]Report, error) {
+	keys, err := jwk.Parse([]byte(text))
+	if err != nil {
+		return nil, err
+	}
+	format := "JWK"
+	if len(keys) > 1 || strings.Contains(text, `"keys"`) {
+		format = "JWK Set"
+	}
+	var reports []Report
+	for _, key := range keys {
+		r := Report{Kind: PublicKey, Format: format, Encoding: "text"}
+		for _, param := range []Field{{"Key ID", key.KeyID}, {"Use", key.Use}, {"JWK algorithm", key.Alg}} {
+			if param.Value != "" {
+				r.Details = append(r.Details, param)
+			}
+		}
+		if secret, ok := key.Key.([]byte); ok {
+			r.Kind, r.Algorithm, r.Bits = SymmetricKey, "oct", len(secret)*8
+			if thumbprint, err := jwk.Thumbprint(secret); err == nil {
+				r.Fingerprints = append(r.Fingerprints, Field{"JWK thumbprint", thumbprint})
+			}
+		} else if jwk.IsPrivate(key.Key) {
+			r.Kind = PrivateKey
+			if err := r.describePrivateKey(key.Key); err != nil {
+				return nil, err
+			}
+		} else {
+			r.describeKey(key.Key)
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+func (r *Report) describePrivateKey(key crypto.PrivateKey) error {
+	if k, ok := key.(*ed25519.PrivateKey); ok {
+		key = *k
+	}
+	signer, ok := key.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return fmt.Errorf("unsupported private key type %T", key)
+	}
+	r.describeKey(signer.Public())
+	return nil
+}
+
+// describeKey fills in the algorithm, size and fingerprints of key.
+func (r *Report) describeKey(key crypto.PublicKey) {
+	r.PublicKey = key
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		r.Algorithm, r.Bits, r.Exponent = "RSA", k.N.BitLen(), k.E
+		if r.Bits < rsakeys.MinSecureBits {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("RSA keys under %d bits are insecure", rsakeys.MinSecureBits))
+		}
+		if k.E < 65537 {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("public exponent %d is unusually small, 65537 is standard", k.E))
+		}
+	case *ecdsa.PublicKey:
+		r.Algorithm, r.Bits = "ECDSA "+k.Curve.Params().Name, k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		r.Algorithm, r.Bits = "Ed25519", 256
+	case *ecdh.PublicKey:
+		if k.Curve() == ecdh.X25519() {
+			r.Algorithm, r.Bits = "X25519", 256
+		} else {
+			r.Algorithm = "ECDH"
+		}
+	case *dsa.PublicKey:
+		r.Algorithm, r.Bits = "DSA", k.P.BitLen()
+		r.Warnings = append(r.Warnings, "DSA is deprecated and no longer accepted by OpenSSH")
+	default:
+		r.Algorithm = fmt.Sprintf("%T", key)
+	}
+
+	if sshKey, err := ssh.NewPublicKey(key); err == nil {
+		r.Fingerprints = append(r.Fingerprints,
+			Field{"SSH SHA-256", ssh.FingerprintSHA256(sshKey)},
+			Field{"SSH MD5", ssh.FingerprintLegacyMD5(sshKey)})
+	}
+	if der, err := x509.MarshalPKIXPublicKey(key); err == nil {
+		sum := sha256.Sum256(der)
+		r.Fingerprints = append(r.Fingerprints,
+			Field{"SPKI SHA-256", colonHex(sum[:])},
+			Field{"SPKI pin-sha256", base64.StdEncoding.EncodeToString(sum[:])})
+	}
+	if thumbprint, err := jwk.Thumbprint(key); err == nil {
+		r.Fingerprints = append(r.Fingerprints, Field{"JWK thumbprint", thumbprint})
+	}
+}
+
+func (r *Report) describeCertificate(cert *x509.Certificate, der []byte) {
+	r.describeKey(cert.PublicKey)
+	r.Details = append(r.Details,
+		Field{"Subject", cert.Subject.String()},
+		Field{"Issuer", cert.Issuer.String()},
+		Field{"Serial", colonHex(cert.SerialNumber.Bytes())},
+		Field{"Not before", cert.NotBefore.UTC().Format(time.RFC3339)},
+		Field{"Not after", cert.NotAfter.UTC().Format(time.RFC3339)},
+		Field{"Signature", cert.SignatureAlgorithm.String()})
+	if names := subjectAltNames(cert.DNSNames, cert.EmailAddresses, cert.IPAddresses, cert.URIs); names != "" {
+		r.Details = append(r.Details, Field{"Subject alt names", names})
+	}
+	if cert.IsCA {
+		r.Details = append(r.Details, Field{"CA", "yes"})
+	}
+	// CheckSignatureFrom would refuse a self-signed leaf as its own parent
+	// because it is not a CA, so check the signature directly.
+	if bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil {
+		r.Details = append(r.Details, Field{"Self-signed", "yes"})
+	}
+
+	sha := sha256.Sum256(der)
+	md := md5.Sum(der)
+	r.Fingerprints = append(r.Fingerprints,
+		Field{"Certificate SHA-256", colonHex(sha[:])},
+		Field{"Certificate MD5", colonHex(md[:])})
+
+	now := time.Now()
+	switch {
+	case now.Before(cert.NotBefore):
+		r.Warnings = append(r.Warnings, "the certificate is not valid yet")
+	case now.After(cert.NotAfter):
+		r.Warnings = append(r.Warnings, "the certificate has expired")
+	}
+	switch cert.SignatureAlgorithm {
+	case x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		r.Warnings = append(r.Warnings, cert.SignatureAlgorithm.String()+" signatures are insecure")
+	}
+}
+
+func (r *Report) describeRequest(csr *x509.CertificateRequest) {
+	r.describeKey(csr.PublicKey)
+	r.Details = append(r.Details,
+		Field{"Subject", csr.Subject.String()},
+		Field{"Signature", csr.SignatureAlgorithm.String()})
+	if names := subjectAltNames(csr.DNSNames, csr.EmailAddresses, csr.IPAddresses, csr.URIs); names != "" {
+		r.Details = append(r.Details, Field{"Subject alt names", names})
+	}
+	if err := csr.CheckSignature(); err != nil {
+		r.Warnings = append(r.Warnings, "the request signature is invalid: "+err.Error())
+	}
+}
+
+func subjectAltNames(dns, email []string, ips []net.IP, uris []*url.URL) string {
+	names := append(append([]string{}, dns...), email...)
+	for _, ip := range ips {
+		names = append(names, ip.String())
+	}
+	for _, uri := range uris {
+		names = append(names, uri.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// colonHex formats b as upper case hex bytes separated by colons, as
+// OpenSSL prints fingerprints.
+func colonHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{c}))
+	}
+	return strings.Join(parts, ":")
+}
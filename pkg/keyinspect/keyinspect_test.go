@@ -0,0 +1,356 @@
+package keyinspect
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/jwk"
+	"joshu/pkg/rsakeys"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// fieldValue returns the value of the named field, or "" if it is missing.
+func fieldValue(fields []Field, name string) string {
+	for _, f := range fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+func mustGenerate(t *testing.T, curve eckeys.Curve, format rsakeys.KeyFormat) (string, string) {
+	t.Helper()
+	private, public, err := eckeys.Generate(curve, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return private, public
+}
+
+// newCertificate returns a certificate for example.com signed by its own
+// key, and a certificate request with the same names.
+func newCertificate(t *testing.T, key crypto.Signer) (string, string) {
+	t.Helper()
+	subject := pkix.Name{CommonName: "example.com"}
+	dnsNames, ips := []string{"example.com"}, []net.IP{net.ParseIP("10.0.0.1")}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: subject, DNSNames: dnsNames, IPAddresses: ips}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: requestDER}))
+}
+
+func TestInspectKeys(t *testing.T) {
+	rsaPKCS1, rsaPublic, err := rsakeys.GenerateFormat(2048, rsakeys.PKCS1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaJWK, rsaPublicJWK, err := rsakeys.GenerateFormat(2048, rsakeys.JWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaEncrypted, _, err := rsakeys.GenerateWithPassphrase(2048, rsakeys.PKCS8, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshEncrypted, _, err := rsakeys.GenerateWithPassphrase(2048, rsakeys.OpenSSH, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, p256Public := mustGenerate(t, eckeys.P256, rsakeys.PKCS8)
+	edSSH, edAuthorized := mustGenerate(t, eckeys.Ed25519, rsakeys.OpenSSH)
+	x25519, _ := mustGenerate(t, eckeys.X25519, rsakeys.PKCS8)
+	p384, _ := mustGenerate(t, eckeys.P384, rsakeys.PKCS8)
+	p384Key, _, _ := eckeys.ParsePrivateKey(p384)
+	sec1, err := x509.MarshalECPrivateKey(p384Key.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384SEC1 := string(pem.EncodeToMemory(&pem.Block{Type: eckeys.SEC1PrivateKeyType, Bytes: sec1}))
+	p256Key, _, _ := eckeys.ParsePrivateKey(p256)
+	p256JWK, err := jwk.Marshal(p256Key, jwk.Params{KeyID: "k1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretJWK, err := jwk.Marshal([]byte("0123456789abcdef"), jwk.Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode([]byte(rsaPublic))
+
+	tests := []struct {
+		name      string
+		data      string
+		kind      string
+		format    string
+		encoding  string
+		algorithm string
+		bits      int
+		encrypted bool
+		hasPublic bool
+	}{
+		{"RSA PKCS#1", rsaPKCS1, PrivateKey, "PKCS#1", "PEM", "RSA", 2048, false, true},
+		{"RSA public", rsaPublic, PublicKey, "PKCS#1", "PEM", "RSA", 2048, false, true},
+		{"RSA public DER", string(block.Bytes), PublicKey, "PKCS#1", "DER", "RSA", 2048, false, true},
+		{"RSA JWK", rsaJWK, PrivateKey, "JWK", "text", "RSA", 2048, false, true},
+		{"RSA public JWK", rsaPublicJWK, PublicKey, "JWK", "text", "RSA", 2048, false, true},
+		{"EC JWK", p256JWK, PrivateKey, "JWK", "text", "ECDSA P-256", 256, false, true},
+		{"oct JWK", secretJWK, SymmetricKey, "JWK", "text", "oct", 128, false, false},
+		{"encrypted PKCS#8", rsaEncrypted, PrivateKey, "PKCS#8", "PEM", "", 0, true, false},
+		{"encrypted OpenSSH", sshEncrypted, PrivateKey, "OpenSSH", "PEM", "RSA", 2048, true, true},
+		{"P-256 PKCS#8", p256, PrivateKey, "PKCS#8", "PEM", "ECDSA P-256", 256, false, true},
+		{"P-256 public", p256Public, PublicKey, "PKIX", "PEM", "ECDSA P-256", 256, false, true},
+		{"P-384 SEC 1", p384SEC1, PrivateKey, "SEC 1", "PEM", "ECDSA P-384", 384, false, true},
+		{"Ed25519 OpenSSH", edSSH, PrivateKey, "OpenSSH", "PEM", "Ed25519", 256, false, true},
+		{"Ed25519 authorized key", edAuthorized, PublicKey, "OpenSSH", "text", "Ed25519", 256, false, true},
+		{"X25519", x25519, PrivateKey, "PKCS#8", "PEM", "X25519", 256, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := Inspect([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Inspect: %v", err)
+			}
+			if len(reports) != 1 {
+				t.Fatalf("Inspect() found %d objects, want 1", len(reports))
+			}
+			r := reports[0]
+			if r.Kind != tt.kind || r.Format != tt.format || r.Encoding != tt.encoding {
+				t.Errorf("Inspect() = %s %s %s, want %s %s %s", r.Kind, r.Format, r.Encoding, tt.kind, tt.format, tt.encoding)
+			}
+			if r.Algorithm != tt.algorithm || r.Bits != tt.bits || r.Encrypted != tt.encrypted {
+				t.Errorf("Inspect() = %q %d bits encrypted %v, want %q %d bits encrypted %v",
+					r.Algorithm, r.Bits, r.Encrypted, tt.algorithm, tt.bits, tt.encrypted)
+			}
+			if (r.PublicKey != nil) != tt.hasPublic {
+				t.Errorf("Inspect() public key %T, want one %v", r.PublicKey, tt.hasPublic)
+			}
+			if tt.hasPublic && fieldValue(r.Fingerprints, "SPKI SHA-256") == "" {
+				t.Error("the SPKI fingerprint is missing")
+			}
+			if !strings.Contains(r.Title(), tt.format+" "+tt.encoding) {
+				t.Errorf("Title() = %q", r.Title())
+			}
+		})
+	}
+}
+
+// TestFingerprintsAgree checks that every encoding of a key gets the same
+// fingerprints.
+func TestFingerprintsAgree(t *testing.T) {
+	private, public := mustGenerate(t, eckeys.Ed25519, rsakeys.OpenSSH)
+	key, _, err := eckeys.ParsePrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := eckeys.EncodePrivateKey(key, rsakeys.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicJWK, err := jwk.Marshal(jwk.Public(key), jwk.Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumbprint, err := jwk.Thumbprint(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []Field
+	for _, data := range []string{private, public, pkcs8, publicJWK} {
+		reports, err := Inspect([]byte(data))
+		if err != nil {
+			t.Fatalf("Inspect: %v", err)
+		}
+		got := reports[0].Fingerprints
+		if fieldValue(got, "JWK thumbprint") != thumbprint {
+			t.Errorf("JWK thumbprint = %q, want %q", fieldValue(got, "JWK thumbprint"), thumbprint)
+		}
+		if want == nil {
+			want = got
+			continue
+		}
+		for _, f := range want {
+			if fieldValue(got, f.Name) != f.Value {
+				t.Errorf("%s %s = %q, want %q", reports[0].Format, f.Name, fieldValue(got, f.Name), f.Value)
+			}
+		}
+	}
+}
+
+func TestInspectCertificates(t *testing.T) {
+	keyText, _ := mustGenerate(t, eckeys.P256, rsakeys.PKCS8)
+	key, _, err := eckeys.ParsePrivateKey(keyText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, request := newCertificate(t, key.(crypto.Signer))
+
+	reports, err := Inspect([]byte(cert + request))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Inspect() found %d objects, want 2", len(reports))
+	}
+	tests := []struct {
+		report Report
+		kind   string
+		fields map[string]string
+	}{
+		{reports[0], Certificate, map[string]string{
+			"Subject":           "CN=example.com",
+			"Issuer":            "CN=example.com",
+			"Subject alt names": "example.com, 10.0.0.1",
+			"Self-signed":       "yes",
+		}},
+		{reports[1], CertificateRequest, map[string]string{
+			"Subject":           "CN=example.com",
+			"Subject alt names": "example.com, 10.0.0.1",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			if tt.report.Kind != tt.kind || tt.report.Algorithm != "ECDSA P-256" {
+				t.Errorf("Inspect() = %s %s", tt.report.Kind, tt.report.Algorithm)
+			}
+			for name, want := range tt.fields {
+				if got := fieldValue(tt.report.Fields(), name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if len(tt.report.Warnings) != 0 {
+				t.Errorf("Warnings = %q", tt.report.Warnings)
+			}
+		})
+	}
+	if err := Match([]byte(keyText), []byte(cert)); err != nil {
+		t.Errorf("Match of the key and its certificate: %v", err)
+	}
+}
+
+func TestInspectTamperedRequest(t *testing.T) {
+	keyText, _ := mustGenerate(t, eckeys.Ed25519, rsakeys.PKCS8)
+	key, _, err := eckeys.ParsePrivateKey(keyText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, request := newCertificate(t, key.(crypto.Signer))
+	block, _ := pem.Decode([]byte(request))
+	block.Bytes[len(block.Bytes)-1] ^= 1
+	if _, err := x509.ParseCertificateRequest(block.Bytes); err != nil {
+		t.Fatal(err)
+	}
+	reports, err := Inspect(pem.EncodeToMemory(block))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if w := reports[0].Warnings; len(w) != 1 || !strings.Contains(w[0], "signature is invalid") {
+		t.Errorf("Warnings = %q, want an invalid signature", w)
+	}
+}
+
+func TestInspectErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		target error
+	}{
+		{"empty", "", ErrUnrecognized},
+		{"text", "hello", ErrUnrecognized},
+		{"bad JWK", `{"kty":"XYZ"}`, jwk.ErrInvalid},
+		{"unsupported PEM", "-----BEGIN FOO-----\nAAAA\n-----END FOO-----\n", nil},
+		{"corrupt certificate", "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n", nil},
+		{"bad authorized key", "ssh-ed25519 !!!", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Inspect([]byte(tt.data))
+			if err == nil {
+				t.Fatal("Inspect succeeded")
+			}
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Errorf("Inspect() error = %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	private, public := mustGenerate(t, eckeys.P256, rsakeys.PKCS8)
+	_, otherPublic := mustGenerate(t, eckeys.P256, rsakeys.PKCS8)
+	encrypted, encryptedPublic, err := rsakeys.GenerateWithPassphrase(2048, rsakeys.PKCS8, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		private string
+		public  string
+		target  error
+		wantErr string
+	}{
+		{"pair", private, public, nil, ""},
+		{"private twice", private, private, nil, ""},
+		{"other key", private, otherPublic, ErrKeyMismatch, ""},
+		{"public key given as private", public, public, nil, "holds a Public key"},
+		{"encrypted", encrypted, encryptedPublic, nil, "decrypt it first"},
+		{"encrypted OpenSSH", readTestdata(t, "ssh_ed25519"), readTestdata(t, "ssh_ed25519.pub"), nil, "decrypt it first"},
+		{"garbage public key", private, "garbage", ErrUnrecognized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Match([]byte(tt.private), []byte(tt.public))
+			if tt.target == nil && tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Match: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Match succeeded")
+			}
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Errorf("Match() error = %v, want %v", err, tt.target)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Match() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
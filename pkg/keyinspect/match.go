@@ -0,0 +1,50 @@
+package keyinspect
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+)
+
+// ErrKeyMismatch is returned by Match when the keys do not form a pair.
+var ErrKeyMismatch = errors.New("the private key does not match the public key")
+
+// Match checks that the private key in privateData and the public key in
+// publicData form a pair. publicData may also hold a certificate, a
+// certificate request or another copy of the private key; the first object
+// in each input is used.
+func Match(privateData, publicData []byte) error {
+	private, err := firstKey(privateData, "private key")
+	if err != nil {
+		return err
+	}
+	if private.Kind != PrivateKey {
+		return fmt.Errorf("the private key input holds a %s", private.Kind)
+	}
+	// The public key of an encrypted OpenSSH key is stored in the clear and
+	// is not authenticated by the passphrase, so it proves nothing.
+	if private.Encrypted {
+		return errors.New("the private key is encrypted, decrypt it first")
+	}
+	public, err := firstKey(publicData, "public key")
+	if err != nil {
+		return err
+	}
+	if public.PublicKey == nil {
+		return errors.New("the public key input is an encrypted private key, decrypt it first")
+	}
+
+	key, ok := private.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !key.Equal(public.PublicKey) {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+func firstKey(data []byte, name string) (Report, error) {
+	reports, err := Inspect(data)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return reports[0], nil
+}
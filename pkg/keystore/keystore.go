@@ -0,0 +1,361 @@
+// Package keystore keeps labelled key pairs in a local file encrypted with a
+// master passphrase.
+//
+// The file is JSON with standard base64 binary fields:
+//
+//	{
+//	  "version": 1,
+//	  "kdf": "argon2id",       key derivation from the master passphrase
+//	  "memory": 65536,         Argon2id memory in KiB
+//	  "iterations": 3,
+//	  "parallelism": 4,
+//	  "salt": "...",
+//	  "nonce": "...",          AES-256-GCM nonce
+//	  "ciphertext": "..."      the JSON entry list, encrypted and authenticated
+//	}
+//
+// A new salt and nonce are used on every save.
+package keystore
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/keyinspect"
+	"joshu/pkg/rsakeys"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the keystore file in the user's config directory.
+const FileName = "keystore.json"
+
+const (
+	version     = 1
+	kdf         = "argon2id"
+	saltSize    = 16
+	keySize     = 32
+	memory      = 64 * 1024
+	iterations  = 3
+	parallelism = 4
+
+	// Limits on the parameters read from a file, so a tampered file
+	// cannot exhaust memory before the passphrase is checked.
+	maxMemory     = 1 << 21
+	maxIterations = 64
+)
+
+// additionalData binds the ciphertext to this file format.
+var additionalData = []byte("joshu keystore v1")
+
+var (
+	// ErrIncorrectPassphrase is returned by Open when the master passphrase
+	// does not decrypt the keystore.
+	ErrIncorrectPassphrase = errors.New("incorrect master passphrase")
+	// ErrNotFound is returned for an unknown entry id.
+	ErrNotFound = errors.New("no such key in the keystore")
+	// ErrExists is returned by Create when the keystore file already exists.
+	ErrExists = errors.New("the keystore already exists")
+)
+
+// Entry is a stored key pair.
+type Entry struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	// Algorithm is the key algorithm and curve, such as "RSA" or "ECDSA P-256".
+	Algorithm string `json:"algorithm"`
+	Bits      int    `json:"bits"`
+	// Fingerprint is the SSH SHA-256 fingerprint of the public key, or its
+	// SPKI SHA-256 digest for keys without an SSH form.
+	Fingerprint string    `json:"fingerprint"`
+	Created     time.Time `json:"created"`
+	PrivateKey  string    `json:"private_key"`
+	PublicKey   string    `json:"public_key"`
+}
+
+type file struct {
+	Version     int    `json:"version"`
+	KDF         string `json:"kdf"`
+	Memory      uint32 `json:"memory"`
+	Iterations  uint32 `json:"iterations"`
+	Parallelism uint8  `json:"parallelism"`
+	Salt        []byte `json:"salt"`
+	Nonce       []byte `json:"nonce"`
+	Ciphertext  []byte `json:"ciphertext"`
+}
+
+// Store is an unlocked keystore. Changes are written to disk immediately.
+type Store struct {
+	path       string
+	passphrase string
+	entries    []Entry
+}
+
+// DefaultPath returns the keystore path in the user's config directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "joshu", FileName), nil
+}
+
+// Exists reports whether a keystore file exists at path.
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Create makes an empty keystore at path protected by passphrase.
+func Create(path, passphrase string) (*Store, error) {
+	if passphrase == "" {
+		return nil, errors.New("the master passphrase must not be empty")
+	}
+	if Exists(path) {
+		return nil, ErrExists
+	}
+	s := &Store{path: path, passphrase: passphrase}
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Open decrypts the keystore at path with passphrase.
+func Open(path, passphrase string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("not a keystore: %v", err)
+	}
+	if f.Version != version || f.KDF != kdf {
+		return nil, fmt.Errorf("unsupported keystore version %d (%s)", f.Version, f.KDF)
+	}
+	if f.Memory == 0 || f.Memory > maxMemory || f.Iterations == 0 || f.Iterations > maxIterations || f.Parallelism == 0 {
+		return nil, errors.New("the keystore has invalid key derivation parameters")
+	}
+
+	aead, err := newAEAD(passphrase, f.Salt, f.Memory, f.Iterations, f.Parallelism)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != aead.NonceSize() {
+		return nil, errors.New("the keystore has an invalid nonce")
+	}
+	plaintext, err := aead.Open(nil, f.Nonce, f.Ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+	s := &Store{path: path, passphrase: passphrase}
+	if err := json.Unmarshal(plaintext, &s.entries); err != nil {
+		return nil, fmt.Errorf("the keystore is corrupted: %v", err)
+	}
+	return s, nil
+}
+
+// Path returns the file the store is saved to.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Entries returns the stored keys, newest first.
+func (s *Store) Entries() []Entry {
+	entries := append([]Entry(nil), s.entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Created.After(entries[j].Created)
+	})
+	return entries
+}
+
+// Get returns the entry with id.
+func (s *Store) Get(id string) (Entry, error) {
+	for _, e := range s.entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return Entry{}, ErrNotFound
+}
+
+// Add stores a key pair under label and saves the keystore. The private key
+// may be passphrase protected; when publicKey is empty it is derived from an
+// unencrypted private key. Keys that do not form a pair are rejected with
+// keyinspect.ErrKeyMismatch; an encrypted private key cannot be checked.
+func (s *Store) Add(label, privateKey, publicKey string) (Entry, error) {
+	privateKey, publicKey = strings.TrimSpace(privateKey), strings.TrimSpace(publicKey)
+	if privateKey == "" {
+		return Entry{}, errors.New("there is no private key to store")
+	}
+	if publicKey == "" {
+		var err error
+		if publicKey, err = publicKeyOf(privateKey); err != nil {
+			return Entry{}, err
+		}
+	}
+	reports, err := keyinspect.Inspect([]byte(publicKey))
+	if err != nil {
+		return Entry{}, fmt.Errorf("public key: %w", err)
+	}
+	report := reports[0]
+	if report.PublicKey == nil {
+		return Entry{}, errors.New("the public key box does not hold a public key")
+	}
+	if !rsakeys.IsEncrypted(privateKey) {
+		if err := keyinspect.Match([]byte(privateKey), []byte(publicKey)); err != nil {
+			return Entry{}, err
+		}
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Entry{}, err
+	}
+	e := Entry{
+		ID:         hex.EncodeToString(id),
+		Label:      strings.TrimSpace(label),
+		Algorithm:  report.Algorithm,
+		Bits:       report.Bits,
+		Created:    time.Now().UTC(),
+		PrivateKey: privateKey + "\n",
+		PublicKey:  publicKey + "\n",
+	}
+	if e.Label == "" {
+		e.Label = fmt.Sprintf("%s key %s", e.Algorithm, e.Created.Local().Format("2006-01-02 15:04"))
+	}
+	if len(report.Fingerprints) > 0 {
+		e.Fingerprint = report.Fingerprints[0].Value
+	}
+
+	s.entries = append(s.entries, e)
+	if err := s.save(); err != nil {
+		s.entries = s.entries[:len(s.entries)-1]
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+// Remove deletes the entry with id and saves the keystore.
+func (s *Store) Remove(id string) error {
+	for i, e := range s.entries {
+		if e.ID == id {
+			entries := append(append([]Entry(nil), s.entries[:i]...), s.entries[i+1:]...)
+			previous := s.entries
+			s.entries = entries
+			if err := s.save(); err != nil {
+				s.entries = previous
+				return err
+			}
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// ChangePassphrase re-encrypts the keystore with a new master passphrase.
+func (s *Store) ChangePassphrase(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("the master passphrase must not be empty")
+	}
+	previous := s.passphrase
+	s.passphrase = passphrase
+	if err := s.save(); err != nil {
+		s.passphrase = previous
+		return err
+	}
+	return nil
+}
+
+// save encrypts the entries and replaces the file, writing to a temporary
+// file first so a failed write never loses the previous keystore.
+func (s *Store) save() error {
+	entries := s.entries
+	if entries == nil {
+		entries = []Entry{}
+	}
+	plaintext, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	f := file{
+		Version:     version,
+		KDF:         kdf,
+		Memory:      memory,
+		Iterations:  iterations,
+		Parallelism: parallelism,
+		Salt:        make([]byte, saltSize),
+	}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	aead, err := newAEAD(s.passphrase, f.Salt, f.Memory, f.Iterations, f.Parallelism)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Ciphertext = aead.Seal(nil, f.Nonce, plaintext, additionalData)
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".keystore-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func newAEAD(passphrase string, salt []byte, memory, iterations uint32, parallelism uint8) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), salt, iterations, memory, parallelism, keySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// publicKeyOf derives the public key from an unencrypted RSA or
+// elliptic-curve private key.
+func publicKeyOf(privateKey string) (string, error) {
+	if _, publicKey, err := rsakeys.ConvertPrivateKey(privateKey, rsakeys.PKCS8); err == nil {
+		return publicKey, nil
+	} else if errors.Is(err, rsakeys.ErrPassphraseRequired) {
+		return "", errors.New("the private key is encrypted, give its public key too")
+	}
+	key, _, err := eckeys.ParsePrivateKey(privateKey)
+	if err != nil {
+		return "", errors.New("failed to decode the private key")
+	}
+	signer, ok := key.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return "", errors.New("failed to derive the public key")
+	}
+	return eckeys.EncodePublicKey(signer.Public(), rsakeys.PKCS8)
+}
@@ -0,0 +1,259 @@
+package keystore
+
+import (
+	"encoding/json"
+	"errors"
+	"joshu/pkg/eckeys"
+	"joshu/pkg/keyinspect"
+	"joshu/pkg/rsakeys"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Create(filepath.Join(t.TempDir(), "nested", FileName), "master")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return s
+}
+
+func TestAddAndReopen(t *testing.T) {
+	rsaPrivate, rsaPublic, err := rsakeys.GenerateFormat(2048, rsakeys.PKCS1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPrivate, ecPublic, err := eckeys.Generate(eckeys.Ed25519, rsakeys.OpenSSH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedPrivate, encryptedPublic, err := rsakeys.GenerateWithPassphrase(2048, rsakeys.OpenSSH, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256Private, _, err := eckeys.Generate(eckeys.P256, rsakeys.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		label      string
+		private    string
+		public     string
+		algorithm  string
+		wantLabel  string
+		derivesKey bool
+	}{
+		{"RSA", "deploy key", rsaPrivate, rsaPublic, "RSA", "deploy key", false},
+		{"Ed25519 OpenSSH", "  ssh  ", ecPrivate, ecPublic, "Ed25519", "ssh", false},
+		{"encrypted with its public key", "locked", encryptedPrivate, encryptedPublic, "RSA", "locked", false},
+		{"public key derived", "", p256Private, "", "ECDSA P-256", "ECDSA P-256 key", true},
+	}
+	s := newStore(t)
+	added := map[string]Entry{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := s.Add(tt.label, tt.private, tt.public)
+			if err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+			if e.Algorithm != tt.algorithm || !strings.HasPrefix(e.Label, tt.wantLabel) || e.Fingerprint == "" {
+				t.Errorf("entry %+v", e)
+			}
+			if e.PrivateKey != strings.TrimSpace(tt.private)+"\n" {
+				t.Error("the private key was not stored as given")
+			}
+			if tt.derivesKey {
+				if err := keyinspect.Match([]byte(e.PrivateKey), []byte(e.PublicKey)); err != nil {
+					t.Errorf("the derived public key does not match: %v", err)
+				}
+			}
+			added[e.ID] = e
+		})
+	}
+
+	reopened, err := Open(s.Path(), "master")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	entries := reopened.Entries()
+	if len(entries) != len(tests) {
+		t.Fatalf("reopened store has %d entries, want %d", len(entries), len(tests))
+	}
+	for i, e := range entries {
+		want, ok := added[e.ID]
+		if !ok || !e.Created.Equal(want.Created) || e.PrivateKey != want.PrivateKey || e.PublicKey != want.PublicKey || e.Label != want.Label {
+			t.Errorf("entry %s changed on reopening", e.ID)
+		}
+		if i > 0 && e.Created.After(entries[i-1].Created) {
+			t.Error("entries are not newest first")
+		}
+	}
+}
+
+func TestAddErrors(t *testing.T) {
+	private, _, err := rsakeys.GenerateFormat(2048, rsakeys.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPublic, err := rsakeys.GenerateFormat(2048, rsakeys.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, _, err := rsakeys.GenerateWithPassphrase(2048, rsakeys.PKCS8, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		private string
+		public  string
+		target  error
+		wantErr string
+	}{
+		{"no private key", " \n", otherPublic, nil, "no private key"},
+		{"keys do not match", private, otherPublic, keyinspect.ErrKeyMismatch, ""},
+		{"encrypted without a public key", encrypted, "", nil, "give its public key too"},
+		{"garbage private key", "garbage", "", nil, "failed to decode the private key"},
+		{"garbage public key", private, "garbage", nil, "public key"},
+	}
+	s := newStore(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Add("label", tt.private, tt.public)
+			if err == nil {
+				t.Fatal("Add succeeded")
+			}
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Errorf("Add() error = %v, want %v", err, tt.target)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Add() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if len(s.Entries()) != 0 {
+		t.Errorf("failed adds left %d entries", len(s.Entries()))
+	}
+}
+
+func TestRemoveAndChangePassphrase(t *testing.T) {
+	s := newStore(t)
+	private, public, err := eckeys.Generate(eckeys.P384, rsakeys.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := s.Add("key", private, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Get(e.ID); err != nil || got.ID != e.ID {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+
+	if err := s.ChangePassphrase("new master"); err != nil {
+		t.Fatalf("ChangePassphrase: %v", err)
+	}
+	if _, err := Open(s.Path(), "master"); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Errorf("Open with the old passphrase = %v, want ErrIncorrectPassphrase", err)
+	}
+	if err := s.ChangePassphrase(""); err == nil {
+		t.Error("ChangePassphrase accepted an empty passphrase")
+	}
+
+	if err := s.Remove(e.ID); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := s.Remove(e.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Remove = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Get(e.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Remove = %v, want ErrNotFound", err)
+	}
+	reopened, err := Open(s.Path(), "new master")
+	if err != nil {
+		t.Fatalf("Open with the new passphrase: %v", err)
+	}
+	if len(reopened.Entries()) != 0 {
+		t.Error("the removed entry came back")
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	s := newStore(t)
+	if _, err := Create(s.Path(), "master"); !errors.Is(err, ErrExists) {
+		t.Errorf("Create over an existing store = %v, want ErrExists", err)
+	}
+	if _, err := Create(filepath.Join(t.TempDir(), FileName), ""); err == nil {
+		t.Error("Create accepted an empty passphrase")
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), FileName), "master"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Open of a missing file = %v, want ErrNotExist", err)
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	s := newStore(t)
+	data, err := os.ReadFile(s.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		modify  func(f map[string]any)
+		target  error
+		wantErr string
+	}{
+		{"wrong passphrase", func(map[string]any) {}, ErrIncorrectPassphrase, ""},
+		{"modified ciphertext", func(f map[string]any) {
+			f["ciphertext"] = flipBase64(f["ciphertext"].(string))
+		}, ErrIncorrectPassphrase, ""},
+		{"modified salt", func(f map[string]any) {
+			f["salt"] = flipBase64(f["salt"].(string))
+		}, ErrIncorrectPassphrase, ""},
+		{"huge memory", func(f map[string]any) { f["memory"] = 1 << 30 }, nil, "invalid key derivation parameters"},
+		{"zero iterations", func(f map[string]any) { f["iterations"] = 0 }, nil, "invalid key derivation parameters"},
+		{"other version", func(f map[string]any) { f["version"] = 2 }, nil, "unsupported keystore version"},
+		{"short nonce", func(f map[string]any) { f["nonce"] = "AAAA" }, nil, "invalid nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f map[string]any
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(f)
+			modified, _ := json.Marshal(f)
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, modified, 0600); err != nil {
+				t.Fatal(err)
+			}
+			passphrase := "master"
+			if tt.name == "wrong passphrase" {
+				passphrase = "Master"
+			}
+			_, err := Open(path, passphrase)
+			if err == nil {
+				t.Fatal("Open succeeded")
+			}
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Errorf("Open() error = %v, want %v", err, tt.target)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Open() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// flipBase64 changes the first character of a base64 string.
+func flipBase64(s string) string {
+	if s[0] == 'A' {
+		return "B" + s[1:]
+	}
+	return "A" + s[1:]
+}
@@ -0,0 +1,97 @@
+package passgen
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Credential is a generated password, optionally with its hash for seeding a database.
+type Credential struct {
+	Password string `json:"password"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// ExportFormat selects how credentials are written by Export.
+type ExportFormat string
+
+// Supported export formats.
+const (
+	CSVFormat  ExportFormat = "csv"
+	JSONFormat ExportFormat = "json"
+	TextFormat ExportFormat = "txt"
+)
+
+// ExportFormats lists the export formats in display order.
+var ExportFormats = []ExportFormat{CSVFormat, JSONFormat, TextFormat}
+
+// GenerateBulk returns count passwords satisfying p.
+func GenerateBulk(p Policy, count int) ([]string, error) {
+	if count < 1 {
+		return nil, errors.New("count must be at least 1")
+	}
+	passwords := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		password, err := Generate(p)
+		if err != nil {
+			return nil, err
+		}
+		passwords = append(passwords, password)
+	}
+	return passwords, nil
+}
+
+// Export writes credentials to w. CSV output has a header row, JSON is an
+// array of objects and text has one credential per line, with the hash after
+// a tab when present.
+func Export(w io.Writer, format ExportFormat, credentials []Credential) error {
+	withHash := len(credentials) > 0 && credentials[0].Hash != ""
+
+	switch format {
+	case CSVFormat:
+		cw := csv.NewWriter(w)
+		header := []string{"password"}
+		if withHash {
+			header = append(header, "hash")
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, c := range credentials {
+			record := []string{c.Password}
+			if withHash {
+				record = append(record, c.Hash)
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case JSONFormat:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if credentials == nil {
+			credentials = []Credential{}
+		}
+		return enc.Encode(credentials)
+	case TextFormat:
+		var sb strings.Builder
+		for _, c := range credentials {
+			sb.WriteString(c.Password)
+			if withHash {
+				sb.WriteString("\t")
+				sb.WriteString(c.Hash)
+			}
+			sb.WriteString("\n")
+		}
+		_, err := io.WriteString(w, sb.String())
+		return err
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
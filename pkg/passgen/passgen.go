@@ -0,0 +1,53 @@
+// Package passgen generates random passwords and keys from a cryptographically
+// secure source.
+package passgen
+
+import (
+	"fmt"
+)
+
+// Character classes used by KeyGen.
+const (
+	LowerCase = "abcdefghijklmnopqrstuvwxyz"
+	UpperCase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Numbers   = "1234567890"
+	Special   = "`~!@#$%^&*()-=_+[]{}|;':\",./<>?"
+	HexChars  = "123456789ABCDEF"
+)
+
+// Strengths accepted by GetKey, see DefaultPolicies.
+const (
+	MemorablePassword = "memorable_pwd"
+	StrongPassword    = "strong_pwd"
+	FortKnoxPassword  = "ft_knox_pwd"
+	CodeIgniterKey    = "ci_key"
+	WPA160Key         = "160_wpa"
+	WPA504Key         = "504_wpa"
+	WEP64Key          = "64_wep"
+	WEP128Key         = "128_wep"
+	WEP152Key         = "152_wep"
+	WEP256Key         = "256_wep"
+)
+
+// Random returns a random float64 between 0 and 1.
+//
+// Deprecated: scaling a float to an index is slightly biased. Use
+// Generator.Intn, or KeyGen and Generate which already do.
+func Random() float64 {
+	return defaultGenerator.Float64()
+}
+
+// KeyGen generates a random key of the specified length.
+func KeyGen(length int, useLowerCase, useUpperCase, useNumbers, useSpecial, useHex bool) string {
+	return defaultGenerator.KeyGen(length, useLowerCase, useUpperCase, useNumbers, useSpecial, useHex)
+}
+
+// GetKey returns a key based on the strength specified, which must be the
+// name of one of the DefaultPolicies.
+func GetKey(strength string) (string, error) {
+	policy, ok := LookupPolicy(strength)
+	if !ok {
+		return "", fmt.Errorf("no such strength \"%s\"", strength)
+	}
+	return defaultGenerator.Generate(policy)
+}
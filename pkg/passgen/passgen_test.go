@@ -0,0 +1,303 @@
+package passgen
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		check  func(password string) bool
+	}{
+		{
+			name:   "lowercase only",
+			policy: Policy{Length: 20, UseLower: true},
+			check: func(p string) bool {
+				return strings.Trim(p, LowerCase) == ""
+			},
+		},
+		{
+			name:   "every class at its minimum",
+			policy: Policy{Length: 8, UseLower: true, UseUpper: true, UseNumbers: true, UseSpecial: true, MinNumbers: 3, MinSpecial: 2},
+			check: func(p string) bool {
+				return strings.ContainsAny(p, LowerCase) && strings.ContainsAny(p, UpperCase) &&
+					countAny(p, Numbers) >= 3 && countAny(p, Special) >= 2
+			},
+		},
+		{
+			name:   "excluded characters",
+			policy: Policy{Length: 200, UseLower: true, UseUpper: true, UseNumbers: true, Exclude: AmbiguousChars},
+			check: func(p string) bool {
+				return !strings.ContainsAny(p, AmbiguousChars)
+			},
+		},
+		{
+			name:   "no repeat",
+			policy: Policy{Length: 10, UseNumbers: true, NoRepeat: true},
+			check: func(p string) bool {
+				seen := map[rune]bool{}
+				for _, c := range p {
+					if seen[c] {
+						return false
+					}
+					seen[c] = true
+				}
+				return true
+			},
+		},
+		{
+			name:   "starts with a letter",
+			policy: Policy{Length: 12, UseNumbers: true, UseSpecial: true, Custom: "x", StartWithLetter: true},
+			check: func(p string) bool {
+				return unicode.IsLetter([]rune(p)[0])
+			},
+		},
+		{
+			name:   "custom characters",
+			policy: Policy{Length: 16, Custom: "äöü"},
+			check: func(p string) bool {
+				return strings.Trim(p, "äöü") == ""
+			},
+		},
+	}
+	g := NewSeededGenerator(1)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				password, err := g.Generate(tt.policy)
+				if err != nil {
+					t.Fatalf("Generate: %v", err)
+				}
+				if n := len([]rune(password)); n != tt.policy.Length {
+					t.Fatalf("%q has length %d, want %d", password, n, tt.policy.Length)
+				}
+				if !tt.check(password) {
+					t.Fatalf("%q does not satisfy the policy", password)
+				}
+			}
+		})
+	}
+}
+
+func countAny(s, chars string) int {
+	n := 0
+	for _, c := range s {
+		if strings.ContainsRune(chars, c) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   string
+	}{
+		{"zero length", Policy{UseLower: true}, "length must be at least 1"},
+		{"no class", Policy{Length: 8}, "no character class selected"},
+		{"minimum of a disabled class", Policy{Length: 8, UseLower: true, MinUpper: 1}, "disabled character class"},
+		{"class excluded entirely", Policy{Length: 8, UseNumbers: true, Exclude: Numbers}, "empty after exclusions"},
+		{"minimums exceed length", Policy{Length: 3, UseLower: true, UseNumbers: true, MinNumbers: 3}, "shorter than the 4 required"},
+		{"no repeat beyond charset", Policy{Length: 11, UseNumbers: true, NoRepeat: true}, "exceeds the 10 distinct"},
+		{"letter without letters", Policy{Length: 8, UseNumbers: true, StartWithLetter: true}, "no letters are allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetKey(t *testing.T) {
+	for _, p := range DefaultPolicies {
+		t.Run(p.Key, func(t *testing.T) {
+			key, err := GetKey(p.Key)
+			if err != nil {
+				t.Fatalf("GetKey: %v", err)
+			}
+			if len(key) != p.Length {
+				t.Errorf("GetKey(%q) has length %d, want %d", p.Key, len(key), p.Length)
+			}
+		})
+	}
+	if _, err := GetKey("no_such_strength"); err == nil {
+		t.Error("GetKey accepted an unknown strength")
+	}
+}
+
+func TestParseWordlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{"plain words", "alpha\nbravo\n", []string{"alpha", "bravo"}, false},
+		{"diceware numbers", "11111\talpha\n11112\tbravo\n", []string{"alpha", "bravo"}, false},
+		{"comments, blanks and duplicates", "# list\n\nalpha\n  bravo  \nalpha\n", []string{"alpha", "bravo"}, false},
+		{"one distinct word", "alpha\nalpha\n", nil, true},
+		{"empty", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := ParseWordlist(strings.NewReader(tt.text))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWordlist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(list, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ParseWordlist() = %q, want %q", list, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultWordlist(t *testing.T) {
+	list := DefaultWordlist()
+	if len(list) != 7776 {
+		t.Fatalf("DefaultWordlist has %d words, want 7776", len(list))
+	}
+	if list[0] != "abacus" || list[len(list)-1] != "zoom" {
+		t.Errorf("DefaultWordlist runs from %q to %q", list[0], list[len(list)-1])
+	}
+	if again := DefaultWordlist(); &again[0] != &list[0] {
+		t.Error("DefaultWordlist parsed the list again")
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	list := Wordlist{"alpha", "bravo", "charlie", "delta"}
+	tests := []struct {
+		name    string
+		opts    PassphraseOptions
+		words   int
+		entropy float64
+		check   func(word string) bool
+	}{
+		{
+			name:    "lowercase",
+			opts:    PassphraseOptions{Words: 5, Separator: "-", Wordlist: list},
+			words:   5,
+			entropy: 10,
+			check:   func(w string) bool { return w == strings.ToLower(w) },
+		},
+		{
+			name:    "title case",
+			opts:    PassphraseOptions{Words: 3, Separator: " ", Capitalization: TitleCaseWords, Wordlist: list},
+			words:   3,
+			entropy: 6,
+			check:   func(w string) bool { return unicode.IsUpper(rune(w[0])) && w[1:] == strings.ToLower(w[1:]) },
+		},
+		{
+			name:    "random case counts a bit per word",
+			opts:    PassphraseOptions{Words: 4, Separator: ".", Capitalization: RandomCaseWords, Wordlist: list},
+			words:   4,
+			entropy: 12,
+			check:   func(w string) bool { return w[1:] == strings.ToLower(w[1:]) },
+		},
+		{
+			name:    "digit and symbol",
+			opts:    PassphraseOptions{Words: 2, Separator: "_", Capitalization: UpperCaseWords, AddDigit: true, AddSymbol: true, Wordlist: list},
+			words:   2,
+			entropy: 4 + math.Log2(20) + math.Log2(float64(2*len(Special))),
+			check:   func(w string) bool { return strings.ContainsAny(w, UpperCase) },
+		},
+		{
+			name:    "default wordlist",
+			opts:    PassphraseOptions{Words: 6, Separator: " "},
+			words:   6,
+			entropy: 6 * math.Log2(7776),
+			check:   func(string) bool { return true },
+		},
+	}
+	g := NewSeededGenerator(3)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := g.GeneratePassphrase(tt.opts)
+			if err != nil {
+				t.Fatalf("GeneratePassphrase: %v", err)
+			}
+			words := strings.Split(p.Phrase, tt.opts.Separator)
+			if len(words) != tt.words {
+				t.Fatalf("%q has %d words, want %d", p.Phrase, len(words), tt.words)
+			}
+			for _, w := range words {
+				if !tt.check(w) {
+					t.Errorf("word %q of %q has the wrong form", w, p.Phrase)
+				}
+			}
+			if math.Abs(p.Entropy-tt.entropy) > 1e-9 {
+				t.Errorf("entropy %v, want %v", p.Entropy, tt.entropy)
+			}
+		})
+	}
+}
+
+func TestGeneratePassphraseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts PassphraseOptions
+	}{
+		{"no words", PassphraseOptions{}},
+		{"one word list", PassphraseOptions{Words: 3, Wordlist: Wordlist{"alpha"}}},
+		{"unknown capitalization", PassphraseOptions{Words: 3, Capitalization: "camel"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GeneratePassphrase(tt.opts); err == nil {
+				t.Error("GeneratePassphrase succeeded")
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	plain := []Credential{{Password: "a,b"}, {Password: "c"}}
+	hashed := []Credential{{Password: "a", Hash: "$2a$x"}}
+	tests := []struct {
+		name        string
+		format      ExportFormat
+		credentials []Credential
+		want        string
+	}{
+		{"csv", CSVFormat, plain, "password\n\"a,b\"\nc\n"},
+		{"csv with hash", CSVFormat, hashed, "password,hash\na,$2a$x\n"},
+		{"json", JSONFormat, hashed, "[\n  {\n    \"password\": \"a\",\n    \"hash\": \"$2a$x\"\n  }\n]\n"},
+		{"json empty", JSONFormat, nil, "[]\n"},
+		{"text", TextFormat, plain, "a,b\nc\n"},
+		{"text with hash", TextFormat, hashed, "a\t$2a$x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Export(&buf, tt.format, tt.credentials); err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Export() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+	if err := Export(&bytes.Buffer{}, "xml", plain); err == nil {
+		t.Error("Export accepted an unknown format")
+	}
+}
+
+func TestGenerateBulk(t *testing.T) {
+	passwords, err := GenerateBulk(DefaultPolicies[0], 5)
+	if err != nil || len(passwords) != 5 {
+		t.Fatalf("GenerateBulk() = %d passwords, %v", len(passwords), err)
+	}
+	if _, err := GenerateBulk(DefaultPolicies[0], 0); err == nil {
+		t.Error("GenerateBulk accepted a count of 0")
+	}
+}
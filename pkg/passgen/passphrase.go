@@ -0,0 +1,180 @@
+package passgen
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// defaultWordlist is the EFF large wordlist: 7776 words, five dice per word,
+// about 12.9 bits of entropy per word. Lines use the EFF format
+// "<dice>\t<word>".
+//
+//go:embed wordlist.txt
+var defaultWordlist string
+
+var (
+	defaultWordlistOnce   sync.Once
+	defaultWordlistParsed Wordlist
+)
+
+// Wordlist is a list of distinct words to build passphrases from.
+type Wordlist []string
+
+// DefaultWordlist returns the embedded wordlist. It is parsed on the first
+// call and shared after that, so callers must not modify it.
+func DefaultWordlist() Wordlist {
+	defaultWordlistOnce.Do(func() {
+		defaultWordlistParsed, _ = ParseWordlist(strings.NewReader(defaultWordlist))
+	})
+	return defaultWordlistParsed
+}
+
+// ParseWordlist reads one word per line. Lines may be prefixed with diceware
+// numbers as in the EFF lists; blank lines and lines starting with # are
+// ignored, as are duplicate words.
+func ParseWordlist(r io.Reader) (Wordlist, error) {
+	var list Wordlist
+	seen := map[string]bool{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		word := fields[len(fields)-1]
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		list = append(list, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(list) < 2 {
+		return nil, errors.New("wordlist must contain at least two distinct words")
+	}
+	return list, nil
+}
+
+// LoadWordlist reads a wordlist file, see ParseWordlist.
+func LoadWordlist(path string) (Wordlist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseWordlist(f)
+}
+
+// Capitalization controls how passphrase words are cased.
+type Capitalization string
+
+// Supported capitalization styles.
+const (
+	LowerCaseWords  Capitalization = "lowercase"
+	TitleCaseWords  Capitalization = "Title Case"
+	UpperCaseWords  Capitalization = "UPPERCASE"
+	RandomCaseWords Capitalization = "Random"
+)
+
+// Capitalizations lists the capitalization styles in display order.
+var Capitalizations = []Capitalization{LowerCaseWords, TitleCaseWords, UpperCaseWords, RandomCaseWords}
+
+// PassphraseOptions describes how a passphrase is built.
+type PassphraseOptions struct {
+	Words          int
+	Separator      string
+	Capitalization Capitalization
+	// AddDigit and AddSymbol append a random digit or symbol to a random word.
+	AddDigit  bool
+	AddSymbol bool
+	// Wordlist defaults to DefaultWordlist when nil.
+	Wordlist Wordlist
+}
+
+// Passphrase is a generated passphrase with its entropy in bits.
+type Passphrase struct {
+	Phrase  string
+	Entropy float64
+}
+
+// GeneratePassphrase returns a random passphrase built from opts.
+//
+// The entropy assumes the attacker knows the wordlist and every option, so
+// only the random choices made here count towards it.
+func GeneratePassphrase(opts PassphraseOptions) (Passphrase, error) {
+	return defaultGenerator.GeneratePassphrase(opts)
+}
+
+// GeneratePassphrase returns a random passphrase built from opts drawn from g.
+func (g *Generator) GeneratePassphrase(opts PassphraseOptions) (Passphrase, error) {
+	if opts.Words < 1 {
+		return Passphrase{}, errors.New("word count must be at least 1")
+	}
+	list := opts.Wordlist
+	if list == nil {
+		list = DefaultWordlist()
+	}
+	if len(list) < 2 {
+		return Passphrase{}, errors.New("wordlist must contain at least two distinct words")
+	}
+
+	entropy := float64(opts.Words) * math.Log2(float64(len(list)))
+	words := make([]string, opts.Words)
+	for i := range words {
+		word := list[g.Intn(len(list))]
+		switch opts.Capitalization {
+		case "", LowerCaseWords:
+			word = strings.ToLower(word)
+		case TitleCaseWords:
+			word = titleCase(word)
+		case UpperCaseWords:
+			word = strings.ToUpper(word)
+		case RandomCaseWords:
+			if g.Intn(2) == 0 {
+				word = titleCase(word)
+			} else {
+				word = strings.ToLower(word)
+			}
+			entropy++
+		default:
+			return Passphrase{}, fmt.Errorf("unknown capitalization %q", opts.Capitalization)
+		}
+		words[i] = word
+	}
+
+	if opts.AddDigit {
+		i := g.Intn(len(words))
+		words[i] += string(Numbers[g.Intn(len(Numbers))])
+		entropy += math.Log2(float64(len(Numbers) * len(words)))
+	}
+	if opts.AddSymbol {
+		i := g.Intn(len(words))
+		words[i] += string(Special[g.Intn(len(Special))])
+		entropy += math.Log2(float64(len(Special) * len(words)))
+	}
+
+	return Passphrase{
+		Phrase:  strings.Join(words, opts.Separator),
+		Entropy: entropy,
+	}, nil
+}
+
+func titleCase(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
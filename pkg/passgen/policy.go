@@ -0,0 +1,251 @@
+package passgen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// AmbiguousChars are characters that are easily confused when read aloud or
+// copied by hand. Add them to Policy.Exclude to leave them out.
+const AmbiguousChars = "0O1lI"
+
+// Policy describes how a password is built.
+//
+// Every enabled character class is guaranteed to appear at least once, or at
+// least Min times when a minimum is set. Custom characters form their own class.
+type Policy struct {
+	// Name is shown to users; Key identifies built-in presets for GetKey.
+	Name   string `json:"name"`
+	Key    string `json:"key,omitempty"`
+	Length int    `json:"length"`
+
+	UseLower   bool `json:"use_lower"`
+	UseUpper   bool `json:"use_upper"`
+	UseNumbers bool `json:"use_numbers"`
+	UseSpecial bool `json:"use_special"`
+	UseHex     bool `json:"use_hex"`
+
+	MinLower   int `json:"min_lower,omitempty"`
+	MinUpper   int `json:"min_upper,omitempty"`
+	MinNumbers int `json:"min_numbers,omitempty"`
+	MinSpecial int `json:"min_special,omitempty"`
+
+	// Custom is an extra character set, e.g. "äöü" or "€£¥".
+	Custom string `json:"custom,omitempty"`
+	// Exclude lists characters that must never be used.
+	Exclude string `json:"exclude,omitempty"`
+	// NoRepeat forbids using any character more than once.
+	NoRepeat bool `json:"no_repeat,omitempty"`
+	// StartWithLetter forces the first character to be a letter.
+	StartWithLetter bool `json:"start_with_letter,omitempty"`
+}
+
+// DefaultPolicies are the built-in presets, in display order. Their keys are
+// the strengths accepted by GetKey.
+var DefaultPolicies = []Policy{
+	{Name: "Memorable Passwords", Key: MemorablePassword, Length: 10, UseLower: true, UseUpper: true, UseNumbers: true},
+	{Name: "Strong Passwords", Key: StrongPassword, Length: 15, UseLower: true, UseUpper: true, UseNumbers: true, UseSpecial: true},
+	{Name: "Fort Knox Passwords", Key: FortKnoxPassword, Length: 30, UseLower: true, UseUpper: true, UseNumbers: true, UseSpecial: true},
+	{Name: "CodeIgniter Encryption Keys", Key: CodeIgniterKey, Length: 32, UseLower: true, UseUpper: true, UseNumbers: true},
+	{Name: "160-bit WPA Key", Key: WPA160Key, Length: 20, UseLower: true, UseUpper: true, UseNumbers: true, UseSpecial: true},
+	{Name: "504-bit WPA Key", Key: WPA504Key, Length: 63, UseLower: true, UseUpper: true, UseNumbers: true, UseSpecial: true},
+	{Name: "64-bit WEP Keys", Key: WEP64Key, Length: 5, UseHex: true},
+	{Name: "128-bit WEP Keys", Key: WEP128Key, Length: 13, UseHex: true},
+	{Name: "152-bit WEP Keys", Key: WEP152Key, Length: 16, UseHex: true},
+	{Name: "256-bit WEP Keys", Key: WEP256Key, Length: 29, UseHex: true},
+}
+
+// LookupPolicy returns the default policy with the given key.
+func LookupPolicy(key string) (Policy, bool) {
+	for _, p := range DefaultPolicies {
+		if p.Key == key {
+			return p, true
+		}
+	}
+	return Policy{}, false
+}
+
+// charClass is a set of characters with the number of times it must appear.
+type charClass struct {
+	chars []rune
+	min   int
+}
+
+// classes returns the enabled character classes with excluded characters removed.
+func (p Policy) classes() []charClass {
+	var classes []charClass
+	add := func(enabled bool, chars string, min int) {
+		if !enabled {
+			return
+		}
+		if min < 1 {
+			min = 1
+		}
+		classes = append(classes, charClass{chars: p.filter(chars), min: min})
+	}
+
+	add(p.UseLower, LowerCase, p.MinLower)
+	add(p.UseUpper, UpperCase, p.MinUpper)
+	add(p.UseNumbers, Numbers, p.MinNumbers)
+	add(p.UseSpecial, Special, p.MinSpecial)
+	add(p.UseHex, HexChars, 1)
+	add(p.Custom != "", p.Custom, 1)
+	return classes
+}
+
+// filter returns the distinct characters of chars that are not excluded.
+func (p Policy) filter(chars string) []rune {
+	var out []rune
+	seen := map[rune]bool{}
+	for _, c := range chars {
+		if seen[c] || strings.ContainsRune(p.Exclude, c) {
+			continue
+		}
+		seen[c] = true
+		out = append(out, c)
+	}
+	return out
+}
+
+// Charset returns every character the policy may produce.
+func (p Policy) Charset() []rune {
+	var all strings.Builder
+	for _, c := range p.classes() {
+		all.WriteString(string(c.chars))
+	}
+	return p.filter(all.String())
+}
+
+// Validate reports whether a password satisfying p can be generated.
+func (p Policy) Validate() error {
+	if p.Length < 1 {
+		return errors.New("length must be at least 1")
+	}
+	if p.MinLower > 0 && !p.UseLower || p.MinUpper > 0 && !p.UseUpper ||
+		p.MinNumbers > 0 && !p.UseNumbers || p.MinSpecial > 0 && !p.UseSpecial {
+		return errors.New("a minimum count is set for a disabled character class")
+	}
+
+	classes := p.classes()
+	if len(classes) == 0 {
+		return errors.New("no character class selected")
+	}
+
+	required := 0
+	for _, c := range classes {
+		if len(c.chars) == 0 {
+			return errors.New("a character class is empty after exclusions")
+		}
+		if p.NoRepeat && c.min > len(c.chars) {
+			return fmt.Errorf("cannot pick %d distinct characters from %q", c.min, string(c.chars))
+		}
+		required += c.min
+	}
+
+	if p.StartWithLetter {
+		if len(letters(p.Charset())) == 0 {
+			return errors.New("must start with a letter but no letters are allowed")
+		}
+		// Without a letter class the letter has to be added on top.
+		if !p.UseLower && !p.UseUpper {
+			required++
+		}
+	}
+	if required > p.Length {
+		return fmt.Errorf("length %d is shorter than the %d required characters", p.Length, required)
+	}
+	if p.NoRepeat && p.Length > len(p.Charset()) {
+		return fmt.Errorf("length %d exceeds the %d distinct characters available", p.Length, len(p.Charset()))
+	}
+	return nil
+}
+
+// Generate returns a random password satisfying p.
+func Generate(p Policy) (string, error) {
+	return defaultGenerator.Generate(p)
+}
+
+// Generate returns a random password satisfying p drawn from g.
+func (g *Generator) Generate(p Policy) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	var password []rune
+	used := map[rune]bool{}
+	pick := func(chars []rune) error {
+		if p.NoRepeat {
+			chars = without(chars, used)
+		}
+		// Overlapping classes (hex and numbers) can run out of distinct characters.
+		if len(chars) == 0 {
+			return errors.New("not enough distinct characters for this policy")
+		}
+		c := chars[g.Intn(len(chars))]
+		used[c] = true
+		password = append(password, c)
+		return nil
+	}
+
+	for _, class := range p.classes() {
+		for i := 0; i < class.min; i++ {
+			if err := pick(class.chars); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	charset := p.Charset()
+	if p.StartWithLetter && len(letters(password)) == 0 {
+		if err := pick(letters(charset)); err != nil {
+			return "", err
+		}
+	}
+	for len(password) < p.Length {
+		if err := pick(charset); err != nil {
+			return "", err
+		}
+	}
+
+	g.shuffle(password)
+
+	if p.StartWithLetter && !unicode.IsLetter(password[0]) {
+		positions := letterPositions(password)
+		i := positions[g.Intn(len(positions))]
+		password[0], password[i] = password[i], password[0]
+	}
+
+	return string(password), nil
+}
+
+func letters(chars []rune) []rune {
+	var out []rune
+	for _, c := range chars {
+		if unicode.IsLetter(c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func letterPositions(chars []rune) []int {
+	var out []int
+	for i, c := range chars {
+		if unicode.IsLetter(c) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func without(chars []rune, used map[rune]bool) []rune {
+	var out []rune
+	for _, c := range chars {
+		if !used[c] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package passgen
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Generator picks characters and words uniformly at random from a byte
+// source. Indexes are drawn by rejection sampling: bytes that would make some
+// characters more likely than others are discarded instead of being reduced
+// modulo the charset size. A Generator is safe for concurrent use.
+type Generator struct {
+	mu  sync.Mutex
+	src io.Reader
+	buf [256]byte
+	pos int
+	end int
+}
+
+// defaultGenerator backs the package level functions.
+var defaultGenerator = NewGenerator(rand.Reader)
+
+// NewGenerator returns a Generator reading from src, which should be a
+// cryptographically secure source such as crypto/rand.Reader.
+func NewGenerator(src io.Reader) *Generator {
+	return &Generator{src: src}
+}
+
+// NewSeededGenerator returns a Generator whose output is fully determined by
+// seed. It exists for tests and reproducible fixtures; never use it for real
+// credentials.
+func NewSeededGenerator(seed uint64) *Generator {
+	return NewGenerator(&seededReader{seed: seed})
+}
+
+// readByte returns the next byte from the buffered source. The caller must hold g.mu.
+func (g *Generator) readByte() byte {
+	if g.pos == g.end {
+		n, err := io.ReadFull(g.src, g.buf[:])
+		if err != nil {
+			// There is no safe fallback when the system random source fails.
+			panic("passgen: reading random source: " + err.Error())
+		}
+		g.pos, g.end = 0, n
+	}
+	b := g.buf[g.pos]
+	g.pos++
+	return b
+}
+
+// Intn returns a uniformly distributed int in [0, n). It panics if n <= 0 or
+// n does not fit in 32 bits.
+func (g *Generator) Intn(n int) int {
+	if n <= 0 || uint64(n) > 1<<32 {
+		panic("passgen: Intn called with n out of range")
+	}
+	if n == 1 {
+		return 0
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// A single byte covers every charset; wider draws are only needed for wordlists.
+	if n <= 1<<8 {
+		limit := 1<<8 - 1<<8%n
+		for {
+			if v := int(g.readByte()); v < limit {
+				return v % n
+			}
+		}
+	}
+
+	limit := uint64(1<<32) - uint64(1<<32)%uint64(n)
+	for {
+		var b [4]byte
+		for i := range b {
+			b[i] = g.readByte()
+		}
+		if v := uint64(binary.BigEndian.Uint32(b[:])); v < limit {
+			return int(v % uint64(n))
+		}
+	}
+}
+
+// Float64 returns a uniformly distributed float64 in [0, 1) with 53 bits of precision.
+func (g *Generator) Float64() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var b [8]byte
+	for i := range b {
+		b[i] = g.readByte()
+	}
+	return float64(binary.BigEndian.Uint64(b[:])>>11) / (1 << 53)
+}
+
+// KeyGen generates a random key of the specified length from the selected character classes.
+func (g *Generator) KeyGen(length int, useLowerCase, useUpperCase, useNumbers, useSpecial, useHex bool) string {
+	var chars string
+	if useLowerCase {
+		chars += LowerCase
+	}
+	if useUpperCase {
+		chars += UpperCase
+	}
+	if useNumbers {
+		chars += Numbers
+	}
+	if useSpecial {
+		chars += Special
+	}
+	if useHex {
+		chars += HexChars
+	}
+	if chars == "" {
+		return ""
+	}
+
+	var key strings.Builder
+	key.Grow(length)
+	for i := 0; i < length; i++ {
+		key.WriteByte(chars[g.Intn(len(chars))])
+	}
+	return key.String()
+}
+
+// shuffle performs an in-place Fisher-Yates shuffle.
+func (g *Generator) shuffle(chars []rune) {
+	for i := len(chars) - 1; i > 0; i-- {
+		j := g.Intn(i + 1)
+		chars[i], chars[j] = chars[j], chars[i]
+	}
+}
+
+// seededReader is a deterministic stream of SHA-256(seed || counter) blocks.
+type seededReader struct {
+	seed    uint64
+	counter uint64
+	block   []byte
+}
+
+func (r *seededReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.block) == 0 {
+			var in [16]byte
+			binary.BigEndian.PutUint64(in[:8], r.seed)
+			binary.BigEndian.PutUint64(in[8:], r.counter)
+			sum := sha256.Sum256(in[:])
+			r.block = sum[:]
+			r.counter++
+		}
+		c := copy(p[n:], r.block)
+		r.block = r.block[c:]
+		n += c
+	}
+	return n, nil
+}
@@ -0,0 +1,143 @@
+package passgen
+
+import (
+	"crypto/rand"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// chiSquareLimit approximates the chi-square value with df degrees of
+// freedom that a uniform sample exceeds with probability 0.001, using the
+// Wilson-Hilferty transformation.
+func chiSquareLimit(df int) float64 {
+	const z = 3.09
+	k := float64(df)
+	v := 1 - 2/(9*k) + z*math.Sqrt(2/(9*k))
+	return k * v * v * v
+}
+
+func TestIntnChiSquare(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     int
+		draws int
+		seed  uint64
+	}{
+		{"power of two", 64, 1e6, 1},
+		{"charset", len(LowerCase + UpperCase + Numbers), 1e6, 2},
+		{"one byte", 255, 1e6, 3},
+		{"two bytes", 257, 1e6, 4},
+		{"wordlist", 7776, 1e7, 5},
+		{"three bytes", 1<<16 + 1, 1e7, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.draws > 1e6 && testing.Short() {
+				t.Skipf("skipping %d draws in short mode", tt.draws)
+			}
+			g := NewSeededGenerator(tt.seed)
+			counts := make([]int, tt.n)
+			for i := 0; i < tt.draws; i++ {
+				counts[g.Intn(tt.n)]++
+			}
+			expected := float64(tt.draws) / float64(tt.n)
+			var chi2 float64
+			for _, c := range counts {
+				d := float64(c) - expected
+				chi2 += d * d / expected
+			}
+			if limit := chiSquareLimit(tt.n - 1); chi2 > limit {
+				t.Errorf("Intn(%d): chi-square %.1f exceeds %.1f", tt.n, chi2, limit)
+			}
+		})
+	}
+}
+
+// failingReader fails every read, so a Generator using it panics if it ever
+// needs random bytes.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("no randomness")
+}
+
+func TestIntnOne(t *testing.T) {
+	g := NewGenerator(failingReader{})
+	for i := 0; i < 10; i++ {
+		if v := g.Intn(1); v != 0 {
+			t.Fatalf("Intn(1) = %d, want 0", v)
+		}
+	}
+}
+
+func TestIntnNonPowerOfTwo(t *testing.T) {
+	tests := []struct {
+		n     int
+		draws int
+	}{
+		{3, 300},
+		{10, 1000},
+		{62, 10000},
+		{1000, 100000},
+		{1<<32 - 1, 1000},
+	}
+	g := NewSeededGenerator(42)
+	for _, tt := range tests {
+		seen := map[int]bool{}
+		for i := 0; i < tt.draws; i++ {
+			v := g.Intn(tt.n)
+			if v < 0 || v >= tt.n {
+				t.Fatalf("Intn(%d) = %d, out of range", tt.n, v)
+			}
+			seen[v] = true
+		}
+		if tt.n <= 1000 && len(seen) != tt.n {
+			t.Errorf("Intn(%d) drew %d of %d values in %d draws", tt.n, len(seen), tt.n, tt.draws)
+		}
+	}
+}
+
+func TestIntnPanics(t *testing.T) {
+	for _, n := range []int{0, -1, 1<<32 + 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Intn(%d) did not panic", n)
+				}
+			}()
+			NewSeededGenerator(1).Intn(n)
+		}()
+	}
+}
+
+func TestSeededGeneratorIsDeterministic(t *testing.T) {
+	a, b := NewSeededGenerator(7), NewSeededGenerator(7)
+	for i := 0; i < 100; i++ {
+		if x, y := a.Intn(7776), b.Intn(7776); x != y {
+			t.Fatalf("draw %d: %d != %d", i, x, y)
+		}
+	}
+	if NewSeededGenerator(7).KeyGen(32, true, true, true, true, false) == NewSeededGenerator(8).KeyGen(32, true, true, true, true, false) {
+		t.Error("different seeds gave the same key")
+	}
+}
+
+func BenchmarkIntn(b *testing.B) {
+	g := NewGenerator(rand.Reader)
+	for _, n := range []int{62, 7776} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g.Intn(n)
+			}
+		})
+	}
+}
+
+func BenchmarkKeyGen(b *testing.B) {
+	g := NewGenerator(rand.Reader)
+	for i := 0; i < b.N; i++ {
+		g.KeyGen(32, true, true, true, true, false)
+	}
+}
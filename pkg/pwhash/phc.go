@@ -0,0 +1,260 @@
+package pwhash
+
+import (
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"joshu/pkg/bcryptx"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// b64 is the PHC string format's base64 flavour.
+var b64 = base64.RawStdEncoding
+
+// passlibB64 is passlib's adapted base64, unpadded with . in place of +.
+var passlibB64 = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789./").WithPadding(base64.NoPadding)
+
+// djangoPBKDF2Prefix starts Django's PBKDF2-SHA256 hashes, which are not
+// PHC strings.
+const djangoPBKDF2Prefix = "pbkdf2_sha256$"
+
+// Info describes a parsed password hash.
+type Info struct {
+	Algorithm Algorithm
+	// Version is the bcrypt prefix, such as "2b", or the Argon2 version.
+	Version string
+	Params  Params
+	// Salt and Checksum are the encoded salt and hash as they appear in the string.
+	Salt     string
+	Checksum string
+	// Format is "passlib" or "Django" for PBKDF2-SHA256 hashes in those
+	// forms, and empty otherwise.
+	Format string
+
+	salt, key []byte
+}
+
+// Detect returns the algorithm a hash was made with.
+func Detect(hash string) (Algorithm, error) {
+	hash = strings.TrimSpace(hash)
+	if strings.HasPrefix(hash, djangoPBKDF2Prefix) {
+		return PBKDF2SHA256, nil
+	}
+	if !strings.HasPrefix(hash, "$") {
+		return "", fmt.Errorf("not a PHC, bcrypt or Django hash, expected it to start with $ or %s", djangoPBKDF2Prefix)
+	}
+	id, _, _ := strings.Cut(hash[1:], "$")
+	switch id {
+	case "2a", "2b", "2y", "2", "2x":
+		return Bcrypt, nil
+	case string(Argon2id), string(Scrypt), string(PBKDF2SHA256):
+		return Algorithm(id), nil
+	case "argon2i", "argon2d":
+		return "", fmt.Errorf("%s hashes are not supported, only argon2id", id)
+	default:
+		return "", fmt.Errorf("unknown hash algorithm %q", id)
+	}
+}
+
+// Inspect parses hash, detecting its algorithm. Parameters outside the
+// limits accepted by Params.Validate are rejected.
+func Inspect(hash string) (Info, error) {
+	hash = strings.TrimSpace(hash)
+	alg, err := Detect(hash)
+	if err != nil {
+		return Info{}, err
+	}
+
+	if alg == Bcrypt {
+		b, err := bcryptx.Inspect(hash)
+		if err != nil {
+			return Info{}, err
+		}
+		return Info{Algorithm: Bcrypt, Version: b.Prefix, Params: Params{Cost: b.Cost}, Salt: b.Salt, Checksum: b.Checksum}, nil
+	}
+
+	// passlib gives the rounds as a bare number rather than i=<rounds>.
+	params, _, _ := strings.Cut(strings.TrimPrefix(hash, "$pbkdf2-sha256$"), "$")
+	if strings.HasPrefix(hash, djangoPBKDF2Prefix) || alg == PBKDF2SHA256 && !strings.Contains(params, "=") {
+		return inspectPBKDF2Variant(hash)
+	}
+
+	// $id[$v=version]$params$salt$hash
+	fields := strings.Split(hash, "$")[2:]
+	info := Info{Algorithm: alg}
+	if len(fields) == 4 && strings.HasPrefix(fields[0], "v=") {
+		info.Version = strings.TrimPrefix(fields[0], "v=")
+		fields = fields[1:]
+	}
+	if len(fields) != 3 {
+		return Info{}, fmt.Errorf("malformed %s hash, expected $%s$<params>$<salt>$<hash>", alg, alg)
+	}
+
+	values, err := parseParams(fields[0])
+	if err != nil {
+		return Info{}, err
+	}
+	if info.salt, err = b64.DecodeString(fields[1]); err != nil {
+		return Info{}, fmt.Errorf("invalid salt: %v", err)
+	}
+	if info.key, err = b64.DecodeString(fields[2]); err != nil {
+		return Info{}, fmt.Errorf("invalid hash: %v", err)
+	}
+	info.Salt, info.Checksum = fields[1], fields[2]
+	info.Params.SaltLength, info.Params.KeyLength = len(info.salt), len(info.key)
+
+	var names []string
+	switch alg {
+	case Argon2id:
+		if info.Version != strconv.Itoa(argon2.Version) {
+			return Info{}, fmt.Errorf("unsupported argon2 version %q, expected %d", info.Version, argon2.Version)
+		}
+		names = []string{"m", "t", "p"}
+		info.Params.Memory, info.Params.Iterations, info.Params.Parallelism = values["m"], values["t"], values["p"]
+	case Scrypt:
+		names = []string{"ln", "r", "p"}
+		info.Params.LogN, info.Params.R, info.Params.Parallelism = values["ln"], values["r"], values["p"]
+	case PBKDF2SHA256:
+		names = []string{"i"}
+		info.Params.Iterations = values["i"]
+	}
+	if alg != Argon2id && info.Version != "" {
+		return Info{}, fmt.Errorf("unexpected version in %s hash", alg)
+	}
+	if err := expectParams(values, names); err != nil {
+		return Info{}, err
+	}
+	if err := info.Params.Validate(alg); err != nil {
+		return Info{}, err
+	}
+	return info, nil
+}
+
+// inspectPBKDF2Variant parses the PBKDF2-SHA256 hashes of passlib,
+// $pbkdf2-sha256$<rounds>$<salt>$<hash> in passlibB64, and of Django,
+// pbkdf2_sha256$<rounds>$<salt>$<hash> with the salt used as text and the
+// hash in padded base64.
+func inspectPBKDF2Variant(hash string) (Info, error) {
+	info := Info{Algorithm: PBKDF2SHA256, Format: "passlib"}
+	rest := strings.TrimPrefix(hash, "$pbkdf2-sha256$")
+	if strings.HasPrefix(hash, djangoPBKDF2Prefix) {
+		info.Format, rest = "Django", strings.TrimPrefix(hash, djangoPBKDF2Prefix)
+	}
+	fields := strings.Split(rest, "$")
+	if len(fields) != 3 {
+		return Info{}, fmt.Errorf("malformed %s pbkdf2-sha256 hash, expected <rounds>$<salt>$<hash> after the prefix", info.Format)
+	}
+	rounds, err := strconv.Atoi(fields[0])
+	if err != nil || rounds < 0 {
+		return Info{}, fmt.Errorf("rounds %q must be a non-negative number", fields[0])
+	}
+
+	if info.Format == "Django" {
+		info.salt = []byte(fields[1])
+		info.key, err = base64.StdEncoding.DecodeString(fields[2])
+	} else {
+		if info.salt, err = passlibB64.DecodeString(fields[1]); err != nil {
+			return Info{}, fmt.Errorf("invalid salt: %v", err)
+		}
+		info.key, err = passlibB64.DecodeString(fields[2])
+	}
+	if err != nil {
+		return Info{}, fmt.Errorf("invalid hash: %v", err)
+	}
+	info.Salt, info.Checksum = fields[1], fields[2]
+	info.Params = Params{Iterations: rounds, SaltLength: len(info.salt), KeyLength: len(info.key)}
+	if err := info.Params.Validate(PBKDF2SHA256); err != nil {
+		return Info{}, err
+	}
+	return info, nil
+}
+
+// Warnings lists parameters weaker than the OWASP minimums, or for bcrypt a
+// cost below bcryptx.MinPolicyCost.
+func (i Info) Warnings() []string {
+	var warnings []string
+	p := i.Params
+	switch i.Algorithm {
+	case Bcrypt:
+		return bcryptx.Info{Prefix: i.Version, Cost: p.Cost}.Warnings(bcryptx.MinPolicyCost)
+	case Argon2id:
+		if p.Memory < 19456 || p.Iterations < 2 {
+			warnings = append(warnings, "argon2id parameters are below the minimum of m=19456 (19 MiB), t=2")
+		}
+	case Scrypt:
+		if p.LogN < 17 {
+			warnings = append(warnings, "scrypt ln is below the minimum of 17 (N=2^17)")
+		}
+	case PBKDF2SHA256:
+		if p.Iterations < 600000 {
+			warnings = append(warnings, "pbkdf2-sha256 iterations are below the minimum of 600000")
+		}
+	}
+	if p.SaltLength < 16 {
+		warnings = append(warnings, fmt.Sprintf("salt is %d bytes, 16 or more is recommended", p.SaltLength))
+	}
+	return warnings
+}
+
+func encode(alg Algorithm, p Params, salt, key []byte) string {
+	var params string
+	switch alg {
+	case Argon2id:
+		params = fmt.Sprintf("v=%d$m=%d,t=%d,p=%d", argon2.Version, p.Memory, p.Iterations, p.Parallelism)
+	case Scrypt:
+		params = fmt.Sprintf("ln=%d,r=%d,p=%d", p.LogN, p.R, p.Parallelism)
+	case PBKDF2SHA256:
+		params = fmt.Sprintf("i=%d", p.Iterations)
+	}
+	return fmt.Sprintf("$%s$%s$%s$%s", alg, params, b64.EncodeToString(salt), b64.EncodeToString(key))
+}
+
+// parseParams parses a PHC parameter list such as "m=65536,t=3,p=4".
+func parseParams(s string) (map[string]int, error) {
+	values := map[string]int{}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("malformed parameter %q", pair)
+		}
+		if _, dup := values[name]; dup {
+			return nil, fmt.Errorf("duplicate parameter %q", name)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("parameter %s=%q must be a non-negative number", name, value)
+		}
+		values[name] = n
+	}
+	return values, nil
+}
+
+func expectParams(values map[string]int, names []string) error {
+	for _, name := range names {
+		if _, ok := values[name]; !ok {
+			return fmt.Errorf("missing parameter %q", name)
+		}
+	}
+	if len(values) != len(names) {
+		var extra []string
+		for name := range values {
+			if !contains(names, name) {
+				extra = append(extra, name)
+			}
+		}
+		sort.Strings(extra)
+		return fmt.Errorf("unexpected parameters %s", strings.Join(extra, ", "))
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
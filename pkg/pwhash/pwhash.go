@@ -0,0 +1,269 @@
+// Package pwhash hashes and verifies passwords with bcrypt, Argon2id, scrypt
+// and PBKDF2-SHA256. Hashes other than bcrypt are encoded as PHC strings,
+// such as $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>, with unpadded
+// standard base64 for the salt and hash. PBKDF2-SHA256 hashes are also read
+// in the forms written by passlib and Django.
+package pwhash
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"joshu/pkg/bcryptx"
+	"strings"
+)
+
+// Algorithm identifies a password hashing algorithm by its PHC identifier.
+type Algorithm string
+
+// Supported algorithms.
+const (
+	Bcrypt       Algorithm = "bcrypt"
+	Argon2id     Algorithm = "argon2id"
+	Scrypt       Algorithm = "scrypt"
+	PBKDF2SHA256 Algorithm = "pbkdf2-sha256"
+)
+
+// Algorithms lists the supported algorithms in display order.
+var Algorithms = []Algorithm{Bcrypt, Argon2id, Scrypt, PBKDF2SHA256}
+
+func (a Algorithm) valid() bool {
+	for _, alg := range Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrMismatch is returned by Verify when the password does not match the hash.
+var ErrMismatch = errors.New("password does not match hash")
+
+// Parameter limits. The upper bounds keep a pasted hash from exhausting
+// memory or hanging the app during Verify.
+const (
+	MinSaltLength = 8
+	MaxSaltLength = 64
+	MinKeyLength  = 16
+	MaxKeyLength  = 64
+
+	MaxArgon2Memory     = 1 << 21 // KiB, 2 GiB
+	MaxArgon2Iterations = 64
+	MaxParallelism      = 64
+	MaxScryptLogN       = 24
+	MaxScryptMemory     = 1 << 30 // bytes, 128 * r * 2^ln
+	MaxPBKDF2Iterations = 10000000
+)
+
+// MaxVerifyArgon2Memory is the memory in KiB that Verify lets an Argon2id
+// hash use, 256 MiB, well above the usual 19 to 64 MiB. Hashes up to
+// MaxArgon2Memory, such as those following RFC 9106's 2 GiB recommendation,
+// are verified with VerifyMemory once the user agrees.
+const MaxVerifyArgon2Memory = 1 << 18
+
+// MemoryLimitError is returned by Verify for an Argon2id hash that needs
+// more memory than allowed.
+type MemoryLimitError struct {
+	// Memory is what the hash needs and Limit what was allowed, in KiB.
+	Memory, Limit int
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("the hash needs %d MiB of memory to verify, more than the %d MiB allowed", e.Memory>>10, e.Limit>>10)
+}
+
+// Params holds the tunable parameters of every algorithm. Each algorithm
+// only reads the fields it uses:
+//
+//	bcrypt         Cost
+//	argon2id       Memory, Iterations, Parallelism, SaltLength, KeyLength
+//	scrypt         LogN, R, Parallelism, SaltLength, KeyLength
+//	pbkdf2-sha256  Iterations, SaltLength, KeyLength
+type Params struct {
+	Cost int
+	// Memory is in KiB.
+	Memory      int
+	Iterations  int
+	Parallelism int
+	// LogN is log2 of the scrypt CPU/memory cost N.
+	LogN       int
+	R          int
+	SaltLength int
+	KeyLength  int
+}
+
+// DefaultParams returns the parameters used for new hashes, following the
+// OWASP password storage recommendations.
+func DefaultParams(alg Algorithm) Params {
+	switch alg {
+	case Argon2id:
+		return Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 32}
+	case Scrypt:
+		return Params{LogN: 17, R: 8, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	case PBKDF2SHA256:
+		return Params{Iterations: 600000, SaltLength: 16, KeyLength: 32}
+	default:
+		return Params{Cost: bcryptx.DefaultCost}
+	}
+}
+
+// Validate reports whether p is usable with alg.
+func (p Params) Validate(alg Algorithm) error {
+	if !alg.valid() {
+		return fmt.Errorf("unknown algorithm %q", alg)
+	}
+	if alg == Bcrypt {
+		return bcryptx.ValidateCost(p.Cost)
+	}
+
+	if p.SaltLength < MinSaltLength || p.SaltLength > MaxSaltLength {
+		return fmt.Errorf("salt length must be between %d and %d bytes", MinSaltLength, MaxSaltLength)
+	}
+	if p.KeyLength < MinKeyLength || p.KeyLength > MaxKeyLength {
+		return fmt.Errorf("key length must be between %d and %d bytes", MinKeyLength, MaxKeyLength)
+	}
+
+	switch alg {
+	case Argon2id:
+		if p.Iterations < 1 || p.Iterations > MaxArgon2Iterations {
+			return fmt.Errorf("iterations must be between 1 and %d", MaxArgon2Iterations)
+		}
+		if p.Parallelism < 1 || p.Parallelism > MaxParallelism {
+			return fmt.Errorf("parallelism must be between 1 and %d", MaxParallelism)
+		}
+		if p.Memory < 8*p.Parallelism || p.Memory > MaxArgon2Memory {
+			return fmt.Errorf("memory must be between %d and %d KiB", 8*p.Parallelism, MaxArgon2Memory)
+		}
+	case Scrypt:
+		if p.LogN < 1 || p.LogN > MaxScryptLogN {
+			return fmt.Errorf("ln must be between 1 and %d", MaxScryptLogN)
+		}
+		if p.R < 1 || p.Parallelism < 1 || p.Parallelism > MaxParallelism {
+			return fmt.Errorf("r must be positive and parallelism between 1 and %d", MaxParallelism)
+		}
+		// 128 * r * 2^ln bytes, compared without overflowing for huge r.
+		if p.R > MaxScryptMemory>>(7+p.LogN) {
+			return fmt.Errorf("scrypt would need more than %d MiB of memory", MaxScryptMemory>>20)
+		}
+	case PBKDF2SHA256:
+		if p.Iterations < 1 || p.Iterations > MaxPBKDF2Iterations {
+			return fmt.Errorf("iterations must be between 1 and %d", MaxPBKDF2Iterations)
+		}
+	}
+	return nil
+}
+
+// Hash returns the hash of password with alg and a fresh random salt.
+func Hash(alg Algorithm, password string, p Params) (string, error) {
+	if err := p.Validate(alg); err != nil {
+		return "", err
+	}
+	if alg == Bcrypt {
+		return bcryptx.Hash(password, p.Cost)
+	}
+
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := derive(alg, []byte(password), salt, p)
+	if err != nil {
+		return "", err
+	}
+	return encode(alg, p, salt, key), nil
+}
+
+// Verify returns nil if password matches hash, detecting the algorithm from
+// the hash. It returns ErrMismatch when the password is wrong, or another
+// error when the hash cannot be parsed. bcrypt errors other than a mismatch
+// are passed through and can be described with bcryptx.Explain. Argon2id
+// hashes needing more than MaxVerifyArgon2Memory are refused with a
+// *MemoryLimitError.
+func Verify(hash, password string) error {
+	return VerifyMemory(hash, password, MaxVerifyArgon2Memory)
+}
+
+// VerifyMemory is Verify allowing Argon2id hashes to use up to maxMemory
+// KiB. Inspect still rejects hashes needing more than MaxArgon2Memory.
+func VerifyMemory(hash, password string, maxMemory int) error {
+	info, err := Inspect(hash)
+	if err != nil {
+		return err
+	}
+	if info.Algorithm == Argon2id && info.Params.Memory > maxMemory {
+		return &MemoryLimitError{Memory: info.Params.Memory, Limit: maxMemory}
+	}
+	if info.Algorithm == Bcrypt {
+		err := bcryptx.Verify(strings.TrimSpace(hash), password)
+		if errors.Is(err, bcryptx.ErrMismatch) {
+			return ErrMismatch
+		}
+		return err
+	}
+
+	key, err := derive(info.Algorithm, []byte(password), info.salt, info.Params)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(key, info.key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// HashContext is Hash, returning ctx.Err() as soon as ctx is cancelled. The
+// hash cannot be interrupted, so it still finishes in the background and its
+// result is discarded.
+func HashContext(ctx context.Context, alg Algorithm, password string, p Params) (string, error) {
+	type result struct {
+		hash string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		hash, err := Hash(alg, password, p)
+		done <- result{hash, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.hash, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// VerifyContext is VerifyMemory, returning ctx.Err() as soon as ctx is
+// cancelled. See HashContext.
+func VerifyContext(ctx context.Context, hash, password string, maxMemory int) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- VerifyMemory(hash, password, maxMemory)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func derive(alg Algorithm, password, salt []byte, p Params) ([]byte, error) {
+	switch alg {
+	case Argon2id:
+		return argon2.IDKey(password, salt, uint32(p.Iterations), uint32(p.Memory), uint8(p.Parallelism), uint32(p.KeyLength)), nil
+	case Scrypt:
+		return scrypt.Key(password, salt, 1<<p.LogN, p.R, p.Parallelism, p.KeyLength)
+	case PBKDF2SHA256:
+		return pbkdf2.Key(password, salt, p.Iterations, p.KeyLength, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", alg)
+	}
+}
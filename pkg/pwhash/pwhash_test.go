@@ -0,0 +1,201 @@
+package pwhash
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fastParams are valid but cheap parameters for every algorithm.
+var fastParams = map[Algorithm]Params{
+	Bcrypt:       {Cost: 4},
+	Argon2id:     {Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	Scrypt:       {LogN: 4, R: 8, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	PBKDF2SHA256: {Iterations: 10, SaltLength: 16, KeyLength: 32},
+}
+
+func TestHashVerifyRoundTrip(t *testing.T) {
+	for _, alg := range Algorithms {
+		t.Run(string(alg), func(t *testing.T) {
+			p := fastParams[alg]
+			hash, err := Hash(alg, "pässword", p)
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if got, err := Detect(hash); err != nil || got != alg {
+				t.Errorf("Detect() = %q, %v, want %q", got, err, alg)
+			}
+			info, err := Inspect(hash)
+			if err != nil {
+				t.Fatalf("Inspect: %v", err)
+			}
+			if info.Params != p {
+				t.Errorf("Inspect() params = %+v, want %+v", info.Params, p)
+			}
+			if err := Verify(hash, "pässword"); err != nil {
+				t.Errorf("Verify with the right password: %v", err)
+			}
+			if err := Verify(hash, "password"); !errors.Is(err, ErrMismatch) {
+				t.Errorf("Verify with a wrong password = %v, want ErrMismatch", err)
+			}
+			other, err := Hash(alg, "pässword", p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other == hash {
+				t.Error("two hashes of the same password share a salt")
+			}
+		})
+	}
+}
+
+// TestVerifyKnownHashes checks hashes made by another implementation,
+// Python's hashlib, so the PHC, passlib and Django encodings match theirs.
+func TestVerifyKnownHashes(t *testing.T) {
+	tests := []struct {
+		name   string
+		hash   string
+		format string
+	}{
+		{"pbkdf2-sha256", "$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0c2FsdA$8nX7hwFEzIB8aPajJTYK8weHQc5Ngz0pFVAKvSu4jQA", ""},
+		{"scrypt", "$scrypt$ln=10,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$BVMRKqdiVYikKAaPR1wucsKUKvw4TuPLkdEYtoSHas4", ""},
+		{"passlib", "$pbkdf2-sha256$29000$c2FsdHNhbHRzYWx0c2FsdA$7xwbY5rCP.qJhnvJ80W3FI7hSRg8wNnl3S9rczjVuCk", "passlib"},
+		{"Django", "pbkdf2_sha256$1000$seasaltseasalt$GPdBehDGYyqd85KPeI9ps1RaSnu62T4M6Bgl2g4QMZI=", "Django"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if info, err := Inspect(tt.hash); err != nil || info.Format != tt.format {
+				t.Errorf("Inspect() format = %q, %v, want %q", info.Format, err, tt.format)
+			}
+			if err := Verify(tt.hash, "password"); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if err := Verify(tt.hash, "Password"); !errors.Is(err, ErrMismatch) {
+				t.Errorf("Verify with a wrong password = %v, want ErrMismatch", err)
+			}
+		})
+	}
+}
+
+func TestVerifyMemoryLimit(t *testing.T) {
+	hash, err := Hash(Argon2id, "password", fastParams[Argon2id])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var limitErr *MemoryLimitError
+	if err := VerifyMemory(hash, "password", 32); !errors.As(err, &limitErr) || limitErr.Memory != 64 || limitErr.Limit != 32 {
+		t.Errorf("VerifyMemory() below the hash's memory = %v, want a MemoryLimitError", err)
+	}
+	if err := VerifyMemory(hash, "password", 64); err != nil {
+		t.Errorf("VerifyMemory() at the hash's memory: %v", err)
+	}
+
+	// A pasted hash asking for 2 GiB is refused before any work is done.
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "8nX7hwFEzIB8aPajJTYK8weHQc5Ngz0pFVAKvSu4jQA"
+	large := "$argon2id$v=19$m=2097152,t=1,p=4$" + salt + "$" + key
+	if err := Verify(large, "password"); !errors.As(err, &limitErr) || limitErr.Limit != MaxVerifyArgon2Memory {
+		t.Errorf("Verify() of a 2 GiB hash = %v, want a MemoryLimitError", err)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "8nX7hwFEzIB8aPajJTYK8weHQc5Ngz0pFVAKvSu4jQA"
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr string
+	}{
+		{"argon2id", "$argon2id$v=19$m=65536,t=3,p=4$" + salt + "$" + key, ""},
+		{"surrounding space", "  $scrypt$ln=17,r=8,p=1$" + salt + "$" + key + "\n", ""},
+		{"not a hash", "hunter2", "expected it to start with $"},
+		{"argon2i", "$argon2i$v=19$m=65536,t=3,p=4$" + salt + "$" + key, "only argon2id"},
+		{"unknown algorithm", "$md5$" + salt, "unknown hash algorithm"},
+		{"argon2 version", "$argon2id$v=16$m=65536,t=3,p=4$" + salt + "$" + key, "unsupported argon2 version"},
+		{"missing field", "$pbkdf2-sha256$i=1000$" + salt, "malformed pbkdf2-sha256 hash"},
+		{"missing parameter", "$argon2id$v=19$m=65536,t=3$" + salt + "$" + key, `missing parameter "p"`},
+		{"extra parameter", "$pbkdf2-sha256$i=1000,x=1$" + salt + "$" + key, "unexpected parameters x"},
+		{"duplicate parameter", "$pbkdf2-sha256$i=1,i=2$" + salt + "$" + key, "duplicate parameter"},
+		{"negative parameter", "$pbkdf2-sha256$i=-1$" + salt + "$" + key, "non-negative number"},
+		{"bad salt", "$pbkdf2-sha256$i=1000$!!!$" + key, "invalid salt"},
+		{"short salt", "$pbkdf2-sha256$i=1000$c2FsdA$" + key, "salt length"},
+		{"memory bomb", "$argon2id$v=19$m=99999999,t=3,p=4$" + salt + "$" + key, "memory must be between"},
+		{"scrypt memory", "$scrypt$ln=24,r=64,p=1$" + salt + "$" + key, "more than 1024 MiB"},
+		{"unexpected version", "$scrypt$v=1$ln=17,r=8,p=1$" + salt + "$" + key, "unexpected version"},
+		{"passlib missing field", "$pbkdf2-sha256$29000$" + salt, "malformed passlib pbkdf2-sha256 hash"},
+		{"passlib bad salt", "$pbkdf2-sha256$29000$c2F+dA$" + key, "invalid salt"},
+		{"Django", "pbkdf2_sha256$870000$seasaltseasalt$GPdBehDGYyqd85KPeI9ps1RaSnu62T4M6Bgl2g4QMZI=", ""},
+		{"Django unpadded hash", "pbkdf2_sha256$1000$seasaltseasalt$" + key, "invalid hash"},
+		{"Django rounds", "pbkdf2_sha256$many$seasaltseasalt$" + key, "non-negative number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Inspect(tt.hash)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Inspect: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Inspect() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		alg  Algorithm
+		p    Params
+		ok   bool
+	}{
+		{"defaults bcrypt", Bcrypt, DefaultParams(Bcrypt), true},
+		{"defaults argon2id", Argon2id, DefaultParams(Argon2id), true},
+		{"defaults scrypt", Scrypt, DefaultParams(Scrypt), true},
+		{"defaults pbkdf2", PBKDF2SHA256, DefaultParams(PBKDF2SHA256), true},
+		{"unknown algorithm", "md5", Params{}, false},
+		{"bcrypt cost", Bcrypt, Params{Cost: 3}, false},
+		{"long key", PBKDF2SHA256, Params{Iterations: 1, SaltLength: 16, KeyLength: 65}, false},
+		{"argon2 memory below 8p", Argon2id, Params{Memory: 31, Iterations: 1, Parallelism: 4, SaltLength: 16, KeyLength: 32}, false},
+		{"scrypt r", Scrypt, Params{LogN: 10, R: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32}, false},
+		{"pbkdf2 iterations", PBKDF2SHA256, Params{Iterations: MaxPBKDF2Iterations + 1, SaltLength: 16, KeyLength: 32}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.p.Validate(tt.alg); (err == nil) != tt.ok {
+				t.Errorf("Validate() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     Info
+		warnings int
+	}{
+		{"strong argon2id", Info{Algorithm: Argon2id, Params: DefaultParams(Argon2id)}, 0},
+		{"weak argon2id", Info{Algorithm: Argon2id, Params: Params{Memory: 1024, Iterations: 1, SaltLength: 16}}, 1},
+		{"weak scrypt, short salt", Info{Algorithm: Scrypt, Params: Params{LogN: 14, SaltLength: 8}}, 2},
+		{"weak pbkdf2", Info{Algorithm: PBKDF2SHA256, Params: Params{Iterations: 1000, SaltLength: 16}}, 1},
+		{"weak bcrypt", Info{Algorithm: Bcrypt, Version: "2b", Params: Params{Cost: 8}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := tt.info.Warnings(); len(w) != tt.warnings {
+				t.Errorf("Warnings() = %q, want %d", w, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestHashContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := HashContext(ctx, Scrypt, "pw", DefaultParams(Scrypt)); !errors.Is(err, context.Canceled) {
+		t.Errorf("HashContext() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,183 @@
+package rsakeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"strings"
+)
+
+var (
+	// ErrPassphraseRequired is returned when a private key is encrypted and
+	// no passphrase was given.
+	ErrPassphraseRequired = errors.New("private key is encrypted, a passphrase is required")
+	// ErrIncorrectPassphrase is returned when a passphrase does not decrypt the key.
+	ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+)
+
+// CanEncrypt reports whether private keys in format can be passphrase
+// protected: PKCS8 as an encrypted PKCS#8 key (PBKDF2 and AES-256-CBC) and
+// OpenSSH with bcrypt-kdf and AES-256-CTR.
+func (f KeyFormat) CanEncrypt() bool {
+	return f == PKCS8 || f == OpenSSH
+}
+
+// IsEncrypted reports whether text is a passphrase protected private key.
+func IsEncrypted(text string) bool {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(text)))
+	if block == nil {
+		return false
+	}
+	switch block.Type {
+	case EncryptedPKCS8Type:
+		return true
+	case OpenSSHPrivateKeyType:
+		_, err := ssh.ParseRawPrivateKey(pem.EncodeToMemory(block))
+		var missing *ssh.PassphraseMissingError
+		return errors.As(err, &missing)
+	default:
+		return block.Headers["Proc-Type"] == "4,ENCRYPTED"
+	}
+}
+
+// EncryptPrivateKey encodes key in format protected by passphrase. An empty
+// passphrase gives the same result as EncodePrivateKey.
+func EncryptPrivateKey(key *rsa.PrivateKey, format KeyFormat, passphrase string) (string, error) {
+	if passphrase == "" {
+		return EncodePrivateKey(key, format)
+	}
+	switch format {
+	case PKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return "", err
+		}
+		encrypted, err := encryptPKCS8(der, passphrase)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: EncryptedPKCS8Type, Bytes: encrypted})), nil
+	case OpenSSH:
+		block, err := ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(block)), nil
+	default:
+		return "", fmt.Errorf("%s keys cannot be encrypted, use %s or %s", format.Label(), PKCS8.Label(), OpenSSH.Label())
+	}
+}
+
+// ParsePrivateKeyWithPassphrase reads a private key like ParsePrivateKey,
+// decrypting it with passphrase if it is encrypted. Besides the formats
+// written by EncryptPrivateKey it reads legacy OpenSSL encrypted PKCS#1 PEM.
+// The passphrase is ignored for unencrypted keys.
+func ParsePrivateKeyWithPassphrase(text, passphrase string) (*rsa.PrivateKey, error) {
+	text = strings.TrimSpace(text)
+	if !IsEncrypted(text) {
+		return ParsePrivateKey(text)
+	}
+	key, _, err := decryptPrivateKey(text, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an RSA key", ErrInvalidPrivateKey)
+	}
+	return rsaKey, nil
+}
+
+// decryptPrivateKey decrypts an encrypted private key of any type and
+// returns it with its PEM block type.
+func decryptPrivateKey(text, passphrase string) (any, string, error) {
+	if passphrase == "" {
+		return nil, "", ErrPassphraseRequired
+	}
+
+	block, _ := pem.Decode([]byte(text))
+	var key any
+	var err error
+	if block.Type == EncryptedPKCS8Type {
+		var der []byte
+		if der, err = decryptPKCS8(block.Bytes, passphrase); err == nil {
+			// About one wrong passphrase in 256 still leaves valid padding,
+			// and then the key fails to parse instead.
+			if key, err = x509.ParsePKCS8PrivateKey(der); err != nil {
+				err = ErrIncorrectPassphrase
+			}
+		}
+	} else {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase([]byte(text), []byte(passphrase))
+	}
+	if errors.Is(err, x509.IncorrectPasswordError) {
+		return nil, "", ErrIncorrectPassphrase
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return key, block.Type, nil
+}
+
+// UnlockPrivateKey decrypts an encrypted private key with passphrase and
+// returns it unencrypted in the same format; legacy encrypted PKCS#1 and
+// SEC 1 PEM stay PKCS#1 and SEC 1. Besides RSA keys it unlocks the ECDSA,
+// Ed25519 and X25519 keys of eckeys. Unencrypted keys and public keys are
+// returned unchanged.
+func UnlockPrivateKey(text, passphrase string) (string, error) {
+	if !IsEncrypted(text) {
+		return text, nil
+	}
+	key, blockType, err := decryptPrivateKey(text, passphrase)
+	if err != nil {
+		return "", err
+	}
+	switch blockType {
+	case EncryptedPKCS8Type:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: PKCS8PrivateKeyType, Bytes: der})), nil
+	case OpenSSHPrivateKeyType:
+		block, err := ssh.MarshalPrivateKey(key, "")
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(block)), nil
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return EncodePrivateKey(k, PKCS1)
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+	}
+	return "", fmt.Errorf("%w: unsupported key type %T", ErrInvalidPrivateKey, key)
+}
+
+// ConvertPrivateKeyWithPassphrase is ConvertPrivateKey for encrypted keys:
+// text is decrypted with passphrase, and the result is encrypted with
+// newPassphrase when it is not empty.
+func ConvertPrivateKeyWithPassphrase(text, passphrase string, format KeyFormat, newPassphrase string) (string, string, error) {
+	key, err := ParsePrivateKeyWithPassphrase(text, passphrase)
+	if err != nil {
+		return "", "", err
+	}
+	privateKey, err := EncryptPrivateKey(key, format, newPassphrase)
+	if err != nil {
+		return "", "", err
+	}
+	publicKey, err := EncodePublicKey(&key.PublicKey, format)
+	if err != nil {
+		return "", "", err
+	}
+	return privateKey, publicKey, nil
+}
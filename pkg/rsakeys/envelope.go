@@ -0,0 +1,283 @@
+package rsakeys
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// EnvelopeFormat is a serialization of a hybrid encrypted message.
+//
+// Seal encrypts the payload with a random AES-256-GCM key and a 12 byte
+// nonce, and wraps the key with RSA-OAEP. The header is authenticated as the
+// GCM associated data "JSHE.<version>.<alg>.<enc>", such as
+// "JSHE.1.RSA-OAEP-256.A256GCM", in both formats, so an envelope with a
+// modified header fails to open like one with modified ciphertext.
+//
+// The JSON format is an object with base64 (standard, padded) binary fields:
+//
+//	{
+//	  "version": 1,
+//	  "alg": "RSA-OAEP-256",    key wrapping: RSA-OAEP, RSA-OAEP-256, -384 or -512
+//	  "enc": "A256GCM",         payload cipher
+//	  "key": "...",             RSA-OAEP encrypted AES key
+//	  "iv": "...",              GCM nonce
+//	  "ciphertext": "..."       GCM ciphertext followed by the 16 byte tag
+//	}
+//
+// The binary format holds the same fields:
+//
+//	magic "JSHE" | version (1 byte) | OAEP hash id (1 byte: 1 SHA-1,
+//	2 SHA-256, 3 SHA-384, 4 SHA-512) | key length (2 bytes, big endian) |
+//	key | nonce (12 bytes) | ciphertext and tag
+type EnvelopeFormat string
+
+// Supported envelope formats.
+const (
+	EnvelopeJSON   EnvelopeFormat = "json"
+	EnvelopeBinary EnvelopeFormat = "binary"
+)
+
+// EnvelopeFormats lists the envelope formats in display order.
+var EnvelopeFormats = []EnvelopeFormat{EnvelopeJSON, EnvelopeBinary}
+
+const (
+	envelopeVersion   = 1
+	envelopeCipher    = "A256GCM"
+	envelopeKeySize   = 32
+	envelopeNonceSize = 12
+	envelopeHeadSize  = 8
+)
+
+var envelopeMagic = []byte("JSHE")
+
+// ErrInvalidEnvelope is returned by Open for data that is not an envelope.
+var ErrInvalidEnvelope = errors.New("not a hybrid encrypted envelope")
+
+// envelopeAlgorithms maps OAEP hashes to their JOSE key wrapping name and
+// binary id.
+var envelopeAlgorithms = []struct {
+	hash Hash
+	name string
+	id   byte
+}{
+	{SHA1, "RSA-OAEP", 1},
+	{SHA256, "RSA-OAEP-256", 2},
+	{SHA384, "RSA-OAEP-384", 3},
+	{SHA512, "RSA-OAEP-512", 4},
+}
+
+type jsonEnvelope struct {
+	Version    int    `json:"version"`
+	Alg        string `json:"alg"`
+	Enc        string `json:"enc"`
+	Key        []byte `json:"key"`
+	IV         []byte `json:"iv"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Seal encrypts plaintext of any length for the RSA public key, wrapping the
+// content key with OAEP and hash, and returns the envelope in format.
+func Seal(plaintext []byte, publicKeyText string, hash Hash, format EnvelopeFormat) ([]byte, error) {
+	publicKey, err := ParsePublicKey(publicKeyText)
+	if err != nil {
+		return nil, err
+	}
+	h, err := hash.crypto()
+	if err != nil {
+		return nil, err
+	}
+
+	contentKey := make([]byte, envelopeKeySize)
+	if _, err := rand.Read(contentKey); err != nil {
+		return nil, err
+	}
+	aead, err := newEnvelopeAEAD(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ciphertext := aead.Seal(nil, nonce, plaintext, envelopeAAD(hash))
+	wrappedKey, err := rsa.EncryptOAEP(h.New(), rand.Reader, publicKey, contentKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, alg := range envelopeAlgorithms {
+		if alg.hash != hash {
+			continue
+		}
+		switch format {
+		case EnvelopeJSON:
+			data, err := json.MarshalIndent(jsonEnvelope{
+				Version:    envelopeVersion,
+				Alg:        alg.name,
+				Enc:        envelopeCipher,
+				Key:        wrappedKey,
+				IV:         nonce,
+				Ciphertext: ciphertext,
+			}, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			return append(data, '\n'), nil
+		case EnvelopeBinary:
+			var buf bytes.Buffer
+			buf.Write(envelopeMagic)
+			buf.WriteByte(envelopeVersion)
+			buf.WriteByte(alg.id)
+			buf.Write(binary.BigEndian.AppendUint16(nil, uint16(len(wrappedKey))))
+			buf.Write(wrappedKey)
+			buf.Write(nonce)
+			buf.Write(ciphertext)
+			return buf.Bytes(), nil
+		default:
+			return nil, fmt.Errorf("unknown envelope format %q", format)
+		}
+	}
+	return nil, fmt.Errorf("unsupported OAEP hash %q", hash)
+}
+
+// Open decrypts an envelope made by Seal in either format with the RSA
+// private key.
+func Open(envelope []byte, privateKeyText string) ([]byte, error) {
+	hash, wrappedKey, nonce, ciphertext, err := parseEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := ParsePrivateKey(privateKeyText)
+	if err != nil {
+		return nil, err
+	}
+	h, err := hash.crypto()
+	if err != nil {
+		return nil, err
+	}
+
+	contentKey, err := rsa.DecryptOAEP(h.New(), rand.Reader, privateKey, wrappedKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unwrap the content key, is this the right private key? %w", err)
+	}
+	aead, err := newEnvelopeAEAD(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: the nonce must be %d bytes", ErrInvalidEnvelope, aead.NonceSize())
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, envelopeAAD(hash))
+	if err != nil {
+		return nil, errors.New("the header or ciphertext was modified or corrupted")
+	}
+	return plaintext, nil
+}
+
+// DetectEnvelope returns the format of envelope, or an error if it is neither.
+func DetectEnvelope(envelope []byte) (EnvelopeFormat, error) {
+	switch {
+	case bytes.HasPrefix(envelope, envelopeMagic):
+		return EnvelopeBinary, nil
+	case bytes.HasPrefix(bytes.TrimSpace(envelope), []byte("{")):
+		return EnvelopeJSON, nil
+	default:
+		return "", ErrInvalidEnvelope
+	}
+}
+
+func parseEnvelope(envelope []byte) (hash Hash, wrappedKey, nonce, ciphertext []byte, err error) {
+	format, err := DetectEnvelope(envelope)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	if format == EnvelopeJSON {
+		var e jsonEnvelope
+		if err := json.Unmarshal(envelope, &e); err != nil {
+			return "", nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+		}
+		if e.Version != envelopeVersion {
+			return "", nil, nil, nil, fmt.Errorf("unsupported envelope version %d", e.Version)
+		}
+		if e.Enc != envelopeCipher {
+			return "", nil, nil, nil, fmt.Errorf("unsupported envelope cipher %q", e.Enc)
+		}
+		for _, alg := range envelopeAlgorithms {
+			if alg.name == e.Alg {
+				return alg.hash, e.Key, e.IV, e.Ciphertext, nil
+			}
+		}
+		return "", nil, nil, nil, fmt.Errorf("unsupported key wrapping %q", e.Alg)
+	}
+
+	if len(envelope) < envelopeHeadSize {
+		return "", nil, nil, nil, fmt.Errorf("%w: truncated header", ErrInvalidEnvelope)
+	}
+	if envelope[4] != envelopeVersion {
+		return "", nil, nil, nil, fmt.Errorf("unsupported envelope version %d", envelope[4])
+	}
+	keyLength := int(binary.BigEndian.Uint16(envelope[6:envelopeHeadSize]))
+	rest := envelope[envelopeHeadSize:]
+	if len(rest) < keyLength+envelopeNonceSize {
+		return "", nil, nil, nil, fmt.Errorf("%w: truncated key or nonce", ErrInvalidEnvelope)
+	}
+	for _, alg := range envelopeAlgorithms {
+		if alg.id == envelope[5] {
+			return alg.hash, rest[:keyLength], rest[keyLength : keyLength+envelopeNonceSize], rest[keyLength+envelopeNonceSize:], nil
+		}
+	}
+	return "", nil, nil, nil, fmt.Errorf("unsupported OAEP hash id %d", envelope[5])
+}
+
+// envelopeAAD returns the associated data authenticating the header of an
+// envelope wrapping its key with OAEP and hash.
+func envelopeAAD(hash Hash) []byte {
+	for _, alg := range envelopeAlgorithms {
+		if alg.hash == hash {
+			return []byte(fmt.Sprintf("%s.%d.%s.%s", envelopeMagic, envelopeVersion, alg.name, envelopeCipher))
+		}
+	}
+	return nil
+}
+
+func newEnvelopeAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != envelopeKeySize {
+		return nil, fmt.Errorf("the content key must be %d bytes", envelopeKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncodeEnvelopeText returns envelope as text: JSON envelopes as is, binary
+// ones as standard base64.
+func EncodeEnvelopeText(envelope []byte) string {
+	if format, _ := DetectEnvelope(envelope); format == EnvelopeBinary {
+		return base64.StdEncoding.EncodeToString(envelope)
+	}
+	return string(envelope)
+}
+
+// DecodeEnvelopeText reverses EncodeEnvelopeText.
+func DecodeEnvelopeText(text string) ([]byte, error) {
+	trimmed := bytes.TrimSpace([]byte(text))
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return trimmed, nil
+	}
+	envelope, err := base64.StdEncoding.DecodeString(string(trimmed))
+	if err != nil {
+		return nil, fmt.Errorf("%w: neither JSON nor base64", ErrInvalidEnvelope)
+	}
+	return envelope, nil
+}
@@ -0,0 +1,234 @@
+package rsakeys
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"strings"
+)
+
+// KeyFormat is an encoding for RSA keys.
+type KeyFormat string
+
+// Supported key formats.
+const (
+	// PKCS1 is "RSA PRIVATE KEY" and "RSA PUBLIC KEY" PEM.
+	PKCS1 KeyFormat = "pkcs1"
+	// PKCS8 is PKCS#8 "PRIVATE KEY" PEM with a PKIX "PUBLIC KEY".
+	PKCS8 KeyFormat = "pkcs8"
+	// OpenSSH is an "OPENSSH PRIVATE KEY" with an authorized_keys line.
+	OpenSSH KeyFormat = "openssh"
+	// JWK is a JSON Web Key (RFC 7517).
+	JWK KeyFormat = "jwk"
+)
+
+// KeyFormats lists the key formats in display order.
+var KeyFormats = []KeyFormat{PKCS1, PKCS8, OpenSSH, JWK}
+
+// Label returns a human readable name for the format.
+func (f KeyFormat) Label() string {
+	switch f {
+	case PKCS1:
+		return "PKCS#1 PEM"
+	case PKCS8:
+		return "PKCS#8 / PKIX PEM"
+	case OpenSSH:
+		return "OpenSSH"
+	case JWK:
+		return "JWK"
+	default:
+		return string(f)
+	}
+}
+
+// PEM block types read and written besides PrivateKeyType and PublicKeyType.
+const (
+	PKCS8PrivateKeyType   = "PRIVATE KEY"
+	PKIXPublicKeyType     = "PUBLIC KEY"
+	OpenSSHPrivateKeyType = "OPENSSH PRIVATE KEY"
+)
+
+// EncodePrivateKey encodes key in the given format.
+func EncodePrivateKey(key *rsa.PrivateKey, format KeyFormat) (string, error) {
+	switch format {
+	case PKCS1:
+		return string(pem.EncodeToMemory(&pem.Block{Type: PrivateKeyType, Bytes: x509.MarshalPKCS1PrivateKey(key)})), nil
+	case PKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: PKCS8PrivateKeyType, Bytes: der})), nil
+	case OpenSSH:
+		block, err := ssh.MarshalPrivateKey(key, "")
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(block)), nil
+	case JWK:
+		return marshalJWK(PrivateJWKMembers(key))
+	default:
+		return "", fmt.Errorf("unknown key format %q", format)
+	}
+}
+
+// EncodePublicKey encodes key in the given format.
+func EncodePublicKey(key *rsa.PublicKey, format KeyFormat) (string, error) {
+	switch format {
+	case PKCS1:
+		return string(pem.EncodeToMemory(&pem.Block{Type: PublicKeyType, Bytes: x509.MarshalPKCS1PublicKey(key)})), nil
+	case PKCS8:
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: PKIXPublicKeyType, Bytes: der})), nil
+	case OpenSSH:
+		sshKey, err := ssh.NewPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		return string(ssh.MarshalAuthorizedKey(sshKey)), nil
+	case JWK:
+		return marshalJWK(PublicJWKMembers(key))
+	default:
+		return "", fmt.Errorf("unknown key format %q", format)
+	}
+}
+
+// ParsePrivateKey reads an RSA private key in any of the KeyFormats.
+func ParsePrivateKey(text string) (*rsa.PrivateKey, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") {
+		return parsePrivateJWK(text)
+	}
+
+	block, _ := pem.Decode([]byte(text))
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case PrivateKeyType:
+		if block.Headers["Proc-Type"] == "4,ENCRYPTED" {
+			return nil, ErrPassphraseRequired
+		}
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case PKCS8PrivateKeyType:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case EncryptedPKCS8Type:
+		return nil, ErrPassphraseRequired
+	case OpenSSHPrivateKeyType:
+		key, err = ssh.ParseRawPrivateKey([]byte(text))
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, ErrPassphraseRequired
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported PEM type %q", ErrInvalidPrivateKey, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an RSA key", ErrInvalidPrivateKey)
+	}
+	return rsaKey, nil
+}
+
+// ParsePublicKey reads an RSA public key in any of the KeyFormats. A private
+// key is accepted too, and its public half returned.
+func ParsePublicKey(text string) (*rsa.PublicKey, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") {
+		return parsePublicJWK(text)
+	}
+	if strings.HasPrefix(text, "ssh-") {
+		sshKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(text))
+		if err != nil {
+			return nil, err
+		}
+		cryptoKey, ok := sshKey.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported SSH key", ErrInvalidPublicKey)
+		}
+		return asRSAPublicKey(cryptoKey.CryptoPublicKey())
+	}
+
+	block, _ := pem.Decode([]byte(text))
+	if block == nil {
+		return nil, ErrInvalidPublicKey
+	}
+	switch block.Type {
+	case PublicKeyType:
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case PKIXPublicKeyType:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return asRSAPublicKey(key)
+	case OpenSSHPrivateKeyType:
+		// Encrypted OpenSSH keys keep the public key in the clear.
+		_, err := ssh.ParseRawPrivateKey([]byte(text))
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			if cryptoKey, ok := missing.PublicKey.(ssh.CryptoPublicKey); ok {
+				return asRSAPublicKey(cryptoKey.CryptoPublicKey())
+			}
+		}
+		key, err := ParsePrivateKey(text)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	case PrivateKeyType, PKCS8PrivateKeyType, EncryptedPKCS8Type:
+		key, err := ParsePrivateKey(text)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported PEM type %q", ErrInvalidPublicKey, block.Type)
+	}
+}
+
+// ConvertPrivateKey re-encodes a private key in any of the KeyFormats as
+// format, returning the private key and its public key.
+func ConvertPrivateKey(text string, format KeyFormat) (string, string, error) {
+	key, err := ParsePrivateKey(text)
+	if err != nil {
+		return "", "", err
+	}
+	privateKey, err := EncodePrivateKey(key, format)
+	if err != nil {
+		return "", "", err
+	}
+	publicKey, err := EncodePublicKey(&key.PublicKey, format)
+	if err != nil {
+		return "", "", err
+	}
+	return privateKey, publicKey, nil
+}
+
+// ConvertPublicKey re-encodes a public key in any of the KeyFormats as format.
+func ConvertPublicKey(text string, format KeyFormat) (string, error) {
+	key, err := ParsePublicKey(text)
+	if err != nil {
+		return "", err
+	}
+	return EncodePublicKey(key, format)
+}
+
+func asRSAPublicKey(key any) (*rsa.PublicKey, error) {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an RSA key", ErrInvalidPublicKey)
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,152 @@
+package rsakeys
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrInvalidJWK is returned for a JSON Web Key that cannot be read.
+// Package jwk returns it for keys of every type.
+var ErrInvalidJWK = errors.New("invalid JWK")
+
+// JWKMembers are the base64url members of an RSA JSON Web Key (RFC 7518
+// section 6.3). Package jwk embeds them in keys of every type, so empty
+// members are omitted; d is shared with EC and OKP keys.
+type JWKMembers struct {
+	N  string `json:"n,omitempty"`
+	E  string `json:"e,omitempty"`
+	D  string `json:"d,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	Dp string `json:"dp,omitempty"`
+	Dq string `json:"dq,omitempty"`
+	Qi string `json:"qi,omitempty"`
+}
+
+// jwk is the JSON form of an RSA JSON Web Key.
+type jwk struct {
+	Kty string `json:"kty"`
+	JWKMembers
+}
+
+// PublicJWKMembers returns the n and e members of key.
+func PublicJWKMembers(key *rsa.PublicKey) JWKMembers {
+	return JWKMembers{
+		N: encodeJWKInt(key.N),
+		E: encodeJWKInt(big.NewInt(int64(key.E))),
+	}
+}
+
+// PrivateJWKMembers returns the members of key, with the CRT values of a
+// two-prime key.
+func PrivateJWKMembers(key *rsa.PrivateKey) JWKMembers {
+	key.Precompute()
+	m := PublicJWKMembers(&key.PublicKey)
+	m.D = encodeJWKInt(key.D)
+	if len(key.Primes) == 2 {
+		m.P = encodeJWKInt(key.Primes[0])
+		m.Q = encodeJWKInt(key.Primes[1])
+		m.Dp = encodeJWKInt(key.Precomputed.Dp)
+		m.Dq = encodeJWKInt(key.Precomputed.Dq)
+		m.Qi = encodeJWKInt(key.Precomputed.Qinv)
+	}
+	return m
+}
+
+// PublicKey decodes the n and e members.
+func (m JWKMembers) PublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeJWKInt("n", m.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeJWKInt("e", m.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("%w: exponent too large", ErrInvalidJWK)
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+// PrivateKey decodes the members of a private key and validates it. The CRT
+// values are recomputed from d and the primes.
+func (m JWKMembers) PrivateKey() (*rsa.PrivateKey, error) {
+	publicKey, err := m.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	key := &rsa.PrivateKey{PublicKey: *publicKey}
+	if key.D, err = decodeJWKInt("d", m.D); err != nil {
+		return nil, err
+	}
+	if m.P == "" || m.Q == "" {
+		return nil, fmt.Errorf("%w: the private key has no primes", ErrInvalidJWK)
+	}
+	for _, prime := range []struct{ name, value string }{{"p", m.P}, {"q", m.Q}} {
+		p, err := decodeJWKInt(prime.name, prime.value)
+		if err != nil {
+			return nil, err
+		}
+		key.Primes = append(key.Primes, p)
+	}
+	if err := key.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+	key.Precompute()
+	return key, nil
+}
+
+func marshalJWK(m JWKMembers) (string, error) {
+	data, err := json.MarshalIndent(jwk{Kty: "RSA", JWKMembers: m}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+func parseJWK(text string) (JWKMembers, error) {
+	var k jwk
+	if err := json.Unmarshal([]byte(text), &k); err != nil {
+		return k.JWKMembers, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+	if k.Kty != "RSA" {
+		return k.JWKMembers, fmt.Errorf("%w: unsupported key type %q, expected RSA", ErrInvalidJWK, k.Kty)
+	}
+	return k.JWKMembers, nil
+}
+
+func parsePublicJWK(text string) (*rsa.PublicKey, error) {
+	m, err := parseJWK(text)
+	if err != nil {
+		return nil, err
+	}
+	return m.PublicKey()
+}
+
+func parsePrivateJWK(text string) (*rsa.PrivateKey, error) {
+	m, err := parseJWK(text)
+	if err != nil {
+		return nil, err
+	}
+	if m.D == "" {
+		return nil, fmt.Errorf("%w: the JWK has no private exponent", ErrInvalidPrivateKey)
+	}
+	return m.PrivateKey()
+}
+
+func encodeJWKInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+func decodeJWKInt(name, value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("%w: %q is not base64url", ErrInvalidJWK, name)
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,81 @@
+package rsakeys
+
+import (
+	"crypto"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"fmt"
+)
+
+// Padding is an RSA encryption padding scheme.
+type Padding string
+
+// Supported paddings. PKCS#1 v1.5 encryption is kept for compatibility with
+// existing ciphertexts; new code should use OAEP.
+const (
+	PKCS1v15Padding Padding = "PKCS1v15"
+	OAEPPadding     Padding = "OAEP"
+)
+
+// Paddings lists the paddings in display order.
+var Paddings = []Padding{OAEPPadding, PKCS1v15Padding}
+
+// Hash names a digest used by OAEP and signatures.
+type Hash string
+
+// Supported hashes.
+const (
+	SHA1   Hash = "SHA-1"
+	SHA256 Hash = "SHA-256"
+	SHA384 Hash = "SHA-384"
+	SHA512 Hash = "SHA-512"
+)
+
+// OAEPHashes lists the hashes offered for OAEP, and SignatureHashes those
+// offered for signatures, where SHA-1 is no longer acceptable.
+var (
+	OAEPHashes      = []Hash{SHA256, SHA1, SHA512}
+	SignatureHashes = []Hash{SHA256, SHA384, SHA512}
+)
+
+func (h Hash) crypto() (crypto.Hash, error) {
+	switch h {
+	case SHA1:
+		return crypto.SHA1, nil
+	case SHA256:
+		return crypto.SHA256, nil
+	case SHA384:
+		return crypto.SHA384, nil
+	case SHA512:
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unknown hash %q", h)
+	}
+}
+
+// EncryptOptions selects the padding used by EncryptWith and DecryptWith.
+// Hash and Label only apply to OAEP; the label must match on decryption.
+type EncryptOptions struct {
+	Padding Padding
+	Hash    Hash
+	Label   string
+}
+
+// SignatureScheme is an RSA signature scheme.
+type SignatureScheme string
+
+// Supported signature schemes.
+const (
+	PKCS1v15Signature SignatureScheme = "PKCS1v15"
+	PSSSignature      SignatureScheme = "PSS"
+)
+
+// SignatureSchemes lists the signature schemes in display order.
+var SignatureSchemes = []SignatureScheme{PSSSignature, PKCS1v15Signature}
+
+// SignOptions selects the scheme and digest used by Sign and Verify.
+type SignOptions struct {
+	Scheme SignatureScheme
+	Hash   Hash
+}
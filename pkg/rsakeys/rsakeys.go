@@ -0,0 +1,196 @@
+// Package rsakeys generates RSA key pairs, converts them between PKCS#1,
+// PKCS#8/PKIX, OpenSSH and JWK, protects private keys with a passphrase,
+// and uses them to encrypt and decrypt short messages and to sign and verify
+// data. Functions taking a key as text accept any of the KeyFormats.
+package rsakeys
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// PKCS#1 PEM block types produced by Generate.
+const (
+	PrivateKeyType = "RSA PRIVATE KEY"
+	PublicKeyType  = "RSA PUBLIC KEY"
+)
+
+// MinSecureBits is the smallest key size considered secure. Smaller keys
+// can be factored with modest resources and are only useful for testing.
+const MinSecureBits = 2048
+
+var (
+	// ErrInvalidPublicKey is returned when the public key is not in a supported format.
+	ErrInvalidPublicKey = errors.New("failed to decode public key")
+	// ErrInvalidPrivateKey is returned when the private key is not in a supported format.
+	ErrInvalidPrivateKey = errors.New("failed to decode private key")
+)
+
+// Generate creates an RSA key pair of bitSize bits and returns the private
+// and public keys encoded as PKCS#1 PEM.
+func Generate(bitSize int) (string, string, error) {
+	return GenerateFormat(bitSize, PKCS1)
+}
+
+// GenerateFormat creates an RSA key pair of bitSize bits and returns the
+// private and public keys encoded in format.
+func GenerateFormat(bitSize int, format KeyFormat) (string, string, error) {
+	return GenerateWithPassphrase(bitSize, format, "")
+}
+
+// GenerateWithPassphrase creates an RSA key pair of bitSize bits and returns
+// the keys encoded in format, with the private key encrypted by passphrase
+// unless it is empty. See EncryptPrivateKey for the formats that support it.
+func GenerateWithPassphrase(bitSize int, format KeyFormat, passphrase string) (string, string, error) {
+	return GenerateContext(context.Background(), bitSize, format, passphrase)
+}
+
+// GenerateContext is GenerateWithPassphrase with cancellation. Large keys
+// take seconds to generate; when ctx is done GenerateContext returns
+// ctx.Err() immediately and the abandoned search stops at its next read of
+// random data. Go 1.26 and later only pass the reader to rsa.GenerateKey with
+// GODEBUG cryptocustomrand=1, the default while go.mod says go 1.22; without
+// it the search runs to the end in the background.
+func GenerateContext(ctx context.Context, bitSize int, format KeyFormat, passphrase string) (string, string, error) {
+	type result struct {
+		privateKey, publicKey string
+		err                   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		r.privateKey, r.publicKey, r.err = generate(contextReader{ctx}, bitSize, format, passphrase)
+		done <- r
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	case r := <-done:
+		return r.privateKey, r.publicKey, r.err
+	}
+}
+
+func generate(random io.Reader, bitSize int, format KeyFormat, passphrase string) (string, string, error) {
+	// Generate RSA private key
+	privateKey, err := rsa.GenerateKey(random, bitSize)
+	if err != nil {
+		return "", "", err
+	}
+
+	privateKeyText, err := EncryptPrivateKey(privateKey, format, passphrase)
+	if err != nil {
+		return "", "", err
+	}
+	publicKeyText, err := EncodePublicKey(&privateKey.PublicKey, format)
+	if err != nil {
+		return "", "", err
+	}
+	return privateKeyText, publicKeyText, nil
+}
+
+// contextReader reads from crypto/rand until its context is done.
+type contextReader struct {
+	ctx context.Context
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return rand.Read(p)
+}
+
+// Encrypt encrypts the given message using the RSA public key with PKCS#1
+// v1.5 padding and returns the ciphertext encoded as standard base64. New
+// code should use EncryptWith and OAEP.
+func Encrypt(message string, publicKeyPEM string) (string, error) {
+	return EncryptWith(message, publicKeyPEM, EncryptOptions{Padding: PKCS1v15Padding})
+}
+
+// EncryptWith encrypts the given message using the RSA public key and the
+// padding in opts, and returns the ciphertext encoded as standard base64.
+func EncryptWith(message string, publicKeyPEM string, opts EncryptOptions) (string, error) {
+	publicKey, err := ParsePublicKey(publicKeyPEM)
+	if err != nil {
+		return "", err
+	}
+
+	var encryptedBytes []byte
+	switch opts.Padding {
+	case PKCS1v15Padding:
+		encryptedBytes, err = rsa.EncryptPKCS1v15(rand.Reader, publicKey, []byte(message))
+	case OAEPPadding:
+		var h crypto.Hash
+		if h, err = opts.Hash.crypto(); err == nil {
+			encryptedBytes, err = rsa.EncryptOAEP(h.New(), rand.Reader, publicKey, []byte(message), []byte(opts.Label))
+		}
+	default:
+		err = fmt.Errorf("unknown padding %q", opts.Padding)
+	}
+	if errors.Is(err, rsa.ErrMessageTooLong) {
+		return "", fmt.Errorf("%w: at most %d bytes fit a %d bit key with %s padding, use Seal for longer messages",
+			err, maxMessageLength(publicKey, opts), publicKey.N.BitLen(), opts.Padding)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Encode the encrypted bytes to base64 to return as a string
+	encryptedBase64 := base64.StdEncoding.EncodeToString(encryptedBytes)
+	return encryptedBase64, nil
+}
+
+// maxMessageLength returns the longest message EncryptWith accepts for key.
+func maxMessageLength(key *rsa.PublicKey, opts EncryptOptions) int {
+	if opts.Padding == OAEPPadding {
+		h, _ := opts.Hash.crypto()
+		return key.Size() - 2*h.Size() - 2
+	}
+	return key.Size() - 11
+}
+
+// Decrypt decrypts the given base64 encoded message using the RSA private
+// key with PKCS#1 v1.5 padding.
+func Decrypt(encryptedMessage string, privateKeyPEM string) (string, error) {
+	return DecryptWith(encryptedMessage, privateKeyPEM, EncryptOptions{Padding: PKCS1v15Padding})
+}
+
+// DecryptWith decrypts the given base64 encoded message using the RSA
+// private key and the padding in opts, which must match the encryption.
+func DecryptWith(encryptedMessage string, privateKeyPEM string, opts EncryptOptions) (string, error) {
+	privateKey, err := ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return "", err
+	}
+
+	// Decode the base64 encoded encrypted message
+	encryptedBytes, err := base64.StdEncoding.DecodeString(encryptedMessage)
+	if err != nil {
+		return "", err
+	}
+
+	var decryptedBytes []byte
+	switch opts.Padding {
+	case PKCS1v15Padding:
+		decryptedBytes, err = rsa.DecryptPKCS1v15(rand.Reader, privateKey, encryptedBytes)
+	case OAEPPadding:
+		var h crypto.Hash
+		if h, err = opts.Hash.crypto(); err == nil {
+			decryptedBytes, err = rsa.DecryptOAEP(h.New(), rand.Reader, privateKey, encryptedBytes, []byte(opts.Label))
+		}
+	default:
+		err = fmt.Errorf("unknown padding %q", opts.Padding)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return string(decryptedBytes), nil
+}
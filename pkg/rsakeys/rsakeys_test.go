@@ -0,0 +1,460 @@
+package rsakeys
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+// newTestKey returns a 2048 bit key shared by the tests, generated once.
+func newTestKey(t testing.TB) *rsa.PrivateKey {
+	t.Helper()
+	testKeyOnce.Do(func() {
+		var err error
+		if testKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return testKey
+}
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestKeyFormatRoundTrip(t *testing.T) {
+	key := newTestKey(t)
+	for _, format := range KeyFormats {
+		t.Run(string(format), func(t *testing.T) {
+			privateText, err := EncodePrivateKey(key, format)
+			if err != nil {
+				t.Fatalf("EncodePrivateKey: %v", err)
+			}
+			publicText, err := EncodePublicKey(&key.PublicKey, format)
+			if err != nil {
+				t.Fatalf("EncodePublicKey: %v", err)
+			}
+			parsed, err := ParsePrivateKey(privateText)
+			if err != nil {
+				t.Fatalf("ParsePrivateKey: %v", err)
+			}
+			if !parsed.Equal(key) {
+				t.Error("private key changed in the round trip")
+			}
+			public, err := ParsePublicKey(publicText)
+			if err != nil {
+				t.Fatalf("ParsePublicKey: %v", err)
+			}
+			if !public.Equal(&key.PublicKey) {
+				t.Error("public key changed in the round trip")
+			}
+			if public, err := ParsePublicKey(privateText); err != nil || !public.Equal(&key.PublicKey) {
+				t.Errorf("ParsePublicKey of the private key: %v", err)
+			}
+			if IsEncrypted(privateText) {
+				t.Error("IsEncrypted reports an unencrypted key")
+			}
+		})
+	}
+}
+
+func TestConvertPrivateKey(t *testing.T) {
+	key := newTestKey(t)
+	pkcs1, err := EncodePrivateKey(key, PKCS1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range KeyFormats {
+		t.Run(string(format), func(t *testing.T) {
+			privateText, publicText, err := ConvertPrivateKey(pkcs1, format)
+			if err != nil {
+				t.Fatalf("ConvertPrivateKey: %v", err)
+			}
+			want, _ := EncodePublicKey(&key.PublicKey, format)
+			if publicText != want {
+				t.Errorf("public key %q, want %q", publicText, want)
+			}
+			back, _, err := ConvertPrivateKey(privateText, PKCS1)
+			if err != nil || back != pkcs1 {
+				t.Errorf("converting back to PKCS#1 = %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedPrivateKeyRoundTrip(t *testing.T) {
+	key := newTestKey(t)
+	for _, format := range KeyFormats {
+		t.Run(string(format), func(t *testing.T) {
+			text, err := EncryptPrivateKey(key, format, "secret")
+			if !format.CanEncrypt() {
+				if err == nil {
+					t.Fatalf("EncryptPrivateKey(%s) succeeded", format)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EncryptPrivateKey: %v", err)
+			}
+			if !IsEncrypted(text) {
+				t.Error("IsEncrypted = false")
+			}
+			if _, err := ParsePrivateKey(text); !errors.Is(err, ErrPassphraseRequired) {
+				t.Errorf("ParsePrivateKey() error = %v, want ErrPassphraseRequired", err)
+			}
+			if _, err := ParsePrivateKeyWithPassphrase(text, ""); !errors.Is(err, ErrPassphraseRequired) {
+				t.Errorf("empty passphrase error = %v, want ErrPassphraseRequired", err)
+			}
+			if _, err := ParsePrivateKeyWithPassphrase(text, "wrong"); !errors.Is(err, ErrIncorrectPassphrase) {
+				t.Errorf("wrong passphrase error = %v, want ErrIncorrectPassphrase", err)
+			}
+			parsed, err := ParsePrivateKeyWithPassphrase(text, "secret")
+			if err != nil {
+				t.Fatalf("ParsePrivateKeyWithPassphrase: %v", err)
+			}
+			if !parsed.Equal(key) {
+				t.Error("private key changed in the round trip")
+			}
+			if public, err := ParsePublicKey(text); format == OpenSSH && (err != nil || !public.Equal(&key.PublicKey)) {
+				t.Errorf("ParsePublicKey of an encrypted OpenSSH key: %v", err)
+			}
+
+			unlocked, err := UnlockPrivateKey(text, "secret")
+			if err != nil {
+				t.Fatalf("UnlockPrivateKey: %v", err)
+			}
+			if IsEncrypted(unlocked) {
+				t.Error("UnlockPrivateKey returned an encrypted key")
+			}
+			if parsed, err := ParsePrivateKey(unlocked); err != nil || !parsed.Equal(key) {
+				t.Errorf("ParsePrivateKey of the unlocked key: %v", err)
+			}
+
+			reencrypted, _, err := ConvertPrivateKeyWithPassphrase(text, "secret", format, "other")
+			if err != nil {
+				t.Fatalf("ConvertPrivateKeyWithPassphrase: %v", err)
+			}
+			if _, err := ParsePrivateKeyWithPassphrase(reencrypted, "other"); err != nil {
+				t.Errorf("new passphrase: %v", err)
+			}
+		})
+	}
+}
+
+// TestParseExternalEncryptedKeys reads keys encrypted by OpenSSL and
+// ssh-keygen with the passphrase "secret".
+func TestParseExternalEncryptedKeys(t *testing.T) {
+	want, err := ParsePrivateKey(readTestdata(t, "openssl_plain.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"PKCS#8 PBKDF2-SHA256 AES-256-CBC", "openssl_pkcs8.pem"},
+		{"PKCS#8 PBKDF2-SHA1 AES-128-CBC", "openssl_pkcs8_sha1.pem"},
+		{"legacy PKCS#1 AES-256-CBC", "openssl_legacy.pem"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := readTestdata(t, tt.file)
+			if !IsEncrypted(text) {
+				t.Error("IsEncrypted = false")
+			}
+			if _, err := ParsePrivateKeyWithPassphrase(text, "wrong"); !errors.Is(err, ErrIncorrectPassphrase) {
+				t.Errorf("wrong passphrase error = %v, want ErrIncorrectPassphrase", err)
+			}
+			key, err := ParsePrivateKeyWithPassphrase(text, "secret")
+			if err != nil {
+				t.Fatalf("ParsePrivateKeyWithPassphrase: %v", err)
+			}
+			if !key.Equal(want) {
+				t.Error("decrypted a different key")
+			}
+			unlocked, err := UnlockPrivateKey(text, "secret")
+			if err != nil {
+				t.Fatalf("UnlockPrivateKey: %v", err)
+			}
+			if key, err := ParsePrivateKey(unlocked); err != nil || !key.Equal(want) {
+				t.Errorf("ParsePrivateKey of the unlocked key: %v", err)
+			}
+		})
+	}
+
+	t.Run("ssh-keygen", func(t *testing.T) {
+		text := readTestdata(t, "ssh_rsa")
+		public, err := ParsePublicKey(readTestdata(t, "ssh_rsa.pub"))
+		if err != nil {
+			t.Fatalf("ParsePublicKey: %v", err)
+		}
+		if _, err := ParsePrivateKeyWithPassphrase(text, "wrong"); !errors.Is(err, ErrIncorrectPassphrase) {
+			t.Errorf("wrong passphrase error = %v, want ErrIncorrectPassphrase", err)
+		}
+		key, err := ParsePrivateKeyWithPassphrase(text, "secret")
+		if err != nil {
+			t.Fatalf("ParsePrivateKeyWithPassphrase: %v", err)
+		}
+		if !key.PublicKey.Equal(public) {
+			t.Error("the private key does not match the public key")
+		}
+	})
+}
+
+func TestTamperedEncryptedPKCS8(t *testing.T) {
+	block, _ := pem.Decode([]byte(readTestdata(t, "openssl_pkcs8.pem")))
+	// The encrypted data comes last, so this changes its padding block.
+	block.Bytes[len(block.Bytes)-1] ^= 1
+	text := string(pem.EncodeToMemory(block))
+	if _, err := ParsePrivateKeyWithPassphrase(text, "secret"); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Errorf("tampered key error = %v, want ErrIncorrectPassphrase", err)
+	}
+
+	// Changing the first block leaves the padding valid but garbles the key,
+	// as a wrong passphrase occasionally does.
+	block, _ = pem.Decode([]byte(readTestdata(t, "openssl_pkcs8.pem")))
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		t.Fatal(err)
+	}
+	block.Bytes[len(block.Bytes)-len(info.EncryptedData)] ^= 1
+	text = string(pem.EncodeToMemory(block))
+	if _, err := ParsePrivateKeyWithPassphrase(text, "secret"); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Errorf("garbled key error = %v, want ErrIncorrectPassphrase", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		public bool
+		target error
+	}{
+		{"garbage private", "not a key", false, ErrInvalidPrivateKey},
+		{"garbage public", "not a key", true, ErrInvalidPublicKey},
+		{"certificate request", "-----BEGIN CERTIFICATE REQUEST-----\nAAAA\n-----END CERTIFICATE REQUEST-----", false, ErrInvalidPrivateKey},
+		{"unsupported public PEM", "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----", true, ErrInvalidPublicKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.public {
+				_, err = ParsePublicKey(tt.text)
+			} else {
+				_, err = ParsePrivateKey(tt.text)
+			}
+			if !errors.Is(err, tt.target) {
+				t.Errorf("error = %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := newTestKey(t)
+	privateText, _ := EncodePrivateKey(key, PKCS1)
+	publicText, _ := EncodePublicKey(&key.PublicKey, PKCS8)
+	tests := []struct {
+		name string
+		opts EncryptOptions
+	}{
+		{"PKCS#1 v1.5", EncryptOptions{Padding: PKCS1v15Padding}},
+		{"OAEP SHA-1", EncryptOptions{Padding: OAEPPadding, Hash: SHA1}},
+		{"OAEP SHA-256", EncryptOptions{Padding: OAEPPadding, Hash: SHA256}},
+		{"OAEP SHA-512 with label", EncryptOptions{Padding: OAEPPadding, Hash: SHA512, Label: "label"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, err := EncryptWith("hello, world", publicText, tt.opts)
+			if err != nil {
+				t.Fatalf("EncryptWith: %v", err)
+			}
+			plaintext, err := DecryptWith(ciphertext, privateText, tt.opts)
+			if err != nil || plaintext != "hello, world" {
+				t.Fatalf("DecryptWith() = %q, %v", plaintext, err)
+			}
+			tampered := []byte(ciphertext)
+			tampered[5] ^= 'A' ^ 'B'
+			if tampered[5] == ciphertext[5] {
+				tampered[5] = 'C'
+			}
+			if _, err := DecryptWith(string(tampered), privateText, tt.opts); err == nil {
+				t.Error("tampered ciphertext decrypted")
+			}
+			if tt.opts.Padding == OAEPPadding {
+				wrongLabel := tt.opts
+				wrongLabel.Label += "x"
+				if _, err := DecryptWith(ciphertext, privateText, wrongLabel); err == nil {
+					t.Error("decrypted with the wrong label")
+				}
+			}
+			max := maxMessageLength(&key.PublicKey, tt.opts)
+			if _, err := EncryptWith(strings.Repeat("a", max), publicText, tt.opts); err != nil {
+				t.Errorf("message of the maximum length %d: %v", max, err)
+			}
+			if _, err := EncryptWith(strings.Repeat("a", max+1), publicText, tt.opts); !errors.Is(err, rsa.ErrMessageTooLong) {
+				t.Errorf("message over the maximum length error = %v, want ErrMessageTooLong", err)
+			}
+		})
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	key := newTestKey(t)
+	privateText, _ := EncodePrivateKey(key, PKCS8)
+	publicText, _ := EncodePublicKey(&key.PublicKey, OpenSSH)
+	data := []byte("signed data")
+	for _, scheme := range SignatureSchemes {
+		for _, hash := range SignatureHashes {
+			opts := SignOptions{Scheme: scheme, Hash: hash}
+			t.Run(string(scheme)+" "+string(hash), func(t *testing.T) {
+				signature, err := Sign(data, privateText, opts)
+				if err != nil {
+					t.Fatalf("Sign: %v", err)
+				}
+				if err := Verify(data, signature, publicText, opts); err != nil {
+					t.Errorf("Verify: %v", err)
+				}
+				if err := Verify([]byte("other data"), signature, publicText, opts); err == nil {
+					t.Error("the signature verified other data")
+				}
+				other := opts
+				other.Hash = SHA1
+				if err := Verify(data, signature, publicText, other); err == nil {
+					t.Error("the signature verified with another hash")
+				}
+			})
+		}
+	}
+	if _, err := Sign(data, privateText, SignOptions{Scheme: "DSA", Hash: SHA256}); err == nil {
+		t.Error("Sign accepted an unknown scheme")
+	}
+	if err := Verify(data, "!!!", publicText, SignOptions{Scheme: PSSSignature, Hash: SHA256}); err == nil {
+		t.Error("Verify accepted a signature that is not base64")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	key := newTestKey(t)
+	privateText, _ := EncodePrivateKey(key, PKCS1)
+	publicText, _ := EncodePublicKey(&key.PublicKey, JWK)
+	plaintext := bytes.Repeat([]byte("longer than one RSA block "), 100)
+	for _, format := range EnvelopeFormats {
+		for _, hash := range OAEPHashes {
+			t.Run(string(format)+" "+string(hash), func(t *testing.T) {
+				envelope, err := Seal(plaintext, publicText, hash, format)
+				if err != nil {
+					t.Fatalf("Seal: %v", err)
+				}
+				if got, err := DetectEnvelope(envelope); err != nil || got != format {
+					t.Errorf("DetectEnvelope() = %q, %v", got, err)
+				}
+				decoded, err := DecodeEnvelopeText(EncodeEnvelopeText(envelope))
+				if err != nil {
+					t.Fatalf("DecodeEnvelopeText: %v", err)
+				}
+				opened, err := Open(decoded, privateText)
+				if err != nil {
+					t.Fatalf("Open: %v", err)
+				}
+				if !bytes.Equal(opened, plaintext) {
+					t.Error("plaintext changed in the round trip")
+				}
+
+				tampered := append([]byte(nil), envelope...)
+				if format == EnvelopeJSON {
+					var e jsonEnvelope
+					if err := json.Unmarshal(envelope, &e); err != nil {
+						t.Fatal(err)
+					}
+					e.Ciphertext[0] ^= 1
+					tampered, _ = json.Marshal(e)
+				} else {
+					tampered[len(tampered)-1] ^= 1
+				}
+				if _, err := Open(tampered, privateText); err == nil {
+					t.Error("tampered ciphertext opened")
+				}
+			})
+		}
+	}
+}
+
+func TestOpenTamperedHeader(t *testing.T) {
+	key := newTestKey(t)
+	privateText, _ := EncodePrivateKey(key, PKCS1)
+	publicText, _ := EncodePublicKey(&key.PublicKey, PKCS1)
+	envelope, err := Seal([]byte("secret"), publicText, SHA256, EnvelopeBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		modify func(e []byte)
+	}{
+		{"version", func(e []byte) { e[4] = 2 }},
+		{"hash id", func(e []byte) { e[5] = 4 }},
+		{"unknown hash id", func(e []byte) { e[5] = 9 }},
+		{"magic", func(e []byte) { e[0] = 'X' }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := append([]byte(nil), envelope...)
+			tt.modify(tampered)
+			if _, err := Open(tampered, privateText); err == nil {
+				t.Error("an envelope with a modified header opened")
+			}
+		})
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherText, _ := EncodePrivateKey(other, PKCS1)
+	if _, err := Open(envelope, otherText); err == nil {
+		t.Error("opened with the wrong private key")
+	}
+	if _, err := Open([]byte("JSH"), privateText); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("Open of garbage error = %v, want ErrInvalidEnvelope", err)
+	}
+}
+
+func TestGenerateContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := GenerateContext(ctx, 4096, PKCS1, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("GenerateContext() error = %v, want context.Canceled", err)
+	}
+}
+
+// TestGenerateReadsRandom guards the cancellation in GenerateContext, which
+// only works while rsa.GenerateKey reads the random data it is given.
+func TestGenerateReadsRandom(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := generate(contextReader{ctx}, 2048, PKCS1, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("generate() error = %v, want context.Canceled; is GODEBUG cryptocustomrand=1 still the default?", err)
+	}
+}
@@ -0,0 +1,69 @@
+package rsakeys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Sign signs data with the RSA private key and returns the signature encoded
+// as standard base64. PSS signatures use a salt as long as the hash.
+func Sign(data []byte, privateKeyPEM string, opts SignOptions) (string, error) {
+	privateKey, err := ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return "", err
+	}
+	h, err := opts.Hash.crypto()
+	if err != nil {
+		return "", err
+	}
+	digest := h.New()
+	digest.Write(data)
+	hashed := digest.Sum(nil)
+
+	var signature []byte
+	switch opts.Scheme {
+	case PKCS1v15Signature:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, privateKey, h, hashed)
+	case PSSSignature:
+		signature, err = rsa.SignPSS(rand.Reader, privateKey, h, hashed, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	default:
+		err = fmt.Errorf("unknown signature scheme %q", opts.Scheme)
+	}
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks a base64 encoded signature of data against the RSA public
+// key, returning nil when it is valid. PSS signatures with any salt length
+// are accepted.
+func Verify(data []byte, signature string, publicKeyPEM string, opts SignOptions) error {
+	publicKey, err := ParsePublicKey(publicKeyPEM)
+	if err != nil {
+		return err
+	}
+	h, err := opts.Hash.crypto()
+	if err != nil {
+		return err
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return fmt.Errorf("signature is not valid base64: %v", err)
+	}
+	digest := h.New()
+	digest.Write(data)
+	hashed := digest.Sum(nil)
+
+	switch opts.Scheme {
+	case PKCS1v15Signature:
+		return rsa.VerifyPKCS1v15(publicKey, h, hashed, signatureBytes)
+	case PSSSignature:
+		return rsa.VerifyPSS(publicKey, h, hashed, signatureBytes, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+	default:
+		return fmt.Errorf("unknown signature scheme %q", opts.Scheme)
+	}
+}
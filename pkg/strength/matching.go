@@ -0,0 +1,615 @@
+package strength
+
+import (
+	"bufio"
+	_ "embed"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Pattern names the kind of weakness a Match found.
+type Pattern string
+
+// Patterns reported in Result.Sequence.
+const (
+	DictionaryPattern Pattern = "dictionary"
+	SpatialPattern    Pattern = "spatial"
+	RepeatPattern     Pattern = "repeat"
+	SequencePattern   Pattern = "sequence"
+	DatePattern       Pattern = "date"
+	BruteforcePattern Pattern = "bruteforce"
+)
+
+// Match is a part of the password, runes I through J inclusive, that follows
+// a guessable pattern. Only the fields relevant to Pattern are set.
+type Match struct {
+	Pattern Pattern
+	I, J    int
+	Token   string
+	Guesses float64
+
+	// Dictionary matches.
+	Dictionary  string
+	MatchedWord string
+	Rank        int
+	Reversed    bool
+	L33t        bool
+	Sub         map[rune]rune
+
+	// Spatial matches.
+	Graph        string
+	Turns        int
+	ShiftedCount int
+
+	// Repeat matches.
+	BaseToken   string
+	BaseGuesses float64
+	RepeatCount int
+
+	// Sequence matches.
+	SequenceName string
+	Ascending    bool
+
+	// Date matches. Month and Day are zero for a bare year.
+	Year, Month, Day int
+	Separator        string
+}
+
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+// englishWordsList holds English words, most frequent first, from the
+// subtitle word counts zxcvbn ranks English words by.
+//
+//go:embed english_words.txt
+var englishWordsList string
+
+const (
+	commonPasswordsDictionary = "passwords"
+	englishDictionary         = "english"
+	userInputsDictionary      = "user_inputs"
+)
+
+// rankedDictionaries maps a dictionary name to word ranks, 1 being the most likely.
+var rankedDictionaries = map[string]map[string]int{
+	commonPasswordsDictionary: rankWords(commonPasswordsList),
+	englishDictionary:         rankWords(englishWordsList),
+}
+
+// rankWords ranks the words of a list with one word per line, most likely
+// first.
+func rankWords(list string) map[string]int {
+	ranks := map[string]int{}
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if _, seen := ranks[word]; word != "" && !seen {
+			ranks[word] = len(ranks) + 1
+		}
+	}
+	return ranks
+}
+
+// referenceYear anchors the date guesses; recent years are more likely.
+var referenceYear = time.Now().Year()
+
+// omnimatch runs every matcher over password.
+func omnimatch(password []rune, userInputs []string) []Match {
+	dictionaries := rankedDictionaries
+	if len(userInputs) > 0 {
+		dictionaries = map[string]map[string]int{}
+		for name, ranks := range rankedDictionaries {
+			dictionaries[name] = ranks
+		}
+		inputs := map[string]int{}
+		for i, input := range userInputs {
+			inputs[normalize(input)] = i + 1
+		}
+		dictionaries[userInputsDictionary] = inputs
+	}
+
+	var matches []Match
+	matches = append(matches, dictionaryMatch(password, dictionaries)...)
+	matches = append(matches, reverseDictionaryMatch(password, dictionaries)...)
+	matches = append(matches, l33tMatch(password, dictionaries)...)
+	matches = append(matches, spatialMatch(password)...)
+	matches = append(matches, repeatMatch(password, userInputs)...)
+	matches = append(matches, sequenceMatch(password)...)
+	matches = append(matches, dateMatch(password)...)
+
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].I != matches[b].I {
+			return matches[a].I < matches[b].I
+		}
+		return matches[a].J < matches[b].J
+	})
+	return matches
+}
+
+func dictionaryMatch(password []rune, dictionaries map[string]map[string]int) []Match {
+	var matches []Match
+	lower := []rune(normalize(string(password)))
+	for name, ranks := range dictionaries {
+		for i := range lower {
+			for j := i; j < len(lower); j++ {
+				word := string(lower[i : j+1])
+				rank, ok := ranks[word]
+				if !ok {
+					continue
+				}
+				matches = append(matches, Match{
+					Pattern:     DictionaryPattern,
+					I:           i,
+					J:           j,
+					Token:       string(password[i : j+1]),
+					Dictionary:  name,
+					MatchedWord: word,
+					Rank:        rank,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+func reverseDictionaryMatch(password []rune, dictionaries map[string]map[string]int) []Match {
+	reversed := reverse(password)
+	matches := dictionaryMatch(reversed, dictionaries)
+	for k := range matches {
+		m := &matches[k]
+		m.Token = string(reverse([]rune(m.Token)))
+		m.Reversed = true
+		m.I, m.J = len(password)-1-m.J, len(password)-1-m.I
+	}
+	return matches
+}
+
+// l33tTable lists the letters each substitution may stand for.
+var l33tTable = map[rune][]rune{
+	'4': {'a'}, '@': {'a'},
+	'8': {'b'},
+	'(': {'c'}, '{': {'c'}, '[': {'c'}, '<': {'c'},
+	'3': {'e'},
+	'6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'},
+	'7': {'l', 't'},
+	'0': {'o'},
+	'$': {'s'}, '5': {'s'},
+	'+': {'t'},
+	'%': {'x'},
+	'2': {'z'},
+}
+
+// maxL33tSubs bounds the substitution combinations tried per password.
+const maxL33tSubs = 64
+
+func l33tMatch(password []rune, dictionaries map[string]map[string]int) []Match {
+	var present []rune
+	seen := map[rune]bool{}
+	for _, c := range password {
+		if _, ok := l33tTable[c]; ok && !seen[c] {
+			seen[c] = true
+			present = append(present, c)
+		}
+	}
+	if len(present) == 0 {
+		return nil
+	}
+
+	// Enumerate one letter per substituted character.
+	subs := []map[rune]rune{{}}
+	for _, c := range present {
+		var next []map[rune]rune
+		for _, sub := range subs {
+			for _, letter := range l33tTable[c] {
+				extended := map[rune]rune{c: letter}
+				for k, v := range sub {
+					extended[k] = v
+				}
+				next = append(next, extended)
+				if len(next) >= maxL33tSubs {
+					break
+				}
+			}
+		}
+		subs = next
+	}
+
+	var matches []Match
+	for _, sub := range subs {
+		subbed := make([]rune, len(password))
+		for i, c := range password {
+			if letter, ok := sub[c]; ok {
+				subbed[i] = letter
+			} else {
+				subbed[i] = c
+			}
+		}
+
+		for _, m := range dictionaryMatch(subbed, dictionaries) {
+			token := password[m.I : m.J+1]
+			used := map[rune]rune{}
+			for _, c := range token {
+				if letter, ok := sub[c]; ok {
+					used[c] = letter
+				}
+			}
+			// Single character tokens are better left to other matchers.
+			if len(used) == 0 || len(token) == 1 {
+				continue
+			}
+			m.Token = string(token)
+			m.L33t = true
+			m.Sub = used
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// keyboard is an adjacency graph of a keyboard layout.
+type keyboard struct {
+	name string
+	// adjacency holds, per direction, the characters on the neighbouring key
+	// or "" when there is no key in that direction.
+	adjacency map[rune][]string
+	// shifted holds the characters typed with shift.
+	shifted map[rune]bool
+	// startingPositions and averageDegree feed the spatial guess estimate.
+	startingPositions float64
+	averageDegree     float64
+}
+
+const qwertyLayout = "`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+\n" +
+	"    qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|\n" +
+	"     aA sS dD fF gG hH jJ kK lL ;: '\"\n" +
+	"      zZ xX cC vV bB nN mM ,< .> /?"
+
+const keypadLayout = "  / * -\n" +
+	"7 8 9 +\n" +
+	"4 5 6\n" +
+	"1 2 3\n" +
+	"  0 ."
+
+var keyboards = []keyboard{
+	buildKeyboard("qwerty", qwertyLayout, true),
+	buildKeyboard("keypad", keypadLayout, false),
+}
+
+// buildKeyboard parses a layout drawing. Slanted layouts are staggered like a
+// real keyboard, so each key has six neighbours; aligned keypads have eight.
+func buildKeyboard(name, layout string, slanted bool) keyboard {
+	type point struct{ x, y int }
+	positions := map[point]string{}
+	var tokenLength int
+
+	for y, line := range strings.Split(layout, "\n") {
+		slant := 0
+		if slanted {
+			slant = y
+		}
+		for x := 0; x < len(line); x++ {
+			if line[x] == ' ' {
+				continue
+			}
+			end := strings.IndexByte(line[x:], ' ')
+			if end < 0 {
+				end = len(line) - x
+			}
+			token := line[x : x+end]
+			tokenLength = len(token)
+			positions[point{(x - slant) / (tokenLength + 1), y}] = token
+			x += end
+		}
+	}
+
+	var deltas []point
+	if slanted {
+		deltas = []point{{-1, 0}, {0, -1}, {1, -1}, {1, 0}, {0, 1}, {-1, 1}}
+	} else {
+		deltas = []point{{-1, 0}, {-1, -1}, {0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}}
+	}
+
+	kb := keyboard{name: name, adjacency: map[rune][]string{}, shifted: map[rune]bool{}}
+	degrees := 0
+	for p, token := range positions {
+		neighbours := make([]string, len(deltas))
+		for i, d := range deltas {
+			if n, ok := positions[point{p.x + d.x, p.y + d.y}]; ok {
+				neighbours[i] = n
+				degrees++
+			}
+		}
+		for i, c := range token {
+			kb.adjacency[c] = neighbours
+			if i > 0 {
+				kb.shifted[c] = true
+			}
+		}
+	}
+	kb.startingPositions = float64(len(positions))
+	kb.averageDegree = float64(degrees) / float64(len(positions))
+	return kb
+}
+
+// direction returns the direction from key c to key next, or -1 when they
+// are not adjacent.
+func (kb keyboard) direction(c, next rune) int {
+	for i, n := range kb.adjacency[c] {
+		if n != "" && strings.ContainsRune(n, next) {
+			return i
+		}
+	}
+	return -1
+}
+
+func spatialMatch(password []rune) []Match {
+	var matches []Match
+	for _, kb := range keyboards {
+		i := 0
+		for i < len(password)-2 {
+			j := i + 1
+			lastDirection := -1
+			turns := 0
+			shifted := 0
+			if kb.shifted[password[i]] {
+				shifted++
+			}
+			for j < len(password) {
+				dir := kb.direction(password[j-1], password[j])
+				if dir < 0 {
+					break
+				}
+				if dir != lastDirection {
+					turns++
+					lastDirection = dir
+				}
+				if kb.shifted[password[j]] {
+					shifted++
+				}
+				j++
+			}
+			if j-i > 2 {
+				matches = append(matches, Match{
+					Pattern:      SpatialPattern,
+					I:            i,
+					J:            j - 1,
+					Token:        string(password[i:j]),
+					Graph:        kb.name,
+					Turns:        turns,
+					ShiftedCount: shifted,
+				})
+			}
+			i = j
+		}
+	}
+	return matches
+}
+
+func repeatMatch(password []rune, userInputs []string) []Match {
+	var matches []Match
+	i := 0
+	for i < len(password) {
+		bestSpan, bestBase := 0, 0
+		for base := 1; base <= (len(password)-i)/2; base++ {
+			count := 1
+			for i+(count+1)*base <= len(password) &&
+				string(password[i+count*base:i+(count+1)*base]) == string(password[i:i+base]) {
+				count++
+			}
+			if count > 1 && count*base > bestSpan {
+				bestSpan, bestBase = count*base, base
+			}
+		}
+		if bestSpan == 0 {
+			i++
+			continue
+		}
+
+		base := password[i : i+bestBase]
+		baseGuesses, _ := mostGuessableSequence(base, omnimatch(base, userInputs))
+		matches = append(matches, Match{
+			Pattern:     RepeatPattern,
+			I:           i,
+			J:           i + bestSpan - 1,
+			Token:       string(password[i : i+bestSpan]),
+			BaseToken:   string(base),
+			BaseGuesses: baseGuesses,
+			RepeatCount: bestSpan / bestBase,
+		})
+		i += bestSpan
+	}
+	return matches
+}
+
+// maxSequenceDelta is the largest step between characters still considered a sequence.
+const maxSequenceDelta = 5
+
+func sequenceMatch(password []rune) []Match {
+	var matches []Match
+	emit := func(i, j, delta int) {
+		if j-i < 2 || delta == 0 || abs(delta) > maxSequenceDelta {
+			return
+		}
+		token := password[i : j+1]
+		var name string
+		switch {
+		case isAll(token, unicode.IsLower):
+			name = "lower"
+		case isAll(token, unicode.IsUpper):
+			name = "upper"
+		case isAll(token, unicode.IsDigit):
+			name = "digits"
+		default:
+			return
+		}
+		matches = append(matches, Match{
+			Pattern:      SequencePattern,
+			I:            i,
+			J:            j,
+			Token:        string(token),
+			SequenceName: name,
+			Ascending:    delta > 0,
+		})
+	}
+
+	if len(password) < 3 {
+		return nil
+	}
+	i := 0
+	lastDelta := 0
+	for k := 1; k < len(password); k++ {
+		delta := int(password[k]) - int(password[k-1])
+		if k == 1 {
+			lastDelta = delta
+			continue
+		}
+		if delta == lastDelta {
+			continue
+		}
+		emit(i, k-1, lastDelta)
+		i = k - 1
+		lastDelta = delta
+	}
+	emit(i, len(password)-1, lastDelta)
+	return matches
+}
+
+// dateSeparators are the characters accepted between day, month and year.
+const dateSeparators = " -/\\_."
+
+func dateMatch(password []rune) []Match {
+	var matches []Match
+
+	for i := range password {
+		for j := i + 3; j < len(password) && j < i+10; j++ {
+			token := string(password[i : j+1])
+
+			// Bare years.
+			if j-i == 3 && isAll(password[i:j+1], unicode.IsDigit) {
+				year, _ := strconv.Atoi(token)
+				if year >= 1900 && year <= 2099 {
+					matches = append(matches, Match{Pattern: DatePattern, I: i, J: j, Token: token, Year: year})
+				}
+			}
+
+			if year, month, day, sep, ok := parseDate(token); ok {
+				matches = append(matches, Match{
+					Pattern:   DatePattern,
+					I:         i,
+					J:         j,
+					Token:     token,
+					Year:      year,
+					Month:     month,
+					Day:       day,
+					Separator: sep,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// parseDate recognises tokens such as 19870523, 5.23.87 or 23-05-1987.
+func parseDate(token string) (year, month, day int, separator string, ok bool) {
+	var parts []string
+	if isAll([]rune(token), unicode.IsDigit) {
+		if len(token) > 8 {
+			return 0, 0, 0, "", false
+		}
+		// Try every way of cutting the digits into three parts.
+		best := -1
+		for a := 1; a < len(token)-1; a++ {
+			for b := a + 1; b < len(token); b++ {
+				y, m, d, found := pickDate([]string{token[:a], token[a:b], token[b:]})
+				if found && (best < 0 || abs(y-referenceYear) < best) {
+					best = abs(y - referenceYear)
+					year, month, day, ok = y, m, d, true
+				}
+			}
+		}
+		return year, month, day, "", ok
+	}
+
+	for _, sep := range dateSeparators {
+		if strings.Count(token, string(sep)) == 2 {
+			parts = strings.Split(token, string(sep))
+			separator = string(sep)
+			break
+		}
+	}
+	if len(parts) != 3 {
+		return 0, 0, 0, "", false
+	}
+	for _, p := range parts {
+		if p == "" || !isAll([]rune(p), unicode.IsDigit) {
+			return 0, 0, 0, "", false
+		}
+	}
+	year, month, day, ok = pickDate(parts)
+	return year, month, day, separator, ok
+}
+
+// pickDate interprets three digit groups as year-month-day, month-day-year
+// or day-month-year, preferring the year closest to referenceYear.
+func pickDate(parts []string) (year, month, day int, ok bool) {
+	if len(parts[1]) > 2 {
+		return 0, 0, 0, false
+	}
+	candidates := [][3]string{
+		{parts[0], parts[1], parts[2]}, // y m d
+		{parts[2], parts[0], parts[1]}, // m d y
+		{parts[2], parts[1], parts[0]}, // d m y
+	}
+	best := -1
+	for _, c := range candidates {
+		if len(c[0]) != 2 && len(c[0]) != 4 || len(c[1]) > 2 || len(c[2]) > 2 {
+			continue
+		}
+		y, _ := strconv.Atoi(c[0])
+		m, _ := strconv.Atoi(c[1])
+		d, _ := strconv.Atoi(c[2])
+		if len(c[0]) == 2 {
+			if y > 50 {
+				y += 1900
+			} else {
+				y += 2000
+			}
+		}
+		if y < 1000 || y > 2050 || m < 1 || m > 12 || d < 1 || d > 31 {
+			continue
+		}
+		if best < 0 || abs(y-referenceYear) < best {
+			best = abs(y - referenceYear)
+			year, month, day, ok = y, m, d, true
+		}
+	}
+	return year, month, day, ok
+}
+
+func reverse(s []rune) []rune {
+	out := make([]rune, len(s))
+	for i, c := range s {
+		out[len(s)-1-i] = c
+	}
+	return out
+}
+
+func isAll(s []rune, pred func(rune) bool) bool {
+	for _, c := range s {
+		if !pred(c) {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
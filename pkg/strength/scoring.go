@@ -0,0 +1,302 @@
+package strength
+
+import (
+	"math"
+	"unicode"
+)
+
+const (
+	// bruteforceCardinality is the guesses per character assumed for text that
+	// matches no pattern. It is deliberately low, as in zxcvbn, because a
+	// guesser tries likely characters first.
+	bruteforceCardinality = 10
+	// minGuessesBeforeGrowingSequence penalises splitting the password into
+	// many small matches.
+	minGuessesBeforeGrowingSequence = 10000
+	minSubmatchGuessesSingleChar    = 10
+	minSubmatchGuessesMultiChar     = 50
+	minYearSpace                    = 20
+)
+
+// mostGuessableSequence finds the decomposition of password into
+// non-overlapping matches, filled with brute force, that needs the fewest
+// guesses to find, and returns those guesses.
+func mostGuessableSequence(password []rune, matches []Match) (float64, []Match) {
+	n := len(password)
+	if n == 0 {
+		return 1, nil
+	}
+
+	// best[k][l] is the cheapest sequence of l matches covering password[:k+1].
+	type candidate struct {
+		match   Match
+		product float64
+		guesses float64
+	}
+	best := make([]map[int]candidate, n)
+	for k := range best {
+		best[k] = map[int]candidate{}
+	}
+
+	update := func(m Match, l int) {
+		k := m.J
+		product := estimateGuesses(&m, n)
+		if l > 1 {
+			product *= best[m.I-1][l-1].product
+		}
+		guesses := factorial(l) * product
+		guesses += math.Pow(minGuessesBeforeGrowingSequence, float64(l-1))
+
+		// Skip if a sequence with as few or fewer matches is already as cheap.
+		for other, c := range best[k] {
+			if other <= l && c.guesses <= guesses {
+				return
+			}
+		}
+		best[k][l] = candidate{match: m, product: product, guesses: guesses}
+	}
+
+	bruteforceUpdate := func(k int) {
+		update(bruteforceMatch(password, 0, k), 1)
+		for i := 1; i <= k; i++ {
+			m := bruteforceMatch(password, i, k)
+			for l, last := range best[i-1] {
+				// Adjacent brute force matches are better merged into one.
+				if last.match.Pattern == BruteforcePattern {
+					continue
+				}
+				update(m, l+1)
+			}
+		}
+	}
+
+	byEnd := make([][]Match, n)
+	for _, m := range matches {
+		byEnd[m.J] = append(byEnd[m.J], m)
+	}
+	for k := 0; k < n; k++ {
+		for _, m := range byEnd[k] {
+			if m.I > 0 {
+				for l := range best[m.I-1] {
+					update(m, l+1)
+				}
+			} else {
+				update(m, 1)
+			}
+		}
+		bruteforceUpdate(k)
+	}
+
+	// Unwind the cheapest sequence covering the whole password.
+	k := n - 1
+	bestL, bestGuesses := 0, math.Inf(1)
+	for l, c := range best[k] {
+		if c.guesses < bestGuesses {
+			bestL, bestGuesses = l, c.guesses
+		}
+	}
+	sequence := make([]Match, bestL)
+	for l := bestL; l > 0; l-- {
+		m := best[k][l].match
+		sequence[l-1] = m
+		k = m.I - 1
+	}
+	return bestGuesses, sequence
+}
+
+func bruteforceMatch(password []rune, i, j int) Match {
+	return Match{Pattern: BruteforcePattern, I: i, J: j, Token: string(password[i : j+1])}
+}
+
+// estimateGuesses fills in m.Guesses and returns it. passwordLength is used to
+// apply a floor to matches that are only part of the password.
+func estimateGuesses(m *Match, passwordLength int) float64 {
+	if m.Guesses != 0 {
+		return m.Guesses
+	}
+
+	tokenLength := len([]rune(m.Token))
+	minGuesses := 1.0
+	if tokenLength < passwordLength {
+		if tokenLength == 1 {
+			minGuesses = minSubmatchGuessesSingleChar
+		} else {
+			minGuesses = minSubmatchGuessesMultiChar
+		}
+	}
+
+	var guesses float64
+	switch m.Pattern {
+	case BruteforcePattern:
+		guesses = bruteforceGuesses(tokenLength)
+	case DictionaryPattern:
+		guesses = dictionaryGuesses(m)
+	case SpatialPattern:
+		guesses = spatialGuesses(m)
+	case RepeatPattern:
+		guesses = m.BaseGuesses * float64(m.RepeatCount)
+	case SequencePattern:
+		guesses = sequenceGuesses(m)
+	case DatePattern:
+		guesses = dateGuesses(m)
+	}
+	m.Guesses = math.Max(guesses, minGuesses)
+	return m.Guesses
+}
+
+func bruteforceGuesses(length int) float64 {
+	guesses := math.Pow(bruteforceCardinality, float64(length))
+	// Keep brute force above any single pattern match of the same length.
+	minGuesses := float64(minSubmatchGuessesMultiChar + 1)
+	if length == 1 {
+		minGuesses = minSubmatchGuessesSingleChar + 1
+	}
+	return math.Max(guesses, minGuesses)
+}
+
+func dictionaryGuesses(m *Match) float64 {
+	guesses := float64(m.Rank) * uppercaseVariations(m.Token) * l33tVariations(m)
+	if m.Reversed {
+		guesses *= 2
+	}
+	return guesses
+}
+
+// uppercaseVariations counts the capitalisations an attacker has to try.
+func uppercaseVariations(token string) float64 {
+	runes := []rune(token)
+	var upper, lower int
+	for _, c := range runes {
+		switch {
+		case unicode.IsUpper(c):
+			upper++
+		case unicode.IsLower(c):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	// First letter, last letter or all capitalised are tried first.
+	first, last := runes[0], runes[len(runes)-1]
+	if lower == 0 || upper == 1 && (unicode.IsUpper(first) || unicode.IsUpper(last)) {
+		return 2
+	}
+
+	var variations float64
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+func l33tVariations(m *Match) float64 {
+	if !m.L33t {
+		return 1
+	}
+	variations := 1.0
+	for subbed, letter := range m.Sub {
+		var s, u int
+		for _, c := range []rune(m.Token) {
+			switch {
+			case c == subbed:
+				s++
+			case unicode.ToLower(c) == letter:
+				u++
+			}
+		}
+		if u == 0 {
+			variations *= 2
+			continue
+		}
+		var possibilities float64
+		for i := 1; i <= min(s, u); i++ {
+			possibilities += binomial(s+u, i)
+		}
+		variations *= possibilities
+	}
+	return variations
+}
+
+func spatialGuesses(m *Match) float64 {
+	kb := keyboards[0]
+	for _, k := range keyboards {
+		if k.name == m.Graph {
+			kb = k
+		}
+	}
+
+	length := len([]rune(m.Token))
+	var guesses float64
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(m.Turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * kb.startingPositions * math.Pow(kb.averageDegree, float64(j))
+		}
+	}
+
+	if m.ShiftedCount > 0 {
+		s, u := m.ShiftedCount, length-m.ShiftedCount
+		if u == 0 {
+			guesses *= 2
+		} else {
+			var variations float64
+			for i := 1; i <= min(s, u); i++ {
+				variations += binomial(s+u, i)
+			}
+			guesses *= variations
+		}
+	}
+	return guesses
+}
+
+func sequenceGuesses(m *Match) float64 {
+	var base float64
+	switch first := []rune(m.Token)[0]; {
+	case first == 'a' || first == 'A' || first == 'z' || first == 'Z' || first == '0' || first == '1' || first == '9':
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if !m.Ascending {
+		base *= 2
+	}
+	return base * float64(len([]rune(m.Token)))
+}
+
+func dateGuesses(m *Match) float64 {
+	yearSpace := math.Max(float64(abs(m.Year-referenceYear)), minYearSpace)
+	if m.Month == 0 {
+		return yearSpace
+	}
+	guesses := yearSpace * 365
+	if m.Separator != "" {
+		guesses *= 4
+	}
+	return guesses
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
+
+func binomial(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	if k == 0 {
+		return 1
+	}
+	r := 1.0
+	for d := 1; d <= k; d++ {
+		r *= float64(n)
+		r /= float64(d)
+		n--
+	}
+	return r
+}
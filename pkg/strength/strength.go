@@ -0,0 +1,258 @@
+// Package strength estimates how hard a password is to guess.
+//
+// Two estimates are reported. CharsetEntropy assumes every character was
+// picked at random from the classes present, which is accurate for generated
+// keys. Estimate looks for patterns people use (dictionary words, keyboard
+// walks, repeats, sequences and dates) in the spirit of zxcvbn, and is the
+// better measure for human chosen passwords.
+package strength
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Scenario is an attack model used to turn guesses into a crack time.
+type Scenario struct {
+	Name             string
+	GuessesPerSecond float64
+}
+
+// Scenarios are the attack models reported by Estimate, slowest first.
+var Scenarios = []Scenario{
+	{Name: "Online attack, throttled (100/hour)", GuessesPerSecond: 100.0 / 3600},
+	{Name: "Online attack, unthrottled (10/s)", GuessesPerSecond: 10},
+	{Name: "Offline attack, slow hash (10k/s)", GuessesPerSecond: 1e4},
+	{Name: "Offline attack, fast hash (10B/s)", GuessesPerSecond: 1e10},
+}
+
+// CrackTime is the expected time to guess a password under a Scenario.
+type CrackTime struct {
+	Scenario Scenario
+	Seconds  float64
+}
+
+// String returns the crack time in words, e.g. "3 hours" or "centuries".
+func (c CrackTime) String() string {
+	return DisplayTime(c.Seconds)
+}
+
+// Result is the outcome of Estimate.
+type Result struct {
+	Password string
+	// CharsetEntropy is the entropy in bits if every character were random.
+	CharsetEntropy float64
+	// Guesses is the pattern based estimate of guesses needed.
+	Guesses float64
+	// Score ranges from 0 (too guessable) to 4 (very unguessable).
+	Score int
+	// Sequence is the cheapest decomposition of the password into matches.
+	Sequence   []Match
+	CrackTimes []CrackTime
+	Warning    string
+}
+
+// Bits returns the pattern based estimate expressed in bits.
+func (r Result) Bits() float64 {
+	return math.Log2(r.Guesses)
+}
+
+// ScoreLabels describes each Score value.
+var ScoreLabels = []string{"Too guessable", "Very guessable", "Somewhat guessable", "Safely unguessable", "Very unguessable"}
+
+// Label returns the description of the score.
+func (r Result) Label() string {
+	return ScoreLabels[r.Score]
+}
+
+// Summary returns a one line description such as
+// "Very unguessable (4/4), 98.3 bits charset, 50.1 bits estimated".
+func (r Result) Summary() string {
+	return fmt.Sprintf("%s (%d/4), %.1f bits charset, %.1f bits estimated", r.Label(), r.Score, r.CharsetEntropy, r.Bits())
+}
+
+// maxLength bounds the pattern search; longer inputs are scored as their
+// first maxLength characters plus brute force for the rest.
+const maxLength = 100
+
+// Estimate analyses password. userInputs are extra words, such as a user
+// name or site name, that should count as dictionary words.
+func Estimate(password string, userInputs ...string) Result {
+	result := Result{
+		Password:       password,
+		CharsetEntropy: CharsetEntropy(password),
+	}
+
+	runes := []rune(password)
+	tail := 0
+	if len(runes) > maxLength {
+		tail = len(runes) - maxLength
+		runes = runes[:maxLength]
+	}
+
+	matches := omnimatch(runes, userInputs)
+	result.Guesses, result.Sequence = mostGuessableSequence(runes, matches)
+	result.Guesses *= math.Pow(bruteforceCardinality, float64(tail))
+
+	result.Score = score(result.Guesses)
+	for _, s := range Scenarios {
+		result.CrackTimes = append(result.CrackTimes, CrackTime{
+			Scenario: s,
+			Seconds:  result.Guesses / s.GuessesPerSecond,
+		})
+	}
+	result.Warning = warning(result)
+	return result
+}
+
+// CharsetEntropy returns len(password) * log2(pool size), where the pool is
+// the union of the character classes present in password.
+func CharsetEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, c := range password {
+		switch {
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= '0' && c <= '9':
+			digit = true
+		case c < unicode.MaxASCII && unicode.IsPrint(c):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 100
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(len([]rune(password))) * math.Log2(float64(pool))
+}
+
+func score(guesses float64) int {
+	// A small delta so that a password at exactly a threshold scores lower.
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func warning(r Result) string {
+	if r.Score > 2 {
+		return ""
+	}
+	if len(r.Sequence) == 0 {
+		return "Use a few words, avoid common phrases"
+	}
+
+	// Warn about the longest pattern, it dominates the estimate.
+	longest := r.Sequence[0]
+	for _, m := range r.Sequence[1:] {
+		if len(m.Token) > len(longest.Token) {
+			longest = m
+		}
+	}
+	switch longest.Pattern {
+	case DictionaryPattern:
+		if longest.Dictionary == commonPasswordsDictionary {
+			return "This is similar to a commonly used password"
+		}
+		if longest.Reversed {
+			return "Reversed words aren't much harder to guess"
+		}
+		if longest.L33t {
+			return "Predictable substitutions like '@' instead of 'a' don't help very much"
+		}
+		if len(r.Sequence) == 1 {
+			return "A word by itself is easy to guess"
+		}
+		return "Common words are easy to guess, add another word or two"
+	case SpatialPattern:
+		if longest.Turns == 1 {
+			return "Straight rows of keys are easy to guess"
+		}
+		return "Short keyboard patterns are easy to guess"
+	case RepeatPattern:
+		if len([]rune(longest.BaseToken)) == 1 {
+			return "Repeats like \"aaa\" are easy to guess"
+		}
+		return "Repeats like \"abcabcabc\" are only slightly harder to guess than \"abc\""
+	case SequencePattern:
+		return "Sequences like abc or 6543 are easy to guess"
+	case DatePattern:
+		return "Dates and years are often easy to guess"
+	}
+	return "Add another word or two, uncommon words are better"
+}
+
+// DisplayTime formats a duration in seconds for people.
+func DisplayTime(seconds float64) string {
+	const (
+		minute  = 60
+		hour    = minute * 60
+		day     = hour * 24
+		month   = day * 31
+		year    = month * 12
+		century = year * 100
+	)
+
+	plural := func(n float64, unit string) string {
+		v := int64(math.Round(n))
+		if v != 1 {
+			unit += "s"
+		}
+		return fmt.Sprintf("%d %s", v, unit)
+	}
+
+	switch {
+	case seconds < 1:
+		return "less than a second"
+	case seconds < minute:
+		return plural(seconds, "second")
+	case seconds < hour:
+		return plural(seconds/minute, "minute")
+	case seconds < day:
+		return plural(seconds/hour, "hour")
+	case seconds < month:
+		return plural(seconds/day, "day")
+	case seconds < year:
+		return plural(seconds/month, "month")
+	case seconds < century:
+		return plural(seconds/year, "year")
+	default:
+		return "centuries"
+	}
+}
+
+// normalize lower-cases s for dictionary lookups.
+func normalize(s string) string {
+	return strings.ToLower(s)
+}
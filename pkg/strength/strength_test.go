@@ -0,0 +1,142 @@
+package strength
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		password   string
+		score      int
+		pattern    Pattern
+		dictionary string
+		warning    string
+	}{
+		{"password", 0, DictionaryPattern, commonPasswordsDictionary, "commonly used password"},
+		{"P@ssw0rd", 0, DictionaryPattern, commonPasswordsDictionary, "commonly used password"},
+		{"drowssap", 0, DictionaryPattern, commonPasswordsDictionary, "commonly used password"},
+		{"correct", 1, DictionaryPattern, englishDictionary, "A word by itself"},
+		{"aaaaaaaaaa", 0, RepeatPattern, "", `Repeats like "aaa"`},
+		{"abcabcabc", 0, RepeatPattern, "", `"abcabcabc"`},
+		{"abcdefgh", 0, SequencePattern, "", "Sequences"},
+		{"97531", 0, SequencePattern, "", "Sequences"},
+		{"12/25/1990", 1, DatePattern, "", "Dates"},
+		{"Tr0ub4dour&3", 2, DictionaryPattern, englishDictionary, "Predictable substitutions"},
+		{"correcthorsebatterystaple", 4, DictionaryPattern, englishDictionary, ""},
+		{"x8#Kq!2mZ@vL9$wR", 4, BruteforcePattern, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			r := Estimate(tt.password)
+			if r.Score != tt.score {
+				t.Errorf("Score = %d (%.1f bits), want %d", r.Score, r.Bits(), tt.score)
+			}
+			if len(r.Sequence) == 0 || r.Sequence[0].Pattern != tt.pattern || r.Sequence[0].Dictionary != tt.dictionary {
+				t.Errorf("Sequence = %+v, want it to start with a %s %s match", r.Sequence, tt.dictionary, tt.pattern)
+			}
+			if tt.warning == "" && r.Warning != "" || !strings.Contains(r.Warning, tt.warning) {
+				t.Errorf("Warning = %q, want %q", r.Warning, tt.warning)
+			}
+
+			var joined string
+			for i, m := range r.Sequence {
+				joined += m.Token
+				if i > 0 && m.I != r.Sequence[i-1].J+1 {
+					t.Errorf("match %d starts at %d, after a match ending at %d", i, m.I, r.Sequence[i-1].J)
+				}
+			}
+			if joined != tt.password {
+				t.Errorf("the sequence covers %q, want the whole password", joined)
+			}
+			if len(r.CrackTimes) != len(Scenarios) {
+				t.Errorf("CrackTimes has %d entries, want %d", len(r.CrackTimes), len(Scenarios))
+			}
+		})
+	}
+}
+
+func TestEstimateUserInputs(t *testing.T) {
+	without := Estimate("zebulon2024")
+	with := Estimate("zebulon2024", "Zebulon")
+	if with.Guesses >= without.Guesses {
+		t.Errorf("Guesses with the user input = %g, want fewer than %g", with.Guesses, without.Guesses)
+	}
+	if m := with.Sequence[0]; m.Dictionary != userInputsDictionary || m.Token != "zebulon" {
+		t.Errorf("Sequence[0] = %+v, want a user_inputs match of zebulon", m)
+	}
+}
+
+func TestEstimateLongPassword(t *testing.T) {
+	long := strings.Repeat("Kx9#mQ2!", 20)
+	r := Estimate(long)
+	if r.Score != 4 || math.IsInf(r.Guesses, 0) || math.IsNaN(r.Guesses) {
+		t.Errorf("Estimate() of %d characters = score %d, %g guesses", len(long), r.Score, r.Guesses)
+	}
+}
+
+func TestRankWords(t *testing.T) {
+	ranks := rankWords("  the\n\nof\nthe\nand\n")
+	want := map[string]int{"the": 1, "of": 2, "and": 3}
+	if len(ranks) != len(want) {
+		t.Fatalf("rankWords() = %v, want %v", ranks, want)
+	}
+	for word, rank := range want {
+		if ranks[word] != rank {
+			t.Errorf("rank of %q = %d, want %d", word, ranks[word], rank)
+		}
+	}
+
+	english := rankedDictionaries[englishDictionary]
+	if len(english) < 20000 {
+		t.Errorf("the English list has %d words, want the full frequency list", len(english))
+	}
+	if english["the"] == 0 || english["the"] > english["correct"] {
+		t.Errorf("rank of the = %d, correct = %d, want common words first", english["the"], english["correct"])
+	}
+}
+
+func TestCharsetEntropy(t *testing.T) {
+	tests := []struct {
+		password string
+		want     float64
+	}{
+		{"", 0},
+		{"abc", 3 * math.Log2(26)},
+		{"aB", 2 * math.Log2(52)},
+		{"a1!", 3 * math.Log2(26+10+33)},
+		{"ü", math.Log2(100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			if got := CharsetEntropy(tt.password); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CharsetEntropy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisplayTime(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0.5, "less than a second"},
+		{1, "1 second"},
+		{59, "59 seconds"},
+		{90, "2 minutes"},
+		{3600, "1 hour"},
+		{86400 * 3, "3 days"},
+		{86400 * 31 * 2, "2 months"},
+		{86400 * 31 * 12 * 5, "5 years"},
+		{1e12, "centuries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := DisplayTime(tt.seconds); got != tt.want {
+				t.Errorf("DisplayTime(%v) = %q, want %q", tt.seconds, got, tt.want)
+			}
+		})
+	}
+}
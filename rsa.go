@@ -1,11 +1,7 @@
 package main
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/base64"
-	"encoding/pem"
+	"context"
 	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
@@ -15,18 +11,56 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"golang.org/x/image/colornames"
+	"joshu/pkg/rsakeys"
 	"time"
 )
 
-func makeRSAUI(w fyne.Window) fyne.CanvasObject {
+// rsaKeySizes are the key sizes offered by the generator. Sizes below
+// rsakeys.MinSecureBits are labelled as insecure.
+var rsaKeySizes = []int{512, 1024, 2048, 4096}
+
+// makeRSAUI builds the RSA part of the key generator: key generation and
+// conversion, the encryption test and the signature test. It returns the key
+// boxes, which the tests read. No key is generated up front; the returned
+// function generates one in the background the first time it is called while
+// the key boxes are empty.
+func makeRSAUI(w fyne.Window) (fyne.CanvasObject, keyPairBoxes, func()) {
 	keySizeText := canvas.NewText("Key Size", theme.ForegroundColor())
-	keySize := widget.NewSelect([]string{"512 bit", "1024 bit", "2048 bit", "4096 bit"}, func(s string) {
+	var keySizes []string
+	for _, bits := range rsaKeySizes {
+		label := fmt.Sprintf("%d bit", bits)
+		if bits < rsakeys.MinSecureBits {
+			label += " (insecure)"
+		}
+		keySizes = append(keySizes, label)
+	}
+	keySize := widget.NewSelect(keySizes, func(s string) {
 	})
-	keySize.SetSelectedIndex(1)
+	keySize.SetSelectedIndex(2)
 
 	generateText := canvas.NewText("", theme.ForegroundColor())
 	generateText.TextStyle = fyne.TextStyle{Italic: true}
 
+	setGenerateStatus := func(text string, ok bool) {
+		generateText.Text = text
+		generateText.Color = colornames.Red
+		if ok {
+			generateText.Color = colornames.Green
+		}
+		generateText.Refresh()
+	}
+
+	keyFormatText := canvas.NewText("Format", theme.ForegroundColor())
+	var keyFormats []string
+	for _, f := range rsakeys.KeyFormats {
+		keyFormats = append(keyFormats, f.Label())
+	}
+	keyFormat := widget.NewSelect(keyFormats, nil)
+	keyFormat.SetSelectedIndex(0)
+	selectedKeyFormat := func() rsakeys.KeyFormat {
+		return rsakeys.KeyFormats[keyFormat.SelectedIndex()]
+	}
+
 	privateKeyTitle := canvas.NewText("Private Key", theme.ForegroundColor())
 	publicKeyTitle := canvas.NewText("Public Key", theme.ForegroundColor())
 
@@ -44,13 +78,16 @@ func makeRSAUI(w fyne.Window) fyne.CanvasObject {
 	privateKeyTextBox.Wrapping = fyne.TextWrapBreak
 	publicKeyTextBox.Wrapping = fyne.TextWrapBreak
 
-	privateKey, publickey, err := generateRSAKeys(1024)
-	if err != nil {
-		generateText.Text = err.Error()
-		generateText.Color = colornames.Red
-	} else {
-		privateKeyTextBox.SetText(privateKey)
-		publicKeyTextBox.SetText(publickey)
+	// The passphrase unlocks an encrypted private key pasted into the box,
+	// and protects generated and converted keys when encryptCheck is set.
+	passphraseInput := widget.NewPasswordEntry()
+	passphraseInput.SetPlaceHolder("Passphrase")
+	encryptCheck := widget.NewCheck("Encrypt private key", nil)
+	exportPassphrase := func() string {
+		if encryptCheck.Checked {
+			return passphraseInput.Text
+		}
+		return ""
 	}
 
 	copyPrivateKeyButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
@@ -63,48 +100,126 @@ func makeRSAUI(w fyne.Window) fyne.CanvasObject {
 		clipboard.SetContent(publicKeyTextBox.Text)
 	})
 
-	generateButton := widget.NewButton("Generate New Keys", func() {
-		startTime := time.Now()
-		var privateKey, publickey string
-		var err error
-		defer func() {
+	// Changing the format or the encryption converts the keys in the text
+	// boxes. Without a private key only the public key is converted.
+	convertKeys := func() {
+		format := selectedKeyFormat()
+		if privateKeyTextBox.Text == "" {
+			if publicKeyTextBox.Text == "" {
+				return
+			}
+			publicKey, err := rsakeys.ConvertPublicKey(publicKeyTextBox.Text, format)
 			if err != nil {
+				setGenerateStatus(err.Error(), false)
 				return
 			}
-			endTime := time.Now()
-			totalTime := endTime.Sub(startTime)
-			generateText.Text = fmt.Sprintf("Generated in %f seconds", totalTime.Seconds())
-			generateText.Color = colornames.Green
-			generateText.Refresh()
-		}()
-		generateText.Text = "Generating new keys..."
-		generateText.Color = colornames.Red
-		generateText.Refresh()
-
-		selectedIndex := keySize.SelectedIndex()
-		bitSize := 1024
-		switch selectedIndex {
-		case 0:
-			bitSize = 512
-		case 1:
-			bitSize = 1024
-		case 2:
-			bitSize = 2048
-		case 3:
-			bitSize = 4096
+			publicKeyTextBox.SetText(publicKey)
+			return
 		}
 
-		privateKey, publickey, err = generateRSAKeys(bitSize)
+		newPassphrase := exportPassphrase()
+		if encryptCheck.Checked && newPassphrase == "" {
+			setGenerateStatus("Enter a passphrase to encrypt the private key", false)
+			return
+		}
+		privateKey, publicKey, err := rsakeys.ConvertPrivateKeyWithPassphrase(privateKeyTextBox.Text,
+			passphraseInput.Text, format, newPassphrase)
 		if err != nil {
-			generateText.Text = err.Error()
-			generateText.Color = colornames.Red
+			setGenerateStatus(err.Error(), false)
+			return
+		}
+		privateKeyTextBox.SetText(privateKey)
+		publicKeyTextBox.SetText(publicKey)
+		if newPassphrase != "" {
+			setGenerateStatus("Converted to encrypted "+format.Label(), true)
 		} else {
-			privateKeyTextBox.SetText(privateKey)
-			publicKeyTextBox.SetText(publickey)
-			publicKeyTextBox.Refresh()
-			privateKeyTextBox.Refresh()
+			setGenerateStatus("Converted to "+format.Label(), true)
+		}
+	}
+	keyFormat.OnChanged = func(string) { convertKeys() }
+	encryptCheck.OnChanged = func(bool) { convertKeys() }
+
+	privateKeyTextBox.OnChanged = func(text string) {
+		if rsakeys.IsEncrypted(text) {
+			setGenerateStatus("The private key is encrypted, its passphrase is needed to use it", true)
+		}
+	}
+
+	// Generation runs in the background: 4096 bit keys take seconds. Fyne
+	// widgets are safe to update from the worker goroutine.
+	var cancelGenerate context.CancelFunc
+	generating := widget.NewProgressBarInfinite()
+	generating.Stop()
+	generating.Hide()
+	cancelGenerateButton := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		if cancelGenerate != nil {
+			cancelGenerate()
 		}
 	})
+	cancelGenerateButton.Disable()
+
+	// The key boxes are replaced when generation ends, so the controls
+	// converting or using them wait for it along with the generate button.
+	var generateButton, EncryptButton, DecryptButton *widget.Button
+	generateKeys := func() {
+		bitSize := rsaKeySizes[keySize.SelectedIndex()]
+		if encryptCheck.Checked && passphraseInput.Text == "" {
+			setGenerateStatus("Enter a passphrase to encrypt the private key", false)
+			return
+		}
+		format, passphrase := selectedKeyFormat(), exportPassphrase()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelGenerate = cancel
+		generateButton.Disable()
+		keySize.Disable()
+		keyFormat.Disable()
+		encryptCheck.Disable()
+		passphraseInput.Disable()
+		EncryptButton.Disable()
+		DecryptButton.Disable()
+		cancelGenerateButton.Enable()
+		generating.Show()
+		generating.Start()
+		setGenerateStatus(fmt.Sprintf("Generating %d bit keys...", bitSize), true)
+
+		startTime := time.Now()
+		go func() {
+			defer cancel()
+			privateKey, publicKey, err := rsakeys.GenerateContext(ctx, bitSize, format, passphrase)
+			totalTime := time.Since(startTime)
+			defer func() {
+				generating.Stop()
+				generating.Hide()
+				cancelGenerateButton.Disable()
+				keyFormat.Enable()
+				keySize.Enable()
+				encryptCheck.Enable()
+				passphraseInput.Enable()
+				EncryptButton.Enable()
+				DecryptButton.Enable()
+				generateButton.Enable()
+			}()
+
+			switch {
+			case errors.Is(err, context.Canceled):
+				setGenerateStatus(fmt.Sprintf("Cancelled after %f seconds", totalTime.Seconds()), false)
+				return
+			case err != nil:
+				setGenerateStatus(err.Error(), false)
+				return
+			}
+			privateKeyTextBox.SetText(privateKey)
+			publicKeyTextBox.SetText(publicKey)
+			if bitSize < rsakeys.MinSecureBits {
+				setGenerateStatus(fmt.Sprintf("Generated in %f seconds. %d bit keys are insecure, use them for testing only",
+					totalTime.Seconds(), bitSize), false)
+				return
+			}
+			setGenerateStatus(fmt.Sprintf("Generated in %f seconds", totalTime.Seconds()), true)
+		}()
+	}
+	generateButton = widget.NewButton("Generate New Keys", generateKeys)
 	generateButton.Importance = widget.HighImportance
 
 	RSAEncryptionText := canvas.NewText("RSA Encryption Test", theme.ForegroundColor())
@@ -126,7 +241,39 @@ func makeRSAUI(w fyne.Window) fyne.CanvasObject {
 	RSAEncryptionOutputTitle.TextSize = 14
 	RSAEncryptionOutputTitle.TextStyle = fyne.TextStyle{Bold: true}
 
-	EncryptButton := widget.NewButton("Encrypt", func() {
+	var paddings []string
+	for _, p := range rsakeys.Paddings {
+		paddings = append(paddings, string(p))
+	}
+	var oaepHashes []string
+	for _, h := range rsakeys.OAEPHashes {
+		oaepHashes = append(oaepHashes, string(h))
+	}
+	oaepHashSelect := widget.NewSelect(oaepHashes, func(string) {})
+	oaepHashSelect.SetSelectedIndex(0)
+	oaepLabelInput := widget.NewEntry()
+	oaepLabelInput.SetPlaceHolder("OAEP label (optional)")
+	paddingSelect := widget.NewSelect(paddings, func(padding string) {
+		// The hash and label only apply to OAEP.
+		if rsakeys.Padding(padding) == rsakeys.OAEPPadding {
+			oaepHashSelect.Enable()
+			oaepLabelInput.Enable()
+		} else {
+			oaepHashSelect.Disable()
+			oaepLabelInput.Disable()
+		}
+	})
+	paddingSelect.SetSelectedIndex(0)
+
+	encryptOptions := func() rsakeys.EncryptOptions {
+		return rsakeys.EncryptOptions{
+			Padding: rsakeys.Padding(paddingSelect.Selected),
+			Hash:    rsakeys.Hash(oaepHashSelect.Selected),
+			Label:   oaepLabelInput.Text,
+		}
+	}
+
+	EncryptButton = widget.NewButton("Encrypt", func() {
 		message := RSAEncryptionInput.Text
 		if len(message) == 0 {
 			return
@@ -137,7 +284,7 @@ func makeRSAUI(w fyne.Window) fyne.CanvasObject {
 			return
 		}
 
-		encrypted, err := encryptRSA(message, publickey)
+		encrypted, err := rsakeys.EncryptWith(message, publickey, encryptOptions())
 		if err != nil {
 			RSAEncryptionOutput.Text = err.Error()
 			RSAEncryptionOutput.Refresh()
@@ -148,7 +295,7 @@ func makeRSAUI(w fyne.Window) fyne.CanvasObject {
 	})
 	EncryptButton.Importance = widget.HighImportance
 
-	DecryptButton := widget.NewButton("Decrypt", func() {
+	DecryptButton = widget.NewButton("Decrypt", func() {
 		encryptedMessage := RSAEncryptionOutput.Text
 		if len(encryptedMessage) == 0 {
 			return
@@ -158,8 +305,13 @@ func makeRSAUI(w fyne.Window) fyne.CanvasObject {
 		if len(privateKey) == 0 {
 			return
 		}
+		privateKey, err := rsakeys.UnlockPrivateKey(privateKey, passphraseInput.Text)
+		if err != nil {
+			RSAEncryptionInput.SetText(err.Error())
+			return
+		}
 
-		decrypted, err := decryptRSA(encryptedMessage, privateKey)
+		decrypted, err := rsakeys.DecryptWith(encryptedMessage, privateKey, encryptOptions())
 		if err != nil {
 			RSAEncryptionInput.Text = err.Error()
 			RSAEncryptionInput.Refresh()
@@ -172,6 +324,9 @@ func makeRSAUI(w fyne.Window) fyne.CanvasObject {
 
 	RSATestContainer := container.NewVBox(
 		RSAEncryptionText,
+		container.NewGridWithColumns(4,
+			widget.NewLabel("Padding"), paddingSelect, oaepHashSelect, oaepLabelInput,
+		),
 		container.NewGridWithColumns(2,
 			container.NewBorder(RSAEncryptionTitle, EncryptButton,
 				container.NewGridWrap(fyne.NewSize(1, 200), layout.NewSpacer()),
@@ -185,17 +340,21 @@ func makeRSAUI(w fyne.Window) fyne.CanvasObject {
 	)
 
 	genKeyContainer := container.NewVBox(
-		container.NewHBox(keySizeText, keySize, generateButton),
+		container.NewHBox(keySizeText, keySize, keyFormatText, keyFormat, generateButton, cancelGenerateButton),
+		container.NewGridWithColumns(4, widget.NewLabel("Passphrase"), passphraseInput, encryptCheck),
+		generating,
 		generateText,
 		container.NewGridWithColumns(2,
 			container.NewBorder(
-				container.NewHBox(privateKeyTitle, copyPrivateKeyButton), nil,
+				container.NewHBox(privateKeyTitle, copyPrivateKeyButton,
+					makeKeyFileButtons(w, privateKeyTextBox, "private.pem", true)), nil,
 				container.NewGridWrap(fyne.NewSize(1, 300), layout.NewSpacer()),
 				container.NewGridWrap(fyne.NewSize(1, 300), layout.NewSpacer()),
 				privateKeyTextBox,
 			),
 			container.NewBorder(
-				container.NewHBox(publicKeyTitle, copyPublicKeyButton), nil,
+				container.NewHBox(publicKeyTitle, copyPublicKeyButton,
+					makeKeyFileButtons(w, publicKeyTextBox, "public.pem", false)), nil,
 				container.NewGridWrap(fyne.NewSize(1, 300), layout.NewSpacer()),
 				container.NewGridWrap(fyne.NewSize(1, 300), layout.NewSpacer()),
 				publicKeyTextBox,
@@ -203,82 +362,19 @@ func makeRSAUI(w fyne.Window) fyne.CanvasObject {
 		),
 	)
 
-	header := makeHeader("RSA Key Generator")
-	footer := makeFooter()
-
-	return container.NewPadded(container.NewBorder(header, footer, layout.NewSpacer(), layout.NewSpacer(),
-		container.NewGridWithRows(2,
-			genKeyContainer,
-			RSATestContainer,
-		)))
-}
-
-func generateRSAKeys(bitSize int) (string, string, error) {
-	// Generate RSA private key
-	privateKey, err := rsa.GenerateKey(rand.Reader, bitSize)
-	if err != nil {
-		return "", "", err
-	}
-
-	// Export private key as PEM format
-	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	})
-
-	// Export public key as PEM format
-	publicKeyPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PUBLIC KEY",
-		Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey),
-	})
-
-	return string(privateKeyPEM), string(publicKeyPEM), nil
-}
-
-// encryptRSA encrypts the given message using the RSA public key
-func encryptRSA(message string, publicKeyPEM string) (string, error) {
-	block, _ := pem.Decode([]byte(publicKeyPEM))
-	if block == nil || block.Type != "RSA PUBLIC KEY" {
-		return "", errors.New("failed to decode PEM block containing public key")
-	}
-
-	publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
-	if err != nil {
-		return "", err
-	}
-
-	encryptedBytes, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, []byte(message))
-	if err != nil {
-		return "", err
-	}
-
-	// Encode the encrypted bytes to base64 to return as a string
-	encryptedBase64 := base64.StdEncoding.EncodeToString(encryptedBytes)
-	return encryptedBase64, nil
-}
-
-// decryptRSA decrypts the given encrypted message using the RSA private key
-func decryptRSA(encryptedMessage string, privateKeyPEM string) (string, error) {
-	block, _ := pem.Decode([]byte(privateKeyPEM))
-	if block == nil || block.Type != "RSA PRIVATE KEY" {
-		return "", errors.New("failed to decode PEM block containing private key")
-	}
-
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		return "", err
-	}
-
-	// Decode the base64 encoded encrypted message
-	encryptedBytes, err := base64.StdEncoding.DecodeString(encryptedMessage)
-	if err != nil {
-		return "", err
-	}
-
-	decryptedBytes, err := rsa.DecryptPKCS1v15(rand.Reader, privateKey, encryptedBytes)
-	if err != nil {
-		return "", err
+	started := false
+	generateInitialKeys := func() {
+		if started || privateKeyTextBox.Text != "" || publicKeyTextBox.Text != "" {
+			return
+		}
+		started = true
+		generateKeys()
 	}
 
-	return string(decryptedBytes), nil
+	return container.NewVBox(
+		genKeyContainer,
+		RSATestContainer,
+		makeRSASignUI(w, privateKeyTextBox, passphraseInput, publicKeyTextBox),
+		makeRSAHybridUI(w, privateKeyTextBox, passphraseInput, publicKeyTextBox),
+	), keyPairBoxes{privateKeyTextBox, publicKeyTextBox}, generateInitialKeys
 }
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"io"
+	"joshu/pkg/rsakeys"
+	"unicode/utf8"
+)
+
+// makeRSAHybridUI builds the hybrid encryption panel for messages and files
+// larger than the RSA modulus. It seals with the key in publicKeyBox and
+// opens with the key in privateKeyBox, unlocked with passphraseBox.
+func makeRSAHybridUI(w fyne.Window, privateKeyBox, passphraseBox, publicKeyBox *widget.Entry) fyne.CanvasObject {
+	title := canvas.NewText("Hybrid Encryption", theme.ForegroundColor())
+	title.TextSize = 24
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	subTitle := canvas.NewText("Encrypts data of any size with a random AES-256-GCM key wrapped with RSA-OAEP.",
+		theme.ForegroundColor())
+	subTitle.TextSize = 14
+	subTitle.TextStyle = fyne.TextStyle{Italic: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	var hashes []string
+	for _, h := range rsakeys.OAEPHashes {
+		hashes = append(hashes, string(h))
+	}
+	hashSelect := widget.NewSelect(hashes, func(string) {})
+	hashSelect.SetSelectedIndex(0)
+
+	var formats []string
+	for _, f := range rsakeys.EnvelopeFormats {
+		formats = append(formats, string(f))
+	}
+	formatSelect := widget.NewSelect(formats, func(string) {})
+	formatSelect.SetSelectedIndex(0)
+
+	messageInput := widget.NewMultiLineEntry()
+	messageInput.SetPlaceHolder("Enter text to encrypt")
+	messageInput.Wrapping = fyne.TextWrapBreak
+
+	envelopeOutput := widget.NewMultiLineEntry()
+	envelopeOutput.SetPlaceHolder("Envelope (JSON, or base64 for the binary format)")
+	envelopeOutput.Wrapping = fyne.TextWrapBreak
+
+	// fileData is the loaded plaintext file, and decrypted the last opened
+	// payload, kept as bytes since files need not be text.
+	var fileData, decrypted []byte
+	fileText := canvas.NewText("No file loaded", theme.ForegroundColor())
+	fileText.TextSize = 14
+
+	loadButton := widget.NewButtonWithIcon("Load File", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			fileData = data
+			fileText.Text = fmt.Sprintf("%s (%d bytes)", reader.URI().Name(), len(data))
+			fileText.Refresh()
+		}, w)
+	})
+	fileRow := container.NewHBox(loadButton, fileText)
+	fileRow.Hide()
+
+	sourceRadio := widget.NewRadioGroup([]string{signTextSource, signFileSource}, func(source string) {
+		if source == signFileSource {
+			messageInput.Hide()
+			fileRow.Show()
+		} else {
+			fileRow.Hide()
+			messageInput.Show()
+		}
+	})
+	sourceRadio.Horizontal = true
+	sourceRadio.SetSelected(signTextSource)
+
+	encryptButton := widget.NewButton("Encrypt", func() {
+		plaintext := []byte(messageInput.Text)
+		if sourceRadio.Selected == signFileSource {
+			if fileData == nil {
+				setStatus("Load a file first", false)
+				return
+			}
+			plaintext = fileData
+		}
+		envelope, err := rsakeys.Seal(plaintext, publicKeyBox.Text, rsakeys.Hash(hashSelect.Selected),
+			rsakeys.EnvelopeFormat(formatSelect.Selected))
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		envelopeOutput.SetText(rsakeys.EncodeEnvelopeText(envelope))
+		setStatus(fmt.Sprintf("Encrypted %d bytes into a %d byte envelope", len(plaintext), len(envelope)), true)
+	})
+	encryptButton.Importance = widget.HighImportance
+
+	decryptButton := widget.NewButton("Decrypt", func() {
+		envelope, err := rsakeys.DecodeEnvelopeText(envelopeOutput.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		privateKey, err := rsakeys.UnlockPrivateKey(privateKeyBox.Text, passphraseBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		plaintext, err := rsakeys.Open(envelope, privateKey)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		decrypted = plaintext
+		if sourceRadio.Selected == signTextSource && utf8.Valid(plaintext) {
+			messageInput.SetText(string(plaintext))
+			setStatus(fmt.Sprintf("Decrypted %d bytes", len(plaintext)), true)
+			return
+		}
+		setStatus(fmt.Sprintf("Decrypted %d bytes, use Save Decrypted to write them to a file", len(plaintext)), true)
+	})
+	decryptButton.Importance = widget.WarningImportance
+
+	saveFile := func(data []byte) {
+		dialog.ShowFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+
+			if _, err := writer.Write(data); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			setStatus(fmt.Sprintf("Saved %d bytes to %s", len(data), writer.URI().Name()), true)
+		}, w)
+	}
+
+	loadEnvelopeButton := widget.NewButtonWithIcon("Load Envelope", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			format, err := rsakeys.DetectEnvelope(data)
+			if err != nil {
+				setStatus(fmt.Sprintf("%s: %v", reader.URI().Name(), err), false)
+				return
+			}
+			formatSelect.SetSelected(string(format))
+			envelopeOutput.SetText(rsakeys.EncodeEnvelopeText(data))
+			setStatus(fmt.Sprintf("Loaded a %s envelope from %s", format, reader.URI().Name()), true)
+		}, w)
+	})
+	saveEnvelopeButton := widget.NewButtonWithIcon("Save Envelope", theme.DocumentSaveIcon(), func() {
+		envelope, err := rsakeys.DecodeEnvelopeText(envelopeOutput.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		saveFile(envelope)
+	})
+	saveDecryptedButton := widget.NewButtonWithIcon("Save Decrypted", theme.DocumentSaveIcon(), func() {
+		if decrypted == nil {
+			setStatus("Decrypt an envelope first", false)
+			return
+		}
+		saveFile(decrypted)
+	})
+
+	return container.NewVBox(
+		title,
+		subTitle,
+		container.NewGridWithColumns(4,
+			widget.NewLabel("OAEP hash"), hashSelect,
+			widget.NewLabel("Envelope format"), formatSelect,
+		),
+		sourceRadio,
+		container.NewGridWithColumns(2,
+			container.NewBorder(nil, encryptButton,
+				container.NewGridWrap(fyne.NewSize(1, 200), layout.NewSpacer()), nil,
+				container.NewBorder(fileRow, nil, nil, nil, messageInput)),
+			container.NewBorder(nil, decryptButton,
+				container.NewGridWrap(fyne.NewSize(1, 200), layout.NewSpacer()), nil,
+				envelopeOutput),
+		),
+		container.NewHBox(loadEnvelopeButton, saveEnvelopeButton, saveDecryptedButton),
+		status,
+	)
+}
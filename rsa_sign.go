@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/colornames"
+	"io"
+	"joshu/pkg/rsakeys"
+)
+
+const (
+	signTextSource = "Text"
+	signFileSource = "File"
+)
+
+// makeRSASignUI builds the sign/verify panel. It signs with the key in
+// privateKeyBox, unlocked with passphraseBox if it is encrypted, and
+// verifies with the key in publicKeyBox.
+func makeRSASignUI(w fyne.Window, privateKeyBox, passphraseBox, publicKeyBox *widget.Entry) fyne.CanvasObject {
+	title := canvas.NewText("RSA Signature Test", theme.ForegroundColor())
+	title.TextSize = 24
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	status := canvas.NewText("", theme.ForegroundColor())
+	status.TextSize = 14
+	status.TextStyle = fyne.TextStyle{Italic: true}
+
+	setStatus := func(text string, ok bool) {
+		status.Text = text
+		status.Color = colornames.Red
+		if ok {
+			status.Color = colornames.Green
+		}
+		status.Refresh()
+	}
+
+	var schemes []string
+	for _, s := range rsakeys.SignatureSchemes {
+		schemes = append(schemes, string(s))
+	}
+	schemeSelect := widget.NewSelect(schemes, func(string) {})
+	schemeSelect.SetSelectedIndex(0)
+
+	var hashes []string
+	for _, h := range rsakeys.SignatureHashes {
+		hashes = append(hashes, string(h))
+	}
+	hashSelect := widget.NewSelect(hashes, func(string) {})
+	hashSelect.SetSelectedIndex(0)
+
+	messageInput := widget.NewMultiLineEntry()
+	messageInput.SetPlaceHolder("Enter text to sign")
+	messageInput.Wrapping = fyne.TextWrapBreak
+	messageInput.Text = "This is a test!"
+
+	var fileData []byte
+	fileText := canvas.NewText("No file loaded", theme.ForegroundColor())
+	fileText.TextSize = 14
+
+	loadButton := widget.NewButtonWithIcon("Load File", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			fileData = data
+			fileText.Text = fmt.Sprintf("%s (%d bytes)", reader.URI().Name(), len(data))
+			fileText.Refresh()
+		}, w)
+	})
+	fileRow := container.NewHBox(loadButton, fileText)
+	fileRow.Hide()
+
+	sourceRadio := widget.NewRadioGroup([]string{signTextSource, signFileSource}, func(source string) {
+		if source == signFileSource {
+			messageInput.Hide()
+			fileRow.Show()
+		} else {
+			fileRow.Hide()
+			messageInput.Show()
+		}
+	})
+	sourceRadio.Horizontal = true
+	sourceRadio.SetSelected(signTextSource)
+
+	signatureOutput := widget.NewMultiLineEntry()
+	signatureOutput.SetPlaceHolder("Signature (base64)")
+	signatureOutput.Wrapping = fyne.TextWrapBreak
+
+	data := func() ([]byte, bool) {
+		if sourceRadio.Selected == signFileSource {
+			if fileData == nil {
+				setStatus("Load a file first", false)
+				return nil, false
+			}
+			return fileData, true
+		}
+		return []byte(messageInput.Text), true
+	}
+	signOptions := func() rsakeys.SignOptions {
+		return rsakeys.SignOptions{
+			Scheme: rsakeys.SignatureScheme(schemeSelect.Selected),
+			Hash:   rsakeys.Hash(hashSelect.Selected),
+		}
+	}
+
+	signButton := widget.NewButton("Sign", func() {
+		message, ok := data()
+		if !ok {
+			return
+		}
+		privateKey, err := rsakeys.UnlockPrivateKey(privateKeyBox.Text, passphraseBox.Text)
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		signature, err := rsakeys.Sign(message, privateKey, signOptions())
+		if err != nil {
+			setStatus(err.Error(), false)
+			return
+		}
+		signatureOutput.SetText(signature)
+		setStatus(fmt.Sprintf("Signed %d bytes with %s %s", len(message), schemeSelect.Selected, hashSelect.Selected), true)
+	})
+	signButton.Importance = widget.HighImportance
+
+	verifyButton := widget.NewButton("Verify", func() {
+		message, ok := data()
+		if !ok {
+			return
+		}
+		if err := rsakeys.Verify(message, signatureOutput.Text, publicKeyBox.Text, signOptions()); err != nil {
+			setStatus(fmt.Sprintf("Invalid signature: %v", err), false)
+			return
+		}
+		setStatus("Signature is valid", true)
+	})
+	verifyButton.Importance = widget.WarningImportance
+
+	return container.NewVBox(
+		title,
+		container.NewGridWithColumns(4,
+			widget.NewLabel("Scheme"), schemeSelect,
+			widget.NewLabel("Hash"), hashSelect,
+		),
+		sourceRadio,
+		container.NewGridWithColumns(2,
+			container.NewBorder(nil, signButton,
+				container.NewGridWrap(fyne.NewSize(1, 150), layout.NewSpacer()), nil,
+				container.NewBorder(fileRow, nil, nil, nil, messageInput)),
+			container.NewBorder(nil, verifyButton,
+				container.NewGridWrap(fyne.NewSize(1, 150), layout.NewSpacer()), nil,
+				signatureOutput),
+		),
+		status,
+	)
+}